@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/monax/bosmarmot/vent/logger"
+	"github.com/spf13/cobra"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Copy a SQLite database to a file while vent keeps writing to it",
+	Run:   runBackupCmd,
+}
+
+var backupFile string
+
+func init() {
+	backupCmd.Flags().StringVar(&backupFile, "to-file", "", "SQLite db file path the database is copied to (replaced if it exists)")
+
+	ventCmd.AddCommand(backupCmd)
+}
+
+func runBackupCmd(cmd *cobra.Command, args []string) {
+	log := logger.NewLogger(cfg.LogLevel)
+
+	if backupFile == "" {
+		log.Error("err", "to-file must be provided")
+		os.Exit(1)
+	}
+
+	db, err := openDB(cfg.DBSchema, log)
+	if err != nil {
+		log.Error("err", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	if err = db.Backup(backupFile); err != nil {
+		log.Error("err", err)
+		db.Close()
+		os.Exit(1)
+	}
+}
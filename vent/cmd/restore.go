@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/monax/bosmarmot/vent/logger"
+	"github.com/monax/bosmarmot/vent/sqldb"
+	"github.com/spf13/cobra"
+)
+
+// restoreTimeLayout is the layout of the restore time flag
+const restoreTimeLayout = "2006-01-02 15:04:05"
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore event tables up to a given height or time into a separate schema or with a table prefix",
+	Run:   runRestoreCmd,
+}
+
+var restoreOpts struct {
+	height       uint64
+	time         string
+	prefix       string
+	targetSchema string
+}
+
+func init() {
+	restoreCmd.Flags().Uint64Var(&restoreOpts.height, "to-height", 0, "Restore changes logged up to this height")
+	restoreCmd.Flags().StringVar(&restoreOpts.time, "to-time", "", "Restore changes logged up to this time ("+restoreTimeLayout+")")
+	restoreCmd.Flags().StringVar(&restoreOpts.prefix, "prefix", "", "Prefix of restored table names (<prefix>_<table>)")
+	restoreCmd.Flags().StringVar(&restoreOpts.targetSchema, "target-schema", "", "PostgreSQL schema or MySQL database where tables are restored")
+
+	ventCmd.AddCommand(restoreCmd)
+}
+
+func runRestoreCmd(cmd *cobra.Command, args []string) {
+	log := logger.NewLogger(cfg.LogLevel)
+
+	options := sqldb.RestoreOptions{
+		Height: restoreOpts.height,
+		Prefix: restoreOpts.prefix,
+		Progress: func(rows int) {
+			log.Warn("msg", "Restored rows", "value", rows)
+		},
+	}
+
+	err := parseRestorePoint(&options)
+	if err != nil {
+		log.Error("err", err)
+		os.Exit(1)
+	}
+
+	db, err := openDB(cfg.DBSchema, log)
+	if err != nil {
+		log.Error("err", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	if restoreOpts.targetSchema != "" {
+		if options.Target, err = openDB(restoreOpts.targetSchema, log); err != nil {
+			log.Error("err", err)
+			db.Close()
+			os.Exit(1)
+		}
+		defer options.Target.Close()
+	}
+
+	if err = db.Restore(options); err != nil {
+		log.Error("err", err)
+		db.Close()
+		os.Exit(1)
+	}
+}
+
+// parseRestorePoint sets the height or time to restore to
+func parseRestorePoint(options *sqldb.RestoreOptions) error {
+	switch {
+	case restoreOpts.height > 0 && restoreOpts.time != "":
+		return errors.New("only one of to-height or to-time must be provided")
+
+	case restoreOpts.height > 0:
+		return nil
+
+	case restoreOpts.time != "":
+		t, err := time.ParseInLocation(restoreTimeLayout, restoreOpts.time, time.Local)
+		options.Time = t
+		return err
+
+	default:
+		return errors.New("one of to-height or to-time must be provided")
+	}
+}
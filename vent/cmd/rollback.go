@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/monax/bosmarmot/vent/logger"
+	"github.com/spf13/cobra"
+)
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Revert event tables to their state at a given height and reset the checkpoint",
+	Run:   runRollbackCmd,
+}
+
+var rollbackHeight uint64
+
+func init() {
+	rollbackCmd.Flags().Uint64Var(&rollbackHeight, "to-height", 0, "Height to roll back to, changes logged after it are reverted")
+	rollbackCmd.MarkFlagRequired("to-height")
+
+	ventCmd.AddCommand(rollbackCmd)
+}
+
+func runRollbackCmd(cmd *cobra.Command, args []string) {
+	log := logger.NewLogger(cfg.LogLevel)
+
+	db, err := openDB(cfg.DBSchema, log)
+	if err != nil {
+		log.Error("err", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	if err = db.RollbackToHeight(rollbackHeight); err != nil {
+		log.Error("err", err)
+		db.Close()
+		os.Exit(1)
+	}
+}
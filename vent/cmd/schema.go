@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/monax/bosmarmot/vent/logger"
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Inspect event tables structure",
+}
+
+var schemaCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Compare tables stored in the dictionary with the database catalog, exits with an error if they drifted",
+	Run:   runSchemaCheckCmd,
+}
+
+var schemaRepair bool
+
+func init() {
+	schemaCheckCmd.Flags().BoolVar(&schemaRepair, "repair", false, "Recreate missing tables & columns and restore column types before checking")
+	schemaCheckCmd.Flags().BoolVar(&cfg.AllowDestructive, "allow-destructive", cfg.AllowDestructive, "Drop unexpected columns when repairing (true/false)")
+
+	schemaCmd.AddCommand(schemaCheckCmd)
+	ventCmd.AddCommand(schemaCmd)
+}
+
+func runSchemaCheckCmd(cmd *cobra.Command, args []string) {
+	log := logger.NewLogger(cfg.LogLevel)
+
+	db, err := openDB(cfg.DBSchema, log)
+	if err != nil {
+		log.Error("err", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+	db.AllowDestructive = cfg.AllowDestructive
+
+	check := db.CheckSchema
+	if schemaRepair {
+		check = db.RepairSchema
+	}
+
+	drifts, err := check()
+	if err != nil {
+		log.Error("err", err)
+		db.Close()
+		os.Exit(1)
+	}
+
+	for _, drift := range drifts {
+		fmt.Println(drift)
+	}
+
+	if len(drifts) > 0 {
+		db.Close()
+		os.Exit(1)
+	}
+}
@@ -1,14 +1,18 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 
 	"github.com/monax/bosmarmot/vent/config"
 	"github.com/monax/bosmarmot/vent/logger"
 	"github.com/monax/bosmarmot/vent/service"
+	"github.com/monax/bosmarmot/vent/sqldb"
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
 	"github.com/monax/bosmarmot/vent/sqlsol"
 	"github.com/monax/bosmarmot/vent/types"
 	"github.com/spf13/cobra"
@@ -23,17 +27,31 @@ var ventCmd = &cobra.Command{
 var cfg = config.DefaultFlags()
 
 func init() {
-	ventCmd.Flags().StringVar(&cfg.DBAdapter, "db-adapter", cfg.DBAdapter, "Database adapter, 'postgres' or 'sqlite' are fully supported")
-	ventCmd.Flags().StringVar(&cfg.DBURL, "db-url", cfg.DBURL, "PostgreSQL database URL or SQLite db file path")
-	ventCmd.Flags().StringVar(&cfg.DBSchema, "db-schema", cfg.DBSchema, "PostgreSQL database schema (empty for SQLite)")
+	ventCmd.PersistentFlags().StringVar(&cfg.DBAdapter, "db-adapter", cfg.DBAdapter, "Database adapter, one of the registered adapters: "+strings.Join(adapters.Registered(), ", "))
+	ventCmd.PersistentFlags().StringVar(&cfg.DBURL, "db-url", cfg.DBURL, "PostgreSQL database URL, SQLite db file path or MySQL data source name")
+	ventCmd.PersistentFlags().StringVar(&cfg.DBSchema, "db-schema", cfg.DBSchema, "PostgreSQL database schema, MySQL database (empty for SQLite)")
+	ventCmd.PersistentFlags().DurationVar(&cfg.SQLiteBusyTimeout, "sqlite-busy-timeout", cfg.SQLiteBusyTimeout, "How long SQLite connections wait for locks held by other connections, such as 30s (0 keeps the driver default)")
+	ventCmd.PersistentFlags().StringVar(&cfg.SQLiteSynchronous, "sqlite-synchronous", cfg.SQLiteSynchronous, "SQLite synchronous level, one of: "+strings.Join(adapters.SQLiteSynchronousLevels, ", ")+" (empty keeps the database default)")
 	ventCmd.Flags().StringVar(&cfg.GRPCAddr, "grpc-addr", cfg.GRPCAddr, "Address to connect to the Hyperledger Burrow gRPC server")
 	ventCmd.Flags().StringVar(&cfg.HTTPAddr, "http-addr", cfg.HTTPAddr, "Address to bind the HTTP server")
-	ventCmd.Flags().StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "Logging level (error, warn, info, debug)")
+	ventCmd.Flags().BoolVar(&cfg.HTTPAbiRegistration, "http-abi-registration", cfg.HTTPAbiRegistration, "Allow registering contract abis with POST /abi, which is not authenticated so http-addr should only be reachable by trusted clients (true/false)")
+	ventCmd.PersistentFlags().StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "Logging level (error, warn, info, debug)")
 	ventCmd.Flags().StringVar(&cfg.SpecFile, "spec-file", cfg.SpecFile, "SQLSol json specification file full path")
 	ventCmd.Flags().StringVar(&cfg.AbiFile, "abi-file", cfg.AbiFile, "Event Abi specification file full path")
 	ventCmd.Flags().StringVar(&cfg.AbiDir, "abi-dir", cfg.AbiDir, "Path of a folder to look for event Abi specification files")
 	ventCmd.Flags().StringVar(&cfg.SpecDir, "spec-dir", cfg.SpecDir, "Path of a folder to look for SQLSol json specification files")
+	ventCmd.Flags().StringVar(&cfg.DeployFile, "deploy-file", cfg.DeployFile, "Burrow deploy output file full path, to reference contracts by deploy job name")
+	ventCmd.Flags().StringVar(&cfg.DBNotifyChannel, "db-notify-channel", cfg.DBNotifyChannel, "PostgreSQL channel notified of every committed block (empty disables notifications, ignored by other adapters)")
 	ventCmd.Flags().BoolVar(&cfg.DBBlockTx, "db-block", cfg.DBBlockTx, "Create block & transaction tables and persist related data (true/false)")
+	ventCmd.Flags().StringSliceVar(&cfg.DBJSONIndexes, "db-json-indexes", cfg.DBJSONIndexes, "Comma separated list of block & transaction JSON columns to index with GIN indexes, such as _events,_receipt (PostgreSQL only)")
+	ventCmd.Flags().BoolVar(&cfg.AllowDestructive, "allow-destructive", cfg.AllowDestructive, "Allow schema changes that may lose data, such as dropping columns or narrowing column types (true/false)")
+	ventCmd.PersistentFlags().StringVar(&cfg.ChainIDPolicy, "chain-id-policy", cfg.ChainIDPolicy, "Behaviour when the chain ID stored in the database differs from the chain one: "+strings.Join(types.ChainIDPolicies, ", "))
+	ventCmd.PersistentFlags().StringVar(&cfg.ChainID, "chain-id", cfg.ChainID, "Chain ID whose schema maintenance commands open with chain-id-policy namespace (vent reads it from the chain)")
+	ventCmd.Flags().Uint64Var(&cfg.LogRetention, "log-retention", cfg.LogRetention, "Number of blocks kept with full detail in the log table, older entries are compacted to one checkpoint per block (0 keeps everything)")
+	ventCmd.Flags().StringVar(&cfg.LogArchiveDir, "log-archive-dir", cfg.LogArchiveDir, "Path of a folder to archive compacted log entries to (json lines files)")
+	ventCmd.Flags().StringVar(&cfg.SchemaDriftPolicy, "schema-drift-policy", cfg.SchemaDriftPolicy, "Behaviour when event tables differ from the structure stored in the dictionary: "+strings.Join(types.SchemaDriftPolicies, ", "))
+	ventCmd.Flags().DurationVar(&cfg.CheckpointInterval, "sqlite-checkpoint-interval", cfg.CheckpointInterval, "Interval between SQLite write-ahead log checkpoints truncating the log (0 leaves checkpoints to SQLite)")
+	ventCmd.Flags().DurationVar(&cfg.SchemaCheckInterval, "schema-check-interval", cfg.SchemaCheckInterval, "Interval between schema drift checks, besides the startup one (0 disables periodic checks)")
 }
 
 // Execute executes the vent command
@@ -62,7 +80,7 @@ func runVentCmd(cmd *cobra.Command, args []string) {
 	var wg sync.WaitGroup
 
 	// setup channel for termination signals
-	ch := make(chan os.Signal)
+	ch := make(chan os.Signal, 1)
 
 	signal.Notify(ch, syscall.SIGTERM)
 	signal.Notify(ch, syscall.SIGINT)
@@ -99,3 +117,25 @@ func runVentCmd(cmd *cobra.Command, args []string) {
 	wg.Wait()
 	os.Exit(0)
 }
+
+// openDB opens a database connection for maintenance commands (not bound to a chain),
+// with chain ID policy namespace the schema of the chain given by chain-id is opened (<schema>_<chain ID>)
+func openDB(schema string, log *logger.Logger) (*sqldb.SQLDB, error) {
+	if cfg.ChainIDPolicy == types.ChainIDPolicyNamespace && cfg.ChainID == "" {
+		return nil, fmt.Errorf("chain-id must be provided with chain-id-policy %s", types.ChainIDPolicyNamespace)
+	}
+
+	// the chain ID is not given to the connection, so the chain ID policy is never applied here
+	if cfg.ChainIDPolicy == types.ChainIDPolicyNamespace {
+		schema = sqldb.NamespacedSchema(schema, cfg.ChainID)
+	}
+
+	return sqldb.NewSQLDB(types.SQLConnection{
+		DBAdapter:         cfg.DBAdapter,
+		DBURL:             cfg.DBURL,
+		DBSchema:          schema,
+		Log:               log,
+		SQLiteBusyTimeout: cfg.SQLiteBusyTimeout,
+		SQLiteSynchronous: cfg.SQLiteSynchronous,
+	})
+}
@@ -7,9 +7,10 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/hyperledger/burrow/execution/evm/abi"
+	"github.com/hyperledger/burrow/crypto"
 	"github.com/hyperledger/burrow/rpc/rpcevents"
 	"github.com/hyperledger/burrow/rpc/rpcquery"
 	"github.com/monax/bosmarmot/vent/config"
@@ -22,6 +23,9 @@ import (
 	"google.golang.org/grpc/connectivity"
 )
 
+// logCompactionBlocks is the minimum number of blocks between log compactions
+const logCompactionBlocks = 100
+
 // Consumer contains basic configuration for consumer to run
 type Consumer struct {
 	Config         *config.Flags
@@ -31,6 +35,13 @@ type Consumer struct {
 	GRPCConnection *grpc.ClientConn
 	// external events channel used for when vent is leveraged as a library
 	EventsChannel chan types.EventData
+	// height of the last log compaction
+	compactedHeight uint64
+	// number of differences found by the last schema check (accessed atomically)
+	schemaDrift int64
+	// abi registry set once the database is open, read by the http server too
+	abiRegistry *sqlsol.AbiRegistry
+	abiMtx      sync.RWMutex
 }
 
 // NewConsumer constructs a new consumer configuration
@@ -46,10 +57,16 @@ func NewConsumer(cfg *config.Flags, log *logger.Logger, eChannel chan types.Even
 // Run connects to a grpc service and subscribes to log events,
 // then gets tables structures, maps them & parse event data.
 // Store data in SQL event tables, it runs forever
-func (c *Consumer) Run(parser *sqlsol.Parser, abiSpec *abi.AbiSpec, stream bool) error {
+func (c *Consumer) Run(parser *sqlsol.Parser, abiSpec *sqlsol.AbiSpec, stream bool) error {
 
 	var err error
 
+	switch c.Config.SchemaDriftPolicy {
+	case "", types.SchemaDriftPolicyWarn, types.SchemaDriftPolicyRepair, types.SchemaDriftPolicyRefuse:
+	default:
+		return fmt.Errorf("invalid schema drift policy %s, valid policies are: %s", c.Config.SchemaDriftPolicy, strings.Join(types.SchemaDriftPolicies, ", "))
+	}
+
 	c.Log.Info("msg", "Connecting to Burrow gRPC server")
 
 	c.GRPCConnection, err = grpc.Dial(c.Config.GRPCAddr, grpc.WithInsecure())
@@ -65,6 +82,23 @@ func (c *Consumer) Run(parser *sqlsol.Parser, abiSpec *abi.AbiSpec, stream bool)
 		return errors.Wrapf(err, "Error getting chain status")
 	}
 
+	// bind contract addresses (given directly or by deploy job name) to event specifications
+	deployed, err := sqlsol.DeployLoader(c.Config.DeployFile)
+	if err != nil {
+		return errors.Wrap(err, "Error loading deploy output file")
+	}
+
+	if err = parser.BindContracts(deployed); err != nil {
+		return errors.Wrap(err, "Error binding contracts to event specifications")
+	}
+
+	// block & tx tables are not declared in specifications, their indexes are configured
+	if len(c.Config.DBJSONIndexes) > 0 {
+		if err = parser.IndexColumns([]string{types.SQLBlockTableName, types.SQLTxTableName}, c.Config.DBJSONIndexes, types.SQLIndexGIN); err != nil {
+			return errors.Wrap(err, "Error indexing block & transaction columns")
+		}
+	}
+
 	// obtain tables structures, event & abi specifications
 	tables := parser.GetTables()
 	eventSpec := parser.GetEventSpec()
@@ -77,12 +111,17 @@ func (c *Consumer) Run(parser *sqlsol.Parser, abiSpec *abi.AbiSpec, stream bool)
 	c.Log.Info("msg", "Connecting to SQL database")
 
 	connection := types.SQLConnection{
-		DBAdapter:     c.Config.DBAdapter,
-		DBURL:         c.Config.DBURL,
-		DBSchema:      c.Config.DBSchema,
-		Log:           c.Log,
-		ChainID:       chainStatus.ChainID,
-		BurrowVersion: chainStatus.BurrowVersion,
+		DBAdapter:         c.Config.DBAdapter,
+		DBURL:             c.Config.DBURL,
+		DBSchema:          c.Config.DBSchema,
+		Log:               c.Log,
+		ChainID:           chainStatus.ChainID,
+		BurrowVersion:     chainStatus.BurrowVersion,
+		AllowDestructive:  c.Config.AllowDestructive,
+		ChainIDPolicy:     c.Config.ChainIDPolicy,
+		NotifyChannel:     c.Config.DBNotifyChannel,
+		SQLiteBusyTimeout: c.Config.SQLiteBusyTimeout,
+		SQLiteSynchronous: c.Config.SQLiteSynchronous,
 	}
 
 	c.DB, err = sqldb.NewSQLDB(connection)
@@ -98,6 +137,16 @@ func (c *Consumer) Run(parser *sqlsol.Parser, abiSpec *abi.AbiSpec, stream bool)
 		return errors.Wrap(err, "Error trying to synchronize database")
 	}
 
+	if err = c.checkSchema(); err != nil {
+		return errors.Wrap(err, "Error checking schema")
+	}
+
+	c.Log.Info("msg", "Loading abi registry")
+
+	if err = c.loadAbiRegistry(abiSpec, deployed); err != nil {
+		return errors.Wrap(err, "Error loading abi registry")
+	}
+
 	// doneCh is used for sending a "done" signal from each goroutine to the main thread
 	// eventCh is used for sending received events to the main thread to be stored in the db
 	doneCh := make(chan error)
@@ -218,6 +267,15 @@ func (c *Consumer) Run(parser *sqlsol.Parser, abiSpec *abi.AbiSpec, stream bool)
 
 						// see which spec filter matches with the one in event data
 						for _, spec := range eventSpec {
+							// skip tables not yet started or bound to other contracts
+							if !spec.MatchesHeight(resp.Height) {
+								continue
+							}
+
+							if eventLog := event.GetLog(); eventLog != nil && !spec.MatchesContract(eventLog.Address) {
+								continue
+							}
+
 							qry, err := spec.Query()
 
 							if err != nil {
@@ -231,7 +289,11 @@ func (c *Consumer) Run(parser *sqlsol.Parser, abiSpec *abi.AbiSpec, stream bool)
 								c.Log.Info("msg", fmt.Sprintf("Matched event header: %v", event.Header), "filter", spec.Filter)
 
 								// unpack, decode & build event data
-								eventData, err := buildEventData(spec, parser, event, abiSpec, c.Log)
+								eventData, err := buildEventData(spec, parser, event, c.AbiRegistry(), c.Log)
+								if errors.Cause(err) == errLogMismatch {
+									c.Log.Info("msg", "Skipping log", "err", err, "filter", spec.Filter)
+									continue
+								}
 								if err != nil {
 									doneCh <- errors.Wrapf(err, "Error building event data")
 								}
@@ -264,6 +326,22 @@ func (c *Consumer) Run(parser *sqlsol.Parser, abiSpec *abi.AbiSpec, stream bool)
 		doneCh <- nil
 	}()
 
+	// periodic schema checks run in the main thread, between blocks
+	var schemaCheckCh <-chan time.Time
+	if c.Config.SchemaCheckInterval > 0 {
+		ticker := time.NewTicker(c.Config.SchemaCheckInterval)
+		defer ticker.Stop()
+		schemaCheckCh = ticker.C
+	}
+
+	// periodic checkpoints run in the main thread too, so they never wait for a block being written
+	var checkpointCh <-chan time.Time
+	if c.Config.CheckpointInterval > 0 {
+		ticker := time.NewTicker(c.Config.CheckpointInterval)
+		defer ticker.Stop()
+		checkpointCh = ticker.C
+	}
+
 loop:
 	for {
 		select {
@@ -278,11 +356,24 @@ loop:
 				return errors.Wrap(err, "Error upserting rows in SQL event tables")
 			}
 
+			// compact log entries older than the retention period
+			if err := c.compactLog(blk.Block); err != nil {
+				return errors.Wrap(err, "Error compacting log")
+			}
+
 			// send to the external events channel in a non-blocking manner
 			select {
 			case c.EventsChannel <- blk:
 			default:
 			}
+		case <-schemaCheckCh:
+			if err := c.checkSchema(); err != nil {
+				return errors.Wrap(err, "Error checking schema")
+			}
+		case <-checkpointCh:
+			if err := c.DB.Checkpoint(); err != nil {
+				return errors.Wrap(err, "Error checkpointing database")
+			}
 		}
 	}
 
@@ -290,6 +381,115 @@ loop:
 	return nil
 }
 
+// compactLog compacts the log table up to the retention period every logCompactionBlocks blocks
+func (c *Consumer) compactLog(block string) error {
+	if c.Config.LogRetention == 0 {
+		return nil
+	}
+
+	height, err := strconv.ParseUint(block, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	if height <= c.Config.LogRetention || height < c.compactedHeight+logCompactionBlocks {
+		return nil
+	}
+
+	c.compactedHeight = height
+	return c.DB.CompactLog(height-c.Config.LogRetention, c.Config.LogArchiveDir)
+}
+
+// checkSchema compares event tables with the database catalog & applies the schema drift policy,
+// drift is reported through health & metrics
+func (c *Consumer) checkSchema() error {
+	check := c.DB.CheckSchema
+	if c.Config.SchemaDriftPolicy == types.SchemaDriftPolicyRepair {
+		check = c.DB.RepairSchema
+	}
+
+	drifts, err := check()
+	if err != nil {
+		return err
+	}
+
+	schemaChecksCounter.Inc()
+	schemaDriftGauge.Set(float64(len(drifts)))
+	atomic.StoreInt64(&c.schemaDrift, int64(len(drifts)))
+
+	if len(drifts) > 0 && c.Config.SchemaDriftPolicy == types.SchemaDriftPolicyRefuse {
+		return fmt.Errorf("error schema drift found: %d differences between the dictionary and the database catalog, first one is %s", len(drifts), drifts[0])
+	}
+
+	return nil
+}
+
+// loadAbiRegistry builds the abi registry from the global abi specification,
+// contract abi specifications stored in the database & deploy artifacts (which are stored too)
+func (c *Consumer) loadAbiRegistry(abiSpec *sqlsol.AbiSpec, deployed map[string]crypto.Address) error {
+	abiRegistry := sqlsol.NewAbiRegistry(abiSpec)
+
+	abis, err := c.DB.GetAbis()
+	if err != nil {
+		return errors.Wrap(err, "Error getting stored abi specifications")
+	}
+
+	for address, abiJSON := range abis {
+		contract, err := crypto.AddressFromHexString(address)
+		if err != nil {
+			return errors.Wrapf(err, "Error parsing stored contract address %s", address)
+		}
+
+		if err = abiRegistry.Register(contract, []byte(abiJSON)); err != nil {
+			return err
+		}
+	}
+
+	registered, err := abiRegistry.RegisterDeployed(deployed, c.Config.AbiDir)
+	if err != nil {
+		return errors.Wrap(err, "Error registering deployed contracts abi specifications")
+	}
+
+	for contract, abiJSON := range registered {
+		if err = c.DB.SetAbi(contract.String(), string(abiJSON)); err != nil {
+			return errors.Wrapf(err, "Error storing abi specification for contract %s", contract)
+		}
+	}
+
+	c.abiMtx.Lock()
+	c.abiRegistry = abiRegistry
+	c.abiMtx.Unlock()
+	return nil
+}
+
+// AbiRegistry returns the abi registry, nil until the consumer has loaded it
+func (c *Consumer) AbiRegistry() *sqlsol.AbiRegistry {
+	c.abiMtx.RLock()
+	defer c.abiMtx.RUnlock()
+
+	return c.abiRegistry
+}
+
+// RegisterAbi binds an abi specification to a contract address & stores it in the database
+func (c *Consumer) RegisterAbi(address string, abiJSON []byte) error {
+	// the registry is loaded once the database is open
+	abiRegistry := c.AbiRegistry()
+	if abiRegistry == nil {
+		return errors.New("abi registry not ready")
+	}
+
+	contract, err := crypto.AddressFromHexString(address)
+	if err != nil {
+		return errors.Wrapf(err, "Error parsing contract address %s", address)
+	}
+
+	if err = abiRegistry.Register(contract, abiJSON); err != nil {
+		return err
+	}
+
+	return c.DB.SetAbi(contract.String(), string(abiJSON))
+}
+
 // Health returns the health status for the consumer
 func (c *Consumer) Health() error {
 	if c.Closing {
@@ -305,6 +505,10 @@ func (c *Consumer) Health() error {
 		return errors.New("database unavailable")
 	}
 
+	if drift := atomic.LoadInt64(&c.schemaDrift); drift > 0 {
+		return fmt.Errorf("schema drift found (%d differences)", drift)
+	}
+
 	// check grpc connection status
 	if c.GRPCConnection == nil {
 		return errors.New("grpc disconnected")
@@ -7,35 +7,106 @@ import (
 	"github.com/hyperledger/burrow/crypto"
 	"github.com/hyperledger/burrow/execution/evm/abi"
 	"github.com/hyperledger/burrow/execution/exec"
+	"github.com/monax/bosmarmot/vent/sqlsol"
 	"github.com/monax/bosmarmot/vent/types"
 	"github.com/pkg/errors"
 )
 
-// decodeEvent unpacks & decodes event data
-func decodeEvent(header *exec.Header, log *exec.LogEvent, abiSpec *abi.AbiSpec) (map[string]interface{}, error) {
-	// to prepare decoded data and map to event item name
-	data := make(map[string]interface{})
+// errLogMismatch is returned when a log can't hold the event it is decoded with,
+// such logs are skipped (e.g. other events emitted by a contract bound to an anonymous event)
+var errLogMismatch = errors.New("log does not match event")
+
+// getEventSpec returns the abi specification used to decode a log for a given event definition,
+// it returns nil if the event definition only maps raw log topics & data
+func getEventSpec(spec types.EventDefinition, log *exec.LogEvent, abiRegistry *sqlsol.AbiRegistry) (*abi.EventSpec, error) {
+	if spec.IsRaw() {
+		return nil, nil
+	}
+
+	// an explicit event name is given, needed for anonymous events
+	if spec.Event != "" {
+		evAbi, ok := abiRegistry.GetEventSpecByName(log.Address, spec.Event)
+		if !ok {
+			return nil, fmt.Errorf("Abi spec not found for event %s", spec.Event)
+		}
+
+		if evAbi.Anonymous {
+			if len(spec.Contracts) == 0 {
+				return nil, fmt.Errorf("Anonymous event %s must be bound to a contract", spec.Event)
+			}
+			// anonymous events have no event ID, each topic holds an indexed input
+			if indexed := countIndexedInputs(evAbi); len(log.Topics) != indexed {
+				return nil, errors.Wrapf(errLogMismatch, "anonymous event %s has %d indexed inputs, log has %d topics", spec.Event, indexed, len(log.Topics))
+			}
+			return &evAbi, nil
+		}
+
+		// other events of the contract (matching the same filter) are skipped
+		if len(log.Topics) == 0 || abi.EventID(log.Topics[0]) != evAbi.EventID {
+			return nil, errors.Wrapf(errLogMismatch, "log is not an event %s", spec.Event)
+		}
+		return checkTopics(evAbi, log)
+	}
+
+	if len(log.Topics) == 0 {
+		return nil, fmt.Errorf("Abi spec not found for log without topics, an event name must be given")
+	}
 
 	var eventID abi.EventID
-	var evAbi abi.EventSpec
 	copy(eventID[:], log.Topics[0].Bytes())
 
-	evAbi, ok := abiSpec.EventsById[eventID]
+	evAbi, ok := abiRegistry.GetEventSpec(log.Address, eventID)
 	if !ok {
 		return nil, fmt.Errorf("Abi spec not found for event %x", eventID)
 	}
 
+	return checkTopics(evAbi, log)
+}
+
+// checkTopics returns the abi specification of a regular event if the log holds
+// its event ID and a topic for each indexed input, events sharing an event ID
+// with a different number of indexed inputs can't be unpacked from the log
+func checkTopics(evAbi abi.EventSpec, log *exec.LogEvent) (*abi.EventSpec, error) {
+	if indexed := countIndexedInputs(evAbi); len(log.Topics) != indexed+1 {
+		return nil, errors.Wrapf(errLogMismatch, "event %s has %d indexed inputs, log has %d topics", evAbi.Name, indexed, len(log.Topics))
+	}
+	return &evAbi, nil
+}
+
+// countIndexedInputs returns the number of indexed inputs of an event
+func countIndexedInputs(evAbi abi.EventSpec) int {
+	indexed := 0
+	for _, input := range evAbi.Inputs {
+		if input.Indexed {
+			indexed++
+		}
+	}
+	return indexed
+}
+
+// decodeEvent unpacks & decodes event data using the given abi specification,
+// only header data is returned if evAbi is nil
+func decodeEvent(header *exec.Header, log *exec.LogEvent, evAbi *abi.EventSpec) (map[string]interface{}, error) {
+	// to prepare decoded data and map to event item name
+	data := make(map[string]interface{})
+
 	// decode header to get context data for each event
-	data[types.EventNameLabel] = evAbi.Name
+	data[types.EventNameLabel] = ""
 	data[types.BlockHeightLabel] = fmt.Sprintf("%v", header.GetHeight())
 	data[types.EventTypeLabel] = header.GetEventType().String()
 	data[types.TxTxHashLabel] = header.TxHash.String()
 
+	if evAbi == nil {
+		return data, nil
+	}
+
+	data[types.EventNameLabel] = evAbi.Name
+
 	// build expected interface type array to get log event values
 	unpackedData := abi.GetPackingTypes(evAbi.Inputs)
 
 	// unpack event data (topics & data part)
-	if err := abi.UnpackEvent(evAbi, log.Topics, log.Data, unpackedData...); err != nil {
+	if err := abi.UnpackEvent(*evAbi, log.Topics, log.Data, unpackedData...); err != nil {
 		return nil, errors.Wrap(err, "Could not unpack event data")
 	}
 
@@ -53,3 +124,19 @@ func decodeEvent(header *exec.Header, log *exec.LogEvent, abiSpec *abi.AbiSpec)
 
 	return data, nil
 }
+
+// getRawLogValue returns a raw log topic or data (named by a raw log label) as a hex string,
+// nil if the log has no such topic
+func getRawLogValue(log *exec.LogEvent, label string) interface{} {
+	if label == types.LogDataLabel {
+		return log.Data.String()
+	}
+
+	for i, topicLabel := range types.LogTopicLabels {
+		if label == topicLabel && i < len(log.Topics) {
+			return fmt.Sprintf("%X", log.Topics[i].Bytes())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/hyperledger/burrow/binary"
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/burrow/execution/exec"
+	"github.com/monax/bosmarmot/vent/logger"
+	"github.com/monax/bosmarmot/vent/sqlsol"
+	"github.com/monax/bosmarmot/vent/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// decoderAbi declares an anonymous event and a regular one with the same inputs
+const decoderAbi = `[
+	{"anonymous": true, "inputs": [{"indexed": true, "name": "from", "type": "address"}, {"indexed": false, "name": "value", "type": "uint256"}], "name": "Transfer", "type": "event"},
+	{"anonymous": false, "inputs": [{"indexed": true, "name": "owner", "type": "address"}, {"indexed": false, "name": "value", "type": "uint256"}], "name": "Approval", "type": "event"}
+]`
+
+// decoderSpec maps raw logs, the anonymous event & the regular event of a bound contract,
+// and events of any contract looked up by event ID
+const decoderSpec = `[
+	{
+		"TableName" : "RawLogs",
+		"Filter" : "EventType = 'LogEvent'",
+		"Columns"  : {
+			"topic0" : {"name" : "topic0", "primary" : false, "raw" : true},
+			"topic1" : {"name" : "topic1", "primary" : false, "raw" : true},
+			"data"	 : {"name" : "data", "primary" : false, "raw" : true}
+		}
+	},
+	{
+		"TableName" : "AnonymousTransfers",
+		"Filter" : "EventType = 'LogEvent'",
+		"Contracts" : ["1AEEFD3783050219C3988098E152A11F02C4F4C4"],
+		"Event" : "Transfer",
+		"Columns"  : {
+			"from"  : {"name" : "from", "type": "address", "primary" : true},
+			"value" : {"name" : "value", "type": "uint256", "primary" : false},
+			"data"	: {"name" : "raw_data", "primary" : false, "raw" : true}
+		}
+	},
+	{
+		"TableName" : "Approvals",
+		"Filter" : "EventType = 'LogEvent'",
+		"Contracts" : ["1AEEFD3783050219C3988098E152A11F02C4F4C4"],
+		"Event" : "Approval",
+		"Columns"  : {
+			"owner" : {"name" : "owner", "type": "address", "primary" : true},
+			"value" : {"name" : "value", "type": "uint256", "primary" : false}
+		}
+	},
+	{
+		"TableName" : "Events",
+		"Filter" : "EventType = 'LogEvent'",
+		"Columns"  : {
+			"owner" : {"name" : "owner", "type": "address", "primary" : true},
+			"value" : {"name" : "value", "type": "uint256", "primary" : false}
+		}
+	}
+]`
+
+func TestBuildEventData(t *testing.T) {
+	parser, err := sqlsol.NewParserFromBytes([]byte(decoderSpec))
+	require.NoError(t, err)
+	abiSpec, err := sqlsol.ReadAbiSpec([]byte(decoderAbi))
+	require.NoError(t, err)
+	abiRegistry := sqlsol.NewAbiRegistry(abiSpec)
+
+	specs := make(map[string]types.EventDefinition)
+	for _, spec := range parser.GetEventSpec() {
+		specs[spec.TableName] = spec
+	}
+
+	contract, err := crypto.AddressFromHexString("1AEEFD3783050219C3988098E152A11F02C4F4C4")
+	require.NoError(t, err)
+	account, err := crypto.AddressFromHexString("2BEEFD3783050219C3988098E152A11F02C4F4C4")
+	require.NoError(t, err)
+
+	value := binary.LeftPadWord256(big.NewInt(5).Bytes()).Bytes()
+	accountTopic := binary.LeftPadWord256(account.Bytes())
+
+	// anonymous events have no event ID topic
+	transfer := logEvent(contract, value, accountTopic)
+	approval := logEvent(contract, value, binary.Word256(abiSpec.Events["Approval"].EventID), accountTopic)
+
+	t.Run("successfully decodes an anonymous event", func(t *testing.T) {
+		row, err := buildEventData(specs["AnonymousTransfers"], parser, transfer, abiRegistry, logger.NewLogger("error"))
+		require.NoError(t, err)
+		require.Equal(t, types.ActionUpsert, row.Action)
+		require.Equal(t, account.String(), row.RowData["from"])
+		require.Equal(t, "5", row.RowData["value"])
+		require.Equal(t, transfer.Log.Data.String(), row.RowData["raw_data"])
+	})
+
+	t.Run("successfully decodes a regular event named by the spec", func(t *testing.T) {
+		row, err := buildEventData(specs["Approvals"], parser, approval, abiRegistry, logger.NewLogger("error"))
+		require.NoError(t, err)
+		require.Equal(t, account.String(), row.RowData["owner"])
+		require.Equal(t, "5", row.RowData["value"])
+	})
+
+	t.Run("successfully stores raw log topics & data", func(t *testing.T) {
+		row, err := buildEventData(specs["RawLogs"], parser, approval, abiRegistry, logger.NewLogger("error"))
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{
+			"topic0": fmt.Sprintf("%X", approval.Log.Topics[0].Bytes()),
+			"topic1": fmt.Sprintf("%X", approval.Log.Topics[1].Bytes()),
+			"data":   approval.Log.Data.String(),
+		}, rawValues(row))
+	})
+
+	t.Run("skips logs of other events of a bound contract", func(t *testing.T) {
+		// a regular event has one topic more than the anonymous event
+		_, err := buildEventData(specs["AnonymousTransfers"], parser, approval, abiRegistry, logger.NewLogger("error"))
+		require.Equal(t, errLogMismatch, errors.Cause(err))
+
+		// the anonymous event has no event ID
+		_, err = buildEventData(specs["Approvals"], parser, transfer, abiRegistry, logger.NewLogger("error"))
+		require.Equal(t, errLogMismatch, errors.Cause(err))
+	})
+
+	t.Run("skips logs with an event ID but fewer topics than indexed inputs", func(t *testing.T) {
+		// e.g. another contract emits an event with the same signature hash but no indexed input
+		collision := logEvent(account, value, binary.Word256(abiSpec.Events["Approval"].EventID))
+
+		_, err := buildEventData(specs["Approvals"], parser, collision, abiRegistry, logger.NewLogger("error"))
+		require.Equal(t, errLogMismatch, errors.Cause(err))
+
+		_, err = buildEventData(specs["Events"], parser, collision, abiRegistry, logger.NewLogger("error"))
+		require.Equal(t, errLogMismatch, errors.Cause(err))
+	})
+}
+
+// logEvent returns a log event emitted by a contract
+func logEvent(address crypto.Address, data []byte, topics ...binary.Word256) *exec.Event {
+	return &exec.Event{
+		Header: &exec.Header{EventType: exec.TypeLog, Height: 10, TxHash: []byte{1, 2, 3}},
+		Log:    &exec.LogEvent{Address: address, Data: data, Topics: topics},
+	}
+}
+
+// rawValues returns the raw log columns of a row
+func rawValues(row types.EventDataRow) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, label := range append(types.LogTopicLabels, types.LogDataLabel) {
+		if value, ok := row.RowData[label]; ok {
+			values[label] = value
+		}
+	}
+	return values
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsRegistry holds the metrics exposed on /metrics
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	schemaDriftGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "vent",
+		Name:      "schema_drift",
+		Help:      "Number of differences found between the dictionary and the database catalog by the last schema check",
+	})
+
+	schemaChecksCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "vent",
+		Name:      "schema_checks_total",
+		Help:      "Number of schema checks run",
+	})
+)
+
+func init() {
+	metricsRegistry.MustRegister(schemaDriftGauge, schemaChecksCounter)
+}
+
+// metricsHandler returns an HTTP handler exposing the metrics in Prometheus format
+func metricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
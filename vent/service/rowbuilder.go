@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/hyperledger/burrow/execution/evm/abi"
 	"github.com/hyperledger/burrow/execution/exec"
 	"github.com/monax/bosmarmot/vent/logger"
 	"github.com/monax/bosmarmot/vent/sqlsol"
@@ -13,8 +12,11 @@ import (
 	"github.com/pkg/errors"
 )
 
+// blockTimeLayout is the layout of block times stored in block table
+const blockTimeLayout = "2006-01-02 15:04:05.000000"
+
 // buildEventData builds event data from transactions
-func buildEventData(spec types.EventDefinition, parser *sqlsol.Parser, event *exec.Event, abiSpec *abi.AbiSpec, l *logger.Logger) (types.EventDataRow, error) {
+func buildEventData(spec types.EventDefinition, parser *sqlsol.Parser, event *exec.Event, abiRegistry *sqlsol.AbiRegistry, l *logger.Logger) (types.EventDataRow, error) {
 
 	// a fresh new row to store column/value data
 	row := make(map[string]interface{})
@@ -26,12 +28,27 @@ func buildEventData(spec types.EventDefinition, parser *sqlsol.Parser, event *ex
 	eventHeader := event.GetHeader()
 	eventLog := event.GetLog()
 
+	if eventLog == nil {
+		return types.EventDataRow{}, fmt.Errorf("Event is not a log event (filter: %s)", spec.Filter)
+	}
+
+	// get the abi specification needed to decode the event (if any)
+	evAbi, err := getEventSpec(spec, eventLog, abiRegistry)
+	if err != nil {
+		return types.EventDataRow{}, errors.Wrapf(err, "Error decoding event (filter: %s)", spec.Filter)
+	}
+
 	// decode event data using the provided abi specification
-	decodedData, err := decodeEvent(eventHeader, eventLog, abiSpec)
+	decodedData, err := decodeEvent(eventHeader, eventLog, evAbi)
 	if err != nil {
 		return types.EventDataRow{}, errors.Wrapf(err, "Error decoding event (filter: %s)", spec.Filter)
 	}
 
+	// name of the contract declaring the event (if known from abi artifacts)
+	if evAbi != nil {
+		decodedData[types.EventContractLabel] = abiRegistry.GetContractName(eventLog.Address, evAbi.EventID)
+	}
+
 	l.Info("msg", fmt.Sprintf("Unpacked data: %v", decodedData), "eventName", decodedData[types.EventNameLabel])
 
 	rowAction := types.ActionUpsert
@@ -57,7 +74,7 @@ func buildEventData(spec types.EventDefinition, parser *sqlsol.Parser, event *ex
 				}
 			}
 		}
-		if column, err := parser.GetColumn(spec.TableName, k); err == nil {
+		if column, err := parser.GetColumn(spec.TableName, k); err == nil && !column.Raw {
 			if column.BytesToString {
 				if bytes, ok := v.(*[]byte); ok {
 					str := strings.Trim(string(*bytes), "\x00")
@@ -69,6 +86,13 @@ func buildEventData(spec types.EventDefinition, parser *sqlsol.Parser, event *ex
 		}
 	}
 
+	// raw log topics & data are stored as they are found in the log
+	for colName, col := range spec.Columns {
+		if value := getRawLogValue(eventLog, colName); col.Raw && value != nil {
+			row[strings.ToLower(col.Name)] = value
+		}
+	}
+
 	return types.EventDataRow{Action: rowAction, RowData: row}, nil
 }
 
@@ -88,6 +112,10 @@ func buildBlkData(tbls types.EventTables, block *exec.BlockExecution) (types.Eve
 
 		row[tbl.Columns[types.BlockHeightLabel].Name] = fmt.Sprintf("%v", block.Height)
 		row[tbl.Columns[types.BlockHeaderLabel].Name] = string(blockHeader)
+
+		if block.BlockHeader != nil {
+			row[tbl.Columns[types.BlockTimeLabel].Name] = block.BlockHeader.Time.UTC().Format(blockTimeLayout)
+		}
 	} else {
 		return types.EventDataRow{}, fmt.Errorf("table: %s not found in table structure %v", types.SQLBlockTableName, tbls)
 	}
@@ -138,6 +166,13 @@ func buildTxData(tbls types.EventTables, txe *exec.TxExecution) (types.EventData
 		row[tbl.Columns[types.TxResultLabel].Name] = string(result)
 		row[tbl.Columns[types.TxReceiptLabel].Name] = string(receipt)
 		row[tbl.Columns[types.TxExceptionLabel].Name] = string(exception)
+
+		// the caller is the first input of the transaction
+		if txe.Envelope != nil && txe.Envelope.Tx != nil && txe.Envelope.Tx.Payload != nil {
+			if inputs := txe.Envelope.Tx.GetInputs(); len(inputs) > 0 {
+				row[tbl.Columns[types.TxCallerLabel].Name] = inputs[0].Address.String()
+			}
+		}
 	} else {
 		return types.EventDataRow{}, fmt.Errorf("Table: %s not found in table structure %v", types.SQLTxTableName, tbls)
 	}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 
 	"github.com/monax/bosmarmot/vent/config"
@@ -23,6 +24,8 @@ func NewServer(cfg *config.Flags, log *logger.Logger, consumer *Consumer) *Serve
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/health", healthHandler(log, consumer))
+	mux.HandleFunc("/abi", abiHandler(log, consumer, cfg.HTTPAbiRegistration))
+	mux.Handle("/metrics", metricsHandler())
 
 	return &Server{
 		Config:   cfg,
@@ -75,3 +78,65 @@ func healthHandler(log *logger.Logger, consumer *Consumer) func(resp http.Respon
 		log.Info("msg", "GET /health", "err", err)
 	}
 }
+
+// maxAbiRequestBytes limits the size of abi registration requests
+const maxAbiRequestBytes = 1 << 20
+
+// abiRequest is the body expected to register the abi specification of a contract
+type abiRequest struct {
+	Address string          `json:"address"`
+	Abi     json.RawMessage `json:"abi"`
+}
+
+// abiHandler lists (GET) contract addresses in the abi registry
+// or registers (POST) the abi specification of a contract if registration is allowed
+func abiHandler(log *logger.Logger, consumer *Consumer, allowRegistration bool) func(resp http.ResponseWriter, req *http.Request) {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			abiRegistry := consumer.AbiRegistry()
+			if abiRegistry == nil {
+				resp.WriteHeader(http.StatusServiceUnavailable)
+				log.Info("msg", "GET /abi", "err", "abi registry not ready")
+				return
+			}
+
+			addresses := make([]string, 0)
+			for _, address := range abiRegistry.Addresses() {
+				addresses = append(addresses, address.String())
+			}
+
+			resp.Header().Set("Content-Type", "application/json")
+			err := json.NewEncoder(resp).Encode(addresses)
+			log.Info("msg", "GET /abi", "err", err)
+
+		case http.MethodPost:
+			if !allowRegistration {
+				resp.WriteHeader(http.StatusForbidden)
+				log.Info("msg", "POST /abi", "err", "abi registration not allowed")
+				return
+			}
+
+			var body abiRequest
+
+			req.Body = http.MaxBytesReader(resp, req.Body, maxAbiRequestBytes)
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				resp.WriteHeader(http.StatusBadRequest)
+				log.Info("msg", "POST /abi", "err", err)
+				return
+			}
+
+			if err := consumer.RegisterAbi(body.Address, body.Abi); err != nil {
+				resp.WriteHeader(http.StatusBadRequest)
+				log.Info("msg", "POST /abi", "err", err)
+				return
+			}
+
+			resp.WriteHeader(http.StatusOK)
+			log.Info("msg", "POST /abi", "address", body.Address)
+
+		default:
+			resp.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
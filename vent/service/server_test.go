@@ -3,10 +3,12 @@
 package service_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -32,6 +34,7 @@ func TestServer(t *testing.T) {
 	cfg.SpecFile = os.Getenv("GOPATH") + "/src/github.com/monax/bosmarmot/vent/test/sqlsol_example.json"
 	cfg.AbiFile = os.Getenv("GOPATH") + "/src/github.com/monax/bosmarmot/vent/test/EventsTest.abi"
 	cfg.GRPCAddr = testConfig.RPC.GRPC.ListenAddress
+	cfg.HTTPAbiRegistration = true
 
 	log := logger.NewLogger(cfg.LogLevel)
 	consumer := service.NewConsumer(cfg, log, make(chan types.EventData))
@@ -64,6 +67,40 @@ func TestServer(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, http.StatusOK, resp.StatusCode)
 
+	// register a contract abi and list registered contracts
+	abiURL := fmt.Sprintf("%s/abi", httpServer.URL)
+	address := "1AEEFD3783050219C3988098E152A11F02C4F4C4"
+	body := fmt.Sprintf(`{"address": "%s", "abi": %s}`, address, test.Abi_EventsTest)
+
+	resp, err = http.Post(abiURL, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(abiURL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var addresses []string
+	err = json.NewDecoder(resp.Body).Decode(&addresses)
+	require.NoError(t, err)
+	require.Equal(t, []string{address}, addresses)
+
+	// oversized bodies are rejected
+	oversized := fmt.Sprintf(`{"address": "%s", "abi": "%s"}`, address, strings.Repeat("x", 2<<20))
+	resp, err = http.Post(abiURL, "application/json", strings.NewReader(oversized))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// registration is refused unless explicitly allowed
+	readOnlyCfg := *cfg
+	readOnlyCfg.HTTPAbiRegistration = false
+	readOnlyServer := httptest.NewServer(service.NewServer(&readOnlyCfg, log, consumer))
+	defer readOnlyServer.Close()
+
+	resp, err = http.Post(fmt.Sprintf("%s/abi", readOnlyServer.URL), "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
 	// shutdown consumer and wait for its end
 	consumer.Shutdown()
 	wg.Wait()
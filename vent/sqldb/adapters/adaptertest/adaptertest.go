@@ -0,0 +1,362 @@
+// Package adaptertest is a conformance test suite for db adapters, it checks the SQL generated by a
+// registered adapter against a golden file and runs every adapter feature against a live database
+package adaptertest
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/monax/bosmarmot/vent/logger"
+	"github.com/monax/bosmarmot/vent/sqldb"
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
+	"github.com/monax/bosmarmot/vent/types"
+	"github.com/stretchr/testify/require"
+)
+
+// Suite describes the adapter under test
+type Suite struct {
+	// Adapter is the name the adapter factory is registered with (see adapters.Register)
+	Adapter string
+	// Database returns the url of an empty database (and the schema to use if the adapter supports them)
+	// along with a function to clean it up, it is called once for each test
+	Database func(t *testing.T) (dbURL, schema string, cleanup func())
+	// Golden is the path of the file storing the SQL generated by the adapter,
+	// run tests with -adaptertest.update to (re)write it
+	Golden string
+	// ErrorQueries returns queries failing with a given error type, for errors the suite can't cause through
+	// the adapter (e.g. invalid values or unsupported features), given the quoted name of an existing test table,
+	// error types without query (or all of them if nil) are not checked
+	ErrorQueries func(tableName string) map[types.SQLErrorType]string
+}
+
+// chain the suite databases are bound to
+const (
+	chainID       = "ID 0123"
+	burrowVersion = "Version 0.0"
+)
+
+// Run runs the whole conformance suite
+func Run(t *testing.T, suite Suite) {
+	t.Run("generates SQL matching the golden file", func(t *testing.T) {
+		RunGolden(t, suite.Adapter, suite.Golden)
+	})
+
+	t.Run("quotes & validates identifiers", suite.testIdentifiers)
+	t.Run("creates, alters & describes tables", suite.testTables)
+	t.Run("maps database errors", suite.testErrors)
+	t.Run("upserts & deletes rows", suite.testRows)
+	t.Run("rolls back, restores & compacts the log", suite.testLog)
+	t.Run("cleans tables", suite.testClean)
+}
+
+// open opens a database through sqldb, bound to the suite chain
+func (suite Suite) open(t *testing.T) (*sqldb.SQLDB, types.SQLConnection, func()) {
+	t.Helper()
+
+	dbURL, schema, cleanup := suite.Database(t)
+
+	connection := types.SQLConnection{
+		DBAdapter:     suite.Adapter,
+		DBURL:         dbURL,
+		DBSchema:      schema,
+		Log:           logger.NewLogger("error"),
+		ChainID:       chainID,
+		BurrowVersion: burrowVersion,
+	}
+
+	db, err := sqldb.NewSQLDB(connection)
+	if err != nil {
+		cleanup()
+		t.Fatal(err.Error())
+	}
+
+	return db, connection, func() {
+		db.Close()
+		cleanup()
+	}
+}
+
+func (suite Suite) testIdentifiers(t *testing.T) {
+	db, _, closeDB := suite.open(t)
+	defer closeDB()
+
+	table := testTable()
+	require.NoError(t, db.DBAdapter.ValidateTableName(table.Name))
+	require.Error(t, db.DBAdapter.ValidateTableName("user's"))
+	require.Error(t, db.DBAdapter.ValidateTableName(""))
+	require.Error(t, db.DBAdapter.ValidateColumnName("user\"name"))
+
+	// keywords & spaces are valid names once quoted
+	tables := types.EventTables{"1": table}
+	require.NoError(t, db.SynchronizeDB(tables))
+	require.NoError(t, db.SetBlock(tables, block("1", upsert("1", "1", "a", "b", "100"))))
+
+	rows, err := db.GetTableAsOf(table.Name, 1)
+	require.NoError(t, err)
+	require.Equal(t, []map[string]interface{}{
+		{"id": int64(1), "from": "a", "group by": "b", "amount": big.NewInt(100), types.SQLColumnLabelHeight: int64(1)},
+	}, rowData(rows))
+}
+
+func (suite Suite) testTables(t *testing.T) {
+	db, _, closeDB := suite.open(t)
+	defer closeDB()
+
+	table := testTable()
+	tables := types.EventTables{"1": table}
+	require.NoError(t, db.SynchronizeDB(tables))
+	requireNoDrift(t, db)
+
+	// add
+	table.Columns["note"] = types.SQLTableColumn{Name: "note", Type: types.SQLColumnTypeVarchar, Length: 10, Order: 6}
+	require.NoError(t, db.SynchronizeDB(tables))
+	requireNoDrift(t, db)
+
+	// widen
+	table.Columns["note"] = types.SQLTableColumn{Name: "note", Type: types.SQLColumnTypeVarchar, Length: 20, Order: 6}
+	require.NoError(t, db.SynchronizeDB(tables))
+	requireNoDrift(t, db)
+
+	// rename
+	delete(table.Columns, "note")
+	table.Columns["comment"] = types.SQLTableColumn{Name: "comment", Type: types.SQLColumnTypeVarchar, Length: 20, Order: 6, PreviousName: "note"}
+	require.NoError(t, db.SynchronizeDB(tables))
+	requireNoDrift(t, db)
+
+	// drop
+	delete(table.Columns, "comment")
+	require.Error(t, db.SynchronizeDB(tables))
+	db.AllowDestructive = true
+	require.NoError(t, db.SynchronizeDB(tables))
+	requireNoDrift(t, db)
+
+	require.NoError(t, db.SetBlock(tables, block("1", upsert("1", "1", "a", "b", "100"))))
+	data, err := db.GetBlock("1")
+	require.NoError(t, err)
+	require.Len(t, data.Tables[table.Name], 1)
+}
+
+func (suite Suite) testErrors(t *testing.T) {
+	db, connection, closeDB := suite.open(t)
+	defer closeDB()
+
+	table := testTable()
+	require.NoError(t, db.SynchronizeDB(types.EventTables{"1": table}))
+
+	query, _ := db.DBAdapter.CreateTableQuery(table.Name, sortedColumns(table))
+	_, err := db.DB.Exec(query)
+	requireErrorType(t, db.DBAdapter, err, types.SQLErrorTypeDuplicatedTable)
+
+	query, _ = db.DBAdapter.AlterColumnQuery(table.Name, "from", types.SQLColumnTypeVarchar, 100, 2)
+	_, err = db.DB.Exec(query)
+	requireErrorType(t, db.DBAdapter, err, types.SQLErrorTypeDuplicatedColumn)
+
+	_, err = db.DB.Exec(db.DBAdapter.SelectRowQuery("missing", db.DBAdapter.SecureColumnName("id"), "1"))
+	requireErrorType(t, db.DBAdapter, err, types.SQLErrorTypeUndefinedTable)
+
+	_, err = db.DB.Exec(fmt.Sprintf("INSERT INTO %s (%s) VALUES (1);", db.DBAdapter.SecureTableName(table.Name), db.DBAdapter.SecureColumnName("missing")))
+	requireErrorType(t, db.DBAdapter, err, types.SQLErrorTypeUndefinedColumn)
+
+	// schemas are created when opening the database, adapters without schemas are not checked
+	if connection.DBSchema != "" {
+		_, err = db.DB.Exec(fmt.Sprintf("CREATE SCHEMA %s;", db.DBAdapter.SecureColumnName(db.Schema)))
+		requireErrorType(t, db.DBAdapter, err, types.SQLErrorTypeDuplicatedSchema)
+	}
+
+	if suite.ErrorQueries != nil {
+		queries := suite.ErrorQueries(db.DBAdapter.SecureTableName(table.Name))
+		for _, errorType := range []types.SQLErrorType{types.SQLErrorTypeInvalidType, types.SQLErrorTypeUnsupported} {
+			if query, ok := queries[errorType]; ok {
+				_, err = db.DB.Exec(query)
+				requireErrorType(t, db.DBAdapter, err, errorType)
+			}
+		}
+	}
+
+	// errors not coming from the database are not SQL errors
+	require.False(t, db.DBAdapter.ErrorEquals(errors.New("error"), types.SQLErrorTypeGeneric))
+
+	_, err = db.DBAdapter.TypeMapping(types.SQLColumnType(-1))
+	require.Error(t, err)
+
+	// existing schemas & system tables are reused
+	reopened, err := sqldb.NewSQLDB(connection)
+	require.NoError(t, err)
+	reopened.Close()
+}
+
+func (suite Suite) testRows(t *testing.T) {
+	db, _, closeDB := suite.open(t)
+	defer closeDB()
+
+	table := testTable()
+	tables := types.EventTables{"1": table}
+
+	require.NoError(t, db.SetBlock(tables, block("1",
+		upsert("1", "1", "a", "b", "100"),
+		upsert("1", "2", "c", "d", "200"))))
+
+	// upserts only update given columns, rows are written in order
+	require.NoError(t, db.SetBlock(tables, block("2",
+		types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"id": "1", "amount": "150", types.SQLColumnLabelHeight: "2"}},
+		upsert("2", "3", "e", "f", "300"),
+		types.EventDataRow{Action: types.ActionDelete, RowData: map[string]interface{}{"id": "2", types.SQLColumnLabelHeight: "2"}},
+		types.EventDataRow{Action: types.ActionDelete, RowData: map[string]interface{}{"id": "3", types.SQLColumnLabelHeight: "2"}})))
+
+	rows, err := db.GetTableAsOf(table.Name, 2)
+	require.NoError(t, err)
+	require.Equal(t, []map[string]interface{}{
+		{"id": int64(1), "from": "a", "group by": "b", "amount": big.NewInt(150), types.SQLColumnLabelHeight: int64(2)},
+	}, rowData(rows))
+
+	data, err := db.GetBlock("2")
+	require.NoError(t, err)
+	require.Len(t, data.Tables[table.Name], 1)
+
+	lastBlock, err := db.GetLastBlockID()
+	require.NoError(t, err)
+	require.Equal(t, "2", lastBlock)
+
+	// notifications (if supported) are sent within the block transaction
+	db.NotifyChannel = "adaptertest"
+	require.NoError(t, db.SetBlock(tables, block("3", upsert("3", "4", "g", "h", "400"))))
+}
+
+func (suite Suite) testLog(t *testing.T) {
+	db, _, closeDB := suite.open(t)
+	defer closeDB()
+
+	table := testTable()
+	tables := types.EventTables{"1": table}
+
+	require.NoError(t, db.SetBlock(tables, block("1", upsert("1", "1", "a", "b", "100"))))
+	require.NoError(t, db.SetBlock(tables, block("2", upsert("2", "1", "a", "b", "200"), upsert("2", "2", "c", "d", "300"))))
+	require.NoError(t, db.SetBlock(tables, block("3", types.EventDataRow{Action: types.ActionDelete, RowData: map[string]interface{}{"id": "1", types.SQLColumnLabelHeight: "3"}})))
+
+	rows, err := db.GetTableAsOf(table.Name, 1)
+	require.NoError(t, err)
+	require.Equal(t, []map[string]interface{}{
+		{"id": int64(1), "from": "a", "group by": "b", "amount": big.NewInt(100), types.SQLColumnLabelHeight: int64(1)},
+	}, rowData(rows))
+
+	require.NoError(t, db.Restore(sqldb.RestoreOptions{Height: 2, Prefix: "restored"}))
+	count := 0
+	require.NoError(t, db.DB.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", db.DBAdapter.SecureTableName("restored_"+table.Name))).Scan(&count))
+	require.Equal(t, 2, count)
+
+	require.NoError(t, db.RollbackToHeight(1))
+	rows, err = db.GetTableAsOf(table.Name, 1)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+
+	lastBlock, err := db.GetLastBlockID()
+	require.NoError(t, err)
+	require.Equal(t, "1", lastBlock)
+
+	// compacted heights can't be rolled back anymore
+	require.NoError(t, db.SetBlock(tables, block("2", upsert("2", "2", "c", "d", "300"))))
+	require.NoError(t, db.CompactLog(1, ""))
+	require.Error(t, db.RollbackToHeight(0))
+	require.NoError(t, db.RollbackToHeight(1))
+}
+
+func (suite Suite) testClean(t *testing.T) {
+	db, _, closeDB := suite.open(t)
+	defer closeDB()
+
+	table := testTable()
+	tables := types.EventTables{"1": table}
+	require.NoError(t, db.SetBlock(tables, block("1", upsert("1", "1", "a", "b", "100"))))
+	require.NoError(t, db.SetAbi("1AEEFD3783050219C3988098E152A11F02C4F4C4", "[]"))
+
+	// same chain, nothing changes
+	require.NoError(t, db.CleanTables(chainID, burrowVersion))
+	require.Error(t, db.CleanTables("NEW_ID", burrowVersion))
+
+	db.ChainIDPolicy = types.ChainIDPolicyDrop
+	require.NoError(t, db.CleanTables("NEW_ID", burrowVersion))
+
+	_, err := db.DB.Exec(db.DBAdapter.SelectRowQuery(table.Name, db.DBAdapter.SecureColumnName("id"), "1"))
+	requireErrorType(t, db.DBAdapter, err, types.SQLErrorTypeUndefinedTable)
+
+	abis, err := db.GetAbis()
+	require.NoError(t, err)
+	require.Empty(t, abis)
+
+	lastBlock, err := db.GetLastBlockID()
+	require.NoError(t, err)
+	require.Equal(t, "0", lastBlock)
+	requireNoDrift(t, db)
+}
+
+// testTable returns the event table used by the suite, its names need quoting
+func testTable() types.SQLTable {
+	return types.SQLTable{
+		Name:   "select table",
+		Filter: "TEST",
+		Columns: map[string]types.SQLTableColumn{
+			"id":      {Name: "id", Type: types.SQLColumnTypeInt, Primary: true, Order: 1},
+			"from":    {Name: "from", Type: types.SQLColumnTypeVarchar, Length: 100, Order: 2},
+			"groupBy": {Name: "group by", Type: types.SQLColumnTypeText, Order: 3},
+			"amount":  {Name: "amount", Type: types.SQLColumnTypeNumeric, Order: 4},
+			"height":  {Name: types.SQLColumnLabelHeight, Type: types.SQLColumnTypeBigInt, Order: 5},
+		},
+	}
+}
+
+// sortedColumns returns the columns of a table sorted by their order
+func sortedColumns(table types.SQLTable) []types.SQLTableColumn {
+	columns := make([]types.SQLTableColumn, len(table.Columns))
+	for _, column := range table.Columns {
+		columns[column.Order-1] = column
+	}
+	return columns
+}
+
+// upsert returns an upsert of the test table
+func upsert(height, id, from, groupBy, amount string) types.EventDataRow {
+	return types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{
+		"id": id, "from": from, "group by": groupBy, "amount": amount, types.SQLColumnLabelHeight: height,
+	}}
+}
+
+// block returns the data of a block with rows of the test table
+func block(height string, rows ...types.EventDataRow) types.EventData {
+	return types.EventData{Block: height, Tables: map[string]types.EventDataTable{testTable().Name: rows}}
+}
+
+// rowData returns the data of rows
+func rowData(rows []types.EventDataRow) []map[string]interface{} {
+	data := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		data[i] = row.RowData
+	}
+	return data
+}
+
+// requireErrorType checks an error is a SQL error of a given type (& only of that type)
+func requireErrorType(t *testing.T, dbAdapter adapters.DBAdapter, err error, sqlErrorType types.SQLErrorType) {
+	t.Helper()
+
+	require.Error(t, err)
+	require.True(t, dbAdapter.ErrorEquals(err, types.SQLErrorTypeGeneric), "%v is not a SQL error", err)
+
+	for _, errorType := range []types.SQLErrorType{
+		types.SQLErrorTypeDuplicatedSchema, types.SQLErrorTypeDuplicatedColumn, types.SQLErrorTypeDuplicatedTable,
+		types.SQLErrorTypeInvalidType, types.SQLErrorTypeUndefinedTable, types.SQLErrorTypeUndefinedColumn,
+		types.SQLErrorTypeUnsupported,
+	} {
+		require.Equal(t, errorType == sqlErrorType, dbAdapter.ErrorEquals(err, errorType), "%v mapped as error type %d", err, errorType)
+	}
+}
+
+// requireNoDrift checks tables match the structure stored in the dictionary
+func requireNoDrift(t *testing.T, db *sqldb.SQLDB) {
+	t.Helper()
+
+	drifts, err := db.CheckSchema()
+	require.NoError(t, err)
+	require.Empty(t, drifts)
+}
@@ -0,0 +1,52 @@
+// +build integration
+
+package adaptertest_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/monax/bosmarmot/vent/sqldb/adapters/adaptertest"
+	"github.com/monax/bosmarmot/vent/test"
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+func TestAdapters(t *testing.T) {
+	for _, adapter := range []string{types.PostgresDB, types.SQLiteDB, types.MySQLDB} {
+		adapter := adapter
+
+		t.Run(adapter, func(t *testing.T) {
+			adaptertest.Run(t, adaptertest.Suite{
+				Adapter: adapter,
+				Database: func(t *testing.T) (string, string, func()) {
+					return test.NewTestDatabase(t, adapter)
+				},
+				Golden:       filepath.Join("testdata", adapter+".golden"),
+				ErrorQueries: errorQueries[adapter],
+			})
+		})
+	}
+}
+
+// errorQueries cause the errors of each adapter the suite can't cause through the adapter
+// (SQLite has no invalid type error)
+var errorQueries = map[string]func(tableName string) map[types.SQLErrorType]string{
+	types.PostgresDB: func(tableName string) map[types.SQLErrorType]string {
+		return map[types.SQLErrorType]string{
+			types.SQLErrorTypeInvalidType: fmt.Sprintf(`ALTER TABLE %s ADD COLUMN "note" missing_type;`, tableName),
+			types.SQLErrorTypeUnsupported: `SELECT * FROM "missing_db"."public"."missing";`,
+		}
+	},
+	types.SQLiteDB: func(tableName string) map[types.SQLErrorType]string {
+		return map[types.SQLErrorType]string{
+			types.SQLErrorTypeUnsupported: `CREATE VIRTUAL TABLE "missing" USING missing_module("note");`,
+		}
+	},
+	types.MySQLDB: func(tableName string) map[types.SQLErrorType]string {
+		return map[types.SQLErrorType]string{
+			types.SQLErrorTypeInvalidType: fmt.Sprintf("INSERT INTO %s (`id`) VALUES ('one');", tableName),
+			types.SQLErrorTypeUnsupported: "SELECT 1 FROM DUAL WHERE 1 IN (SELECT 1 FROM DUAL LIMIT 1);",
+		}
+	},
+}
@@ -0,0 +1,204 @@
+package adaptertest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"testing"
+
+	"github.com/monax/bosmarmot/vent/logger"
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
+	"github.com/monax/bosmarmot/vent/types"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("adaptertest.update", false, "rewrite adapter golden files with the generated SQL")
+
+// goldenSchema is the schema the golden SQL is generated for
+const goldenSchema = "vent"
+
+var spaces = regexp.MustCompile(`\s+`)
+
+// RunGolden compares the SQL generated by a registered adapter with a golden file,
+// the file is rewritten instead if tests run with -adaptertest.update
+func RunGolden(t *testing.T, adapter, path string) {
+	t.Helper()
+
+	generated, err := GenerateSQL(adapter)
+	require.NoError(t, err)
+
+	if *update {
+		require.NoError(t, ioutil.WriteFile(path, generated, 0644))
+		return
+	}
+
+	golden, err := ioutil.ReadFile(path)
+	require.NoError(t, err, "run tests with -adaptertest.update to create the golden file")
+	require.Equal(t, string(golden), string(generated), "run tests with -adaptertest.update to update the golden file")
+}
+
+// GenerateSQL returns the SQL built by every query builder of a registered adapter for the suite test table,
+// one query per builder (whitespace collapsed) preceded by a comment naming the builder
+func GenerateSQL(adapter string) ([]byte, error) {
+	dbAdapter, _, err := adapters.New(adapter, goldenSchema, logger.NewLogger("none"))
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	write := func(name, query string) {
+		fmt.Fprintf(buf, "-- %s\n%s\n", name, bytes.TrimSpace(spaces.ReplaceAll([]byte(query), []byte(" "))))
+	}
+
+	table := testTable()
+	columns := sortedColumns(table)
+	rows := []types.EventDataRow{upsert("1", "1", "a", "b", "100"), upsert("1", "2", "c", "d", "200")}
+
+	for sqlColumnType := types.SQLColumnTypeBool; sqlColumnType <= types.SQLColumnTypeJSONB; sqlColumnType++ {
+		typeName, err := dbAdapter.TypeMapping(sqlColumnType)
+		if err != nil {
+			return nil, err
+		}
+		write(fmt.Sprintf("TypeMapping %d", sqlColumnType), typeName)
+		write(fmt.Sprintf("CatalogColumnType %d", sqlColumnType), dbAdapter.CatalogColumnType(sqlColumnType))
+		if keyAdapter, ok := dbAdapter.(adapters.KeyColumnAdapter); ok {
+			write(fmt.Sprintf("KeyCatalogColumnType %d", sqlColumnType), keyAdapter.KeyCatalogColumnType(sqlColumnType))
+		}
+	}
+
+	write("SecureTableName", dbAdapter.SecureTableName(table.Name))
+	write("SecureColumnName", dbAdapter.SecureColumnName("group by"))
+
+	query, dictionary := dbAdapter.CreateTableQuery(table.Name, columns)
+	write("CreateTableQuery", query)
+	write("CreateTableQuery dictionary", dictionary)
+
+	query, dictionary = dbAdapter.AlterColumnQuery(table.Name, "note", types.SQLColumnTypeVarchar, 10, 6)
+	write("AlterColumnQuery", query)
+	write("AlterColumnQuery dictionary", dictionary)
+
+	widened := table.Columns["from"]
+	widened.Length = 200
+	query, dictionary = dbAdapter.AlterColumnTypeQuery(table.Name, columns, widened)
+	write("AlterColumnTypeQuery", query)
+	write("AlterColumnTypeQuery dictionary", dictionary)
+
+	query, dictionary = dbAdapter.RenameColumnQuery(table.Name, "from", "sender")
+	write("RenameColumnQuery", query)
+	write("RenameColumnQuery dictionary", dictionary)
+
+	query, dictionary = dbAdapter.DropColumnQuery(table.Name, columns[:len(columns)-1], types.SQLColumnLabelHeight)
+	write("DropColumnQuery", query)
+	write("DropColumnQuery dictionary", dictionary)
+
+	write("LastBlockIDQuery", dbAdapter.LastBlockIDQuery())
+	write("FindTableQuery", dbAdapter.FindTableQuery())
+	write("TableDefinitionQuery", dbAdapter.TableDefinitionQuery())
+	write("TableColumnsQuery", dbAdapter.TableColumnsQuery(table.Name).Query)
+	write("SelectRowQuery", dbAdapter.SelectRowQuery(table.Name, dbAdapter.SecureColumnName("id"), "1"))
+	write("SelectLogQuery", dbAdapter.SelectLogQuery())
+	write("InsertLogQuery", dbAdapter.InsertLogQuery())
+
+	upsertQuery, _, err := dbAdapter.UpsertQuery(table, rows[0])
+	if err != nil {
+		return nil, err
+	}
+	write("UpsertQuery", upsertQuery.Query)
+
+	deleteQuery, err := dbAdapter.DeleteQuery(table, rows[0])
+	if err != nil {
+		return nil, err
+	}
+	write("DeleteQuery", deleteQuery.Query)
+
+	selectQuery, err := dbAdapter.SelectByKeyQuery(table, rows)
+	if err != nil {
+		return nil, err
+	}
+	write("SelectByKeyQuery", selectQuery.Query)
+
+	write("RestoreDBQuery", dbAdapter.RestoreDBQuery())
+	write("RestoreHeightQuery", dbAdapter.RestoreHeightQuery())
+
+	cleanQueries := dbAdapter.CleanDBQueries()
+	write("CleanDBQueries SelectChainIDQry", cleanQueries.SelectChainIDQry)
+	write("CleanDBQueries DeleteChainIDQry", cleanQueries.DeleteChainIDQry)
+	write("CleanDBQueries InsertChainIDQry", cleanQueries.InsertChainIDQry)
+	write("CleanDBQueries SelectDictionaryQry", cleanQueries.SelectDictionaryQry)
+	write("CleanDBQueries DeleteDictionaryQry", cleanQueries.DeleteDictionaryQry)
+	write("CleanDBQueries DeleteLogQry", cleanQueries.DeleteLogQry)
+	write("CleanDBQueries DeleteAbiQry", cleanQueries.DeleteAbiQry)
+
+	write("SelectAbiQuery", dbAdapter.SelectAbiQuery())
+	write("DropTableQuery", dbAdapter.DropTableQuery(table.Name))
+	write("SelectLogAfterHeightQuery", dbAdapter.SelectLogAfterHeightQuery())
+	write("DeleteLogAfterHeightQuery", dbAdapter.DeleteLogAfterHeightQuery())
+	write("SelectLogUpToHeightQuery", dbAdapter.SelectLogUpToHeightQuery())
+	write("CheckpointLogQuery", dbAdapter.CheckpointLogQuery())
+	write("DeleteLogUpToHeightQuery", dbAdapter.DeleteLogUpToHeightQuery())
+	write("CompactedHeightQuery", dbAdapter.CompactedHeightQuery())
+	write("RenameTableQuery", dbAdapter.RenameTableQuery(table.Name, "archived table"))
+	write("CreateHistoryViewQuery", dbAdapter.CreateHistoryViewQuery(table))
+	write("CreateEnrichedViewQuery", dbAdapter.CreateEnrichedViewQuery(table.Name))
+	write("DropViewQuery", dbAdapter.DropViewQuery(adapters.HistoryViewName(table.Name)))
+	write("SelectAsOfQuery", dbAdapter.SelectAsOfQuery(table))
+
+	if bulkAdapter, ok := dbAdapter.(adapters.BulkAdapter); ok {
+		bulkQuery, err := bulkAdapter.BulkUpsertQuery(table, rows)
+		if err != nil {
+			return nil, err
+		}
+		write("BulkUpsertQuery", bulkQuery.Query)
+
+		bulkQuery, err = bulkAdapter.BulkDeleteQuery(table, rows)
+		if err != nil {
+			return nil, err
+		}
+		write("BulkDeleteQuery", bulkQuery.Query)
+		write("BulkInsertLogQuery", bulkAdapter.BulkInsertLogQuery(len(rows)))
+		write("MaxQueryParams", fmt.Sprint(bulkAdapter.MaxQueryParams()))
+	}
+
+	if notifyAdapter, ok := dbAdapter.(adapters.NotifyAdapter); ok {
+		write("NotifyQuery", notifyAdapter.NotifyQuery())
+	}
+
+	if indexAdapter, ok := dbAdapter.(adapters.IndexAdapter); ok {
+		query, err := indexAdapter.CreateIndexQuery(table.Name, "group by", types.SQLIndexGIN)
+		if err != nil {
+			return nil, err
+		}
+		write("CreateIndexQuery", query)
+		write("RenameIndexQuery", indexAdapter.RenameIndexQuery(table.Name, "archived table", "group by"))
+	}
+
+	if jsonPathAdapter, ok := dbAdapter.(adapters.JSONPathAdapter); ok {
+		write("AddJSONPathQuery", jsonPathAdapter.AddJSONPathQuery(table.Name, types.SQLJSONPath{Name: "code", Column: "group by", Path: "$.Code"}))
+	}
+
+	if fullTextAdapter, ok := dbAdapter.(adapters.FullTextAdapter); ok {
+		write("FullTextQuery", fullTextAdapter.FullTextQuery(table.Name, []string{"from", "group by"}))
+		write("SearchQuery", fullTextAdapter.SearchQuery(table, "group by"))
+
+		if tableAdapter, ok := fullTextAdapter.(adapters.FullTextTableAdapter); ok {
+			write("DropFullTextQuery", tableAdapter.DropFullTextQuery(table.Name))
+		}
+	}
+
+	if partitionAdapter, ok := dbAdapter.(adapters.PartitionAdapter); ok {
+		query, dictionary = partitionAdapter.CreatePartitionedTableQuery(table.Name, columns)
+		write("CreatePartitionedTableQuery", query)
+		write("CreatePartitionedTableQuery dictionary", dictionary)
+		write("CreatePartitionQuery", partitionAdapter.CreatePartitionQuery(table.Name, 1000, 2000))
+		write("SelectPartitionsQuery", partitionAdapter.SelectPartitionsQuery(table.Name).Query)
+		write("PartitionName", partitionAdapter.PartitionName(table.Name, 1000))
+	}
+
+	if checkpointAdapter, ok := dbAdapter.(adapters.CheckpointAdapter); ok {
+		write("CheckpointQuery", checkpointAdapter.CheckpointQuery())
+	}
+
+	return buf.Bytes(), nil
+}
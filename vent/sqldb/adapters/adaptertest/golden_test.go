@@ -0,0 +1,17 @@
+package adaptertest_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/monax/bosmarmot/vent/sqldb/adapters/adaptertest"
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+func TestGolden(t *testing.T) {
+	for _, adapter := range []string{types.PostgresDB, types.SQLiteDB, types.MySQLDB} {
+		t.Run(adapter, func(t *testing.T) {
+			adaptertest.RunGolden(t, adapter, filepath.Join("testdata", adapter+".golden"))
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package adapters
+
+import (
+	"database/sql"
+	"fmt"
+	"math/bits"
+
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// BulkAdapter is implemented by db adapters able to write multiple rows in a single round trip,
+// adapters not implementing it write rows one by one
+type BulkAdapter interface {
+	// BulkUpsertQuery builds an upsert query for multiple rows having the same columns & distinct primary keys
+	BulkUpsertQuery(table types.SQLTable, rows []types.EventDataRow) (types.UpsertDeleteQuery, error)
+	// BulkDeleteQuery builds a DELETE FROM event tables query for multiple rows based on PK
+	BulkDeleteQuery(table types.SQLTable, rows []types.EventDataRow) (types.UpsertDeleteQuery, error)
+	// BulkInsertLogQuery builds an INSERT query to store multiple rows in Log table
+	BulkInsertLogQuery(rows int) string
+	// MaxQueryParams returns the maximum number of parameters allowed in a single query
+	MaxQueryParams() int
+}
+
+// CopyAdapter is implemented by bulk adapters able to stream large sets of rows into a table,
+// rows are upserted with BulkUpsertQuery below CopyThreshold rows
+type CopyAdapter interface {
+	// CopyUpsert upserts rows having the same columns & distinct primary keys into a table
+	CopyUpsert(tx *sql.Tx, table types.SQLTable, rows []types.EventDataRow) error
+	// CopyThreshold returns the number of rows from which CopyUpsert is used
+	CopyThreshold() int
+}
+
+// LogQueryParams is the number of parameters of each row inserted in Log table
+const LogQueryParams = 10
+
+// bulkColumns returns the table columns present in a row sorted by column order
+func bulkColumns(table types.SQLTable, row types.EventDataRow) ([]types.SQLTableColumn, error) {
+	columns := make([]types.SQLTableColumn, 0, len(row.RowData))
+
+	for _, tableColumn := range sortedColumns(table) {
+		if _, ok := row.RowData[tableColumn.Name]; ok {
+			columns = append(columns, tableColumn)
+		} else if tableColumn.Primary {
+			return nil, fmt.Errorf("error null primary key for column %s", tableColumn.Name)
+		}
+	}
+
+	return columns, nil
+}
+
+// bulkValues returns row values for the given columns
+func bulkValues(columns []types.SQLTableColumn, row types.EventDataRow) []interface{} {
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		values[i] = row.RowData[column.Name]
+	}
+	return values
+}
+
+// BulkChunkSize returns the number of rows of a full chunk (needing at most maxParams query parameters)
+func BulkChunkSize(paramsPerRow, maxParams int) int {
+	if paramsPerRow < 1 || maxParams < paramsPerRow {
+		return 1
+	}
+	return maxParams / paramsPerRow
+}
+
+// BulkChunkSizes splits a number of rows into full chunks (needing at most maxParams query parameters)
+// followed by chunks of decreasing powers of two, so that queries built for each chunk have a few
+// distinct shapes whatever the number of rows and their prepared statements can be reused
+func BulkChunkSizes(rows, paramsPerRow, maxParams int) []int {
+	size := BulkChunkSize(paramsPerRow, maxParams)
+
+	sizes := make([]int, 0)
+	for ; rows >= size; rows -= size {
+		sizes = append(sizes, size)
+	}
+
+	for bit := bits.Len(uint(rows)) - 1; bit >= 0; bit-- {
+		if rows&(1<<uint(bit)) != 0 {
+			sizes = append(sizes, 1<<uint(bit))
+		}
+	}
+
+	return sizes
+}
+
+// BulkChunks splits rows in chunks of BulkChunkSizes
+func BulkChunks(rows []types.EventDataRow, paramsPerRow, maxParams int) [][]types.EventDataRow {
+	sizes := BulkChunkSizes(len(rows), paramsPerRow, maxParams)
+
+	chunks := make([][]types.EventDataRow, len(sizes))
+	start := 0
+	for i, size := range sizes {
+		chunks[i] = rows[start : start+size]
+		start += size
+	}
+
+	return chunks
+}
+
+// bulkInsertQuery builds a multi-row INSERT query (without conflict clause)
+func bulkInsertQuery(tableName string, columns []types.SQLTableColumn, rows []types.EventDataRow,
+	secureColumnName func(string) string, param func(int) string) types.UpsertDeleteQuery {
+
+	fields := ""
+	for _, column := range columns {
+		if fields != "" {
+			fields += ", "
+		}
+		fields += secureColumnName(column.Name)
+	}
+
+	pointers := make([]interface{}, 0, len(rows)*len(columns))
+	params := ""
+	values := ""
+
+	for _, row := range rows {
+		rowParams := ""
+		for _, value := range bulkValues(columns, row) {
+			pointers = append(pointers, value)
+			if rowParams != "" {
+				rowParams += ", "
+			}
+			rowParams += param(len(pointers))
+
+			if values != "" {
+				values += ", "
+			}
+			values += fmt.Sprint(value)
+		}
+
+		if params != "" {
+			params += ", "
+		}
+		params += "(" + rowParams + ")"
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", tableName, fields, params)
+
+	return types.UpsertDeleteQuery{Query: query, Values: values, Pointers: pointers}
+}
+
+// bulkDeleteQuery builds a DELETE query matching the PK of each row
+func bulkDeleteQuery(tableName string, table types.SQLTable, rows []types.EventDataRow,
+	secureColumnName func(string) string, param func(int) string) (types.UpsertDeleteQuery, error) {
+
+	conditions, values, pointers, err := keyConditions(table, rows, secureColumnName, param)
+	if err != nil {
+		return types.UpsertDeleteQuery{}, err
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s;", tableName, conditions)
+
+	return types.UpsertDeleteQuery{Query: query, Values: values, Pointers: pointers}, nil
+}
+
+// bulkInsertLogQuery builds an INSERT query for multiple rows in Log table
+func bulkInsertLogQuery(logTableName string, rows int, param func(int) string) string {
+	values := ""
+	for i := 0; i < rows; i++ {
+		if values != "" {
+			values += ", "
+		}
+		values += "(CURRENT_TIMESTAMP"
+		for j := 1; j <= LogQueryParams; j++ {
+			values += ", " + param(i*LogQueryParams+j)
+		}
+		values += ")"
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s) VALUES %s;",
+		logTableName,
+		types.SQLColumnLabelTimeStamp, types.SQLColumnLabelTableName, types.SQLColumnLabelEventName, types.SQLColumnLabelEventFilter,
+		types.SQLColumnLabelHeight, types.SQLColumnLabelTxHash, types.SQLColumnLabelAction, types.SQLColumnLabelDataRow,
+		types.SQLColumnLabelSqlStmt, types.SQLColumnLabelSqlValues, types.SQLColumnLabelBeforeImage,
+		values)
+}
+
+// dollarParam returns $n placeholders
+func dollarParam(i int) string {
+	return fmt.Sprintf("$%d", i)
+}
+
+// questionParam returns ? placeholders
+func questionParam(int) string {
+	return "?"
+}
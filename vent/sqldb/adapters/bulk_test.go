@@ -0,0 +1,27 @@
+package adapters_test
+
+import (
+	"testing"
+
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
+	"github.com/monax/bosmarmot/vent/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkChunkSizes(t *testing.T) {
+	t.Run("successfully splits rows in full chunks and powers of two", func(t *testing.T) {
+		require.Empty(t, adapters.BulkChunkSizes(0, 10, 999))
+		require.Equal(t, []int{1}, adapters.BulkChunkSizes(1, 10, 999))
+		require.Equal(t, []int{8, 4, 1}, adapters.BulkChunkSizes(13, 10, 999))
+		require.Equal(t, []int{99, 99, 64, 32, 2}, adapters.BulkChunkSizes(296, 10, 999))
+	})
+
+	t.Run("successfully chunks rows by size", func(t *testing.T) {
+		rows := make([]types.EventDataRow, 13)
+		chunks := adapters.BulkChunks(rows, 10, 999)
+		require.Len(t, chunks, 3)
+		require.Len(t, chunks[0], 8)
+		require.Len(t, chunks[1], 4)
+		require.Len(t, chunks[2], 1)
+	})
+}
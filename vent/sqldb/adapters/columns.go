@@ -0,0 +1,102 @@
+package adapters
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// KeyColumnAdapter is implemented by db adapters storing primary key columns of some types with another type
+type KeyColumnAdapter interface {
+	// KeyCatalogColumnType returns the type name reported by the database catalog for a primary key column of a generic SQL column type
+	KeyCatalogColumnType(sqlColumnType types.SQLColumnType) string
+}
+
+// sortedColumns returns table columns sorted by column order (then by name),
+// so that generated queries are the same for every row of a table
+func sortedColumns(table types.SQLTable) []types.SQLTableColumn {
+	columns := make([]types.SQLTableColumn, 0, len(table.Columns))
+	for _, tableColumn := range table.Columns {
+		columns = append(columns, tableColumn)
+	}
+
+	sort.Slice(columns, func(i, j int) bool {
+		if columns[i].Order == columns[j].Order {
+			return columns[i].Name < columns[j].Name
+		}
+		return columns[i].Order < columns[j].Order
+	})
+
+	return columns
+}
+
+// primaryKeyColumns returns the primary key columns of a table sorted by column order
+func primaryKeyColumns(table types.SQLTable) []types.SQLTableColumn {
+	columns := make([]types.SQLTableColumn, 0)
+	for _, tableColumn := range sortedColumns(table) {
+		if tableColumn.Primary {
+			columns = append(columns, tableColumn)
+		}
+	}
+
+	return columns
+}
+
+// keyConditions builds a WHERE condition matching the PK of each row and returns its parameters,
+// param returns the placeholder of the i-th (1 based) query parameter
+func keyConditions(table types.SQLTable, rows []types.EventDataRow,
+	secureColumnName func(string) string, param func(int) string) (string, string, []interface{}, error) {
+
+	pkColumns := primaryKeyColumns(table)
+	if len(pkColumns) == 0 {
+		return "", "", nil, fmt.Errorf("error primary key not found")
+	}
+
+	pointers := make([]interface{}, 0, len(rows)*len(pkColumns))
+	conditions := ""
+	values := ""
+
+	for _, row := range rows {
+		condition := ""
+
+		for _, pkColumn := range pkColumns {
+			value, ok := row.RowData[pkColumn.Name]
+			if !ok {
+				return "", "", nil, fmt.Errorf("error null primary key for column %s", secureColumnName(pkColumn.Name))
+			}
+
+			if condition != "" {
+				condition += " AND "
+			}
+			if values != "" {
+				values += ", "
+			}
+
+			pointers = append(pointers, value)
+			condition += fmt.Sprintf("%s = %s", secureColumnName(pkColumn.Name), param(len(pointers)))
+			values += fmt.Sprint(value)
+		}
+
+		if conditions != "" {
+			conditions += " OR "
+		}
+		conditions += "(" + condition + ")"
+	}
+
+	return conditions, values, pointers, nil
+}
+
+// selectByKeyQuery builds a SELECT query returning the current contents of rows matching the PK of each row
+func selectByKeyQuery(tableName string, table types.SQLTable, rows []types.EventDataRow,
+	secureColumnName func(string) string, param func(int) string) (types.UpsertDeleteQuery, error) {
+
+	conditions, values, pointers, err := keyConditions(table, rows, secureColumnName, param)
+	if err != nil {
+		return types.UpsertDeleteQuery{}, err
+	}
+
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s;", tableName, conditions)
+
+	return types.UpsertDeleteQuery{Query: query, Values: values, Pointers: pointers}, nil
+}
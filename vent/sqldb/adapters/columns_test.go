@@ -0,0 +1,71 @@
+package adapters_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/monax/bosmarmot/vent/logger"
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
+	"github.com/monax/bosmarmot/vent/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeterministicQueries(t *testing.T) {
+	log := logger.NewLogger("debug")
+
+	columns := make(map[string]types.SQLTableColumn)
+	row := types.EventDataRow{Action: types.ActionUpsert, RowData: make(map[string]interface{})}
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("col%d", i)
+		columns[name] = types.SQLTableColumn{Name: name, Type: types.SQLColumnTypeVarchar, Length: 100, Primary: i < 2, Order: 20 - i}
+		row.RowData[name] = name
+	}
+	table := types.SQLTable{Name: "test_table", Columns: columns}
+
+	for _, dbAdapter := range []adapters.DBAdapter{
+		adapters.NewPostgresAdapter("vent", log),
+		adapters.NewSQLiteAdapter(log),
+		adapters.NewMySQLAdapter("vent", log),
+	} {
+		t.Run(fmt.Sprintf("%T: successfully builds the same queries for every row", dbAdapter), func(t *testing.T) {
+			upsert, _, err := dbAdapter.UpsertQuery(table, row)
+			require.NoError(t, err)
+			require.Contains(t, upsert.Query, dbAdapter.SecureColumnName("col19")+", "+dbAdapter.SecureColumnName("col18"))
+
+			del, err := dbAdapter.DeleteQuery(table, row)
+			require.NoError(t, err)
+
+			for i := 0; i < 10; i++ {
+				query, _, err := dbAdapter.UpsertQuery(table, row)
+				require.NoError(t, err)
+				require.Equal(t, upsert.Query, query.Query)
+				require.Equal(t, upsert.Values, query.Values)
+
+				query, err = dbAdapter.DeleteQuery(table, row)
+				require.NoError(t, err)
+				require.Equal(t, del.Query, query.Query)
+			}
+		})
+	}
+}
+
+func TestMySQLKeyColumns(t *testing.T) {
+	dbAdapter := adapters.NewMySQLAdapter("vent", logger.NewLogger("debug"))
+	columns := []types.SQLTableColumn{
+		{Name: "name", Type: types.SQLColumnTypeText, Primary: true, Order: 1},
+		{Name: "code", Type: types.SQLColumnTypeByteA, Primary: true, Order: 2},
+		{Name: "note", Type: types.SQLColumnTypeText, Order: 3},
+	}
+
+	t.Run("successfully indexes whole text & bytes primary keys", func(t *testing.T) {
+		query, _ := dbAdapter.CreateTableQuery("test_table", columns)
+		require.Contains(t, query, "`name` VARCHAR(255) NOT NULL, `code` VARBINARY(255) NOT NULL, `note` LONGTEXT")
+		require.Contains(t, query, "PRIMARY KEY (`name`, `code`)")
+
+		query, _ = dbAdapter.AlterColumnTypeQuery("test_table", columns, columns[0])
+		require.Contains(t, query, "MODIFY COLUMN `name` VARCHAR(255) NOT NULL")
+
+		require.Equal(t, "varchar", dbAdapter.KeyCatalogColumnType(types.SQLColumnTypeText))
+		require.Equal(t, "longtext", dbAdapter.CatalogColumnType(types.SQLColumnTypeText))
+	})
+}
@@ -0,0 +1,53 @@
+package adapters
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// logColumns are the log table columns written to log archives
+var logColumns = []string{
+	types.SQLColumnLabelId, types.SQLColumnLabelTimeStamp, types.SQLColumnLabelTableName, types.SQLColumnLabelEventName,
+	types.SQLColumnLabelEventFilter, types.SQLColumnLabelHeight, types.SQLColumnLabelTxHash, types.SQLColumnLabelAction,
+	types.SQLColumnLabelDataRow, types.SQLColumnLabelSqlStmt, types.SQLColumnLabelSqlValues, types.SQLColumnLabelBeforeImage,
+}
+
+// selectLogUpToHeightQuery builds a SELECT query returning upserts & deletes logged up to a given height
+func selectLogUpToHeightQuery(logTableName, param string) string {
+	return fmt.Sprintf(`SELECT %s FROM %s WHERE %s <= %s AND %s IN ('%s', '%s') ORDER BY %s;`,
+		strings.Join(logColumns, ", "), logTableName,
+		types.SQLColumnLabelHeight, param,
+		types.SQLColumnLabelAction, types.ActionUpsert, types.ActionDelete,
+		types.SQLColumnLabelId)
+}
+
+// checkpointLogQuery builds an UPDATE query turning the last upsert or delete logged for each block
+// up to a given height into a checkpoint without row data
+func checkpointLogQuery(logTableName, param string) string {
+	return fmt.Sprintf(`UPDATE %s SET %s = '%s', %s = NULL, %s = NULL, %s = NULL, %s = NULL
+		WHERE %s IN (SELECT %s FROM (SELECT MAX(%s) AS %s FROM %s WHERE %s <= %s AND %s IN ('%s', '%s') GROUP BY %s) c);`,
+		logTableName,
+		types.SQLColumnLabelAction, types.ActionCheckpoint,
+		types.SQLColumnLabelDataRow, types.SQLColumnLabelSqlStmt, types.SQLColumnLabelSqlValues, types.SQLColumnLabelBeforeImage,
+		types.SQLColumnLabelId, types.SQLColumnLabelId, types.SQLColumnLabelId, types.SQLColumnLabelId, logTableName,
+		types.SQLColumnLabelHeight, param,
+		types.SQLColumnLabelAction, types.ActionUpsert, types.ActionDelete,
+		types.SQLColumnLabelHeight)
+}
+
+// deleteLogUpToHeightQuery builds a DELETE query removing upserts & deletes logged up to a given height
+func deleteLogUpToHeightQuery(logTableName, param string) string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE %s <= %s AND %s IN ('%s', '%s');`,
+		logTableName,
+		types.SQLColumnLabelHeight, param,
+		types.SQLColumnLabelAction, types.ActionUpsert, types.ActionDelete)
+}
+
+// compactedHeightQuery builds a SELECT query returning the highest compacted height (0 if none)
+func compactedHeightQuery(logTableName string) string {
+	return fmt.Sprintf(`SELECT COALESCE(MAX(%s), 0) FROM %s WHERE %s = '%s';`,
+		types.SQLColumnLabelHeight, logTableName,
+		types.SQLColumnLabelAction, types.ActionCheckpoint)
+}
@@ -16,6 +16,12 @@ type DBAdapter interface {
 	ErrorEquals(err error, sqlErrorType types.SQLErrorType) bool
 	// SecureColumnName returns columns with proper delimiters to ensure well formed column names
 	SecureColumnName(columnName string) string
+	// SecureTableName returns tables (qualified by the schema if any) with proper delimiters to ensure well formed table names
+	SecureTableName(tableName string) string
+	// ValidateTableName returns an error if a table name (or a name derived from it) breaks the database naming rules
+	ValidateTableName(tableName string) error
+	// ValidateColumnName returns an error if a column name breaks the database naming rules
+	ValidateColumnName(columnName string) error
 	// CreateTableQuery builds a CREATE TABLE query to create a new table
 	CreateTableQuery(tableName string, columns []types.SQLTableColumn) (string, string)
 	// LastBlockIDQuery builds a SELECT query to return the last block# from the Log table
@@ -24,8 +30,21 @@ type DBAdapter interface {
 	FindTableQuery() string
 	// TableDefinitionQuery builds a SELECT query to get a table structure from the Dictionary table
 	TableDefinitionQuery() string
-	// AlterColumnQuery builds an ALTER COLUMN query to alter a table structure (only adding columns is supported)
+	// TableColumnsQuery builds a SELECT query to get the name, type & length of the columns of a table
+	// from the database catalog (the actual table structure), along with its parameters
+	TableColumnsQuery(tableName string) types.UpsertDeleteQuery
+	// CatalogColumnType returns the type name reported by the database catalog for a generic SQL column type
+	CatalogColumnType(sqlColumnType types.SQLColumnType) string
+	// AlterColumnQuery builds an ALTER COLUMN query to add a new column to a table structure
 	AlterColumnQuery(tableName, columnName string, sqlColumnType types.SQLColumnType, length, order int) (string, string)
+	// AlterColumnTypeQuery builds a query to change the type of an existing column,
+	// columns is the resulting (sorted) table structure for adapters that need to rebuild the table
+	AlterColumnTypeQuery(tableName string, columns []types.SQLTableColumn, column types.SQLTableColumn) (string, string)
+	// RenameColumnQuery builds a query to rename an existing column
+	RenameColumnQuery(tableName, columnName, newColumnName string) (string, string)
+	// DropColumnQuery builds a query to remove an existing column,
+	// columns is the resulting (sorted) table structure for adapters that need to rebuild the table
+	DropColumnQuery(tableName string, columns []types.SQLTableColumn, columnName string) (string, string)
 	// SelectRowQuery builds a SELECT query to get row values
 	SelectRowQuery(tableName, fields, indexValue string) string
 	// SelectLogQuery builds a SELECT query to get all tables involved in a given block transaction
@@ -36,10 +55,41 @@ type DBAdapter interface {
 	UpsertQuery(table types.SQLTable, row types.EventDataRow) (types.UpsertDeleteQuery, interface{}, error)
 	// DeleteQuery builds a DELETE FROM event tables query based on PK
 	DeleteQuery(table types.SQLTable, row types.EventDataRow) (types.UpsertDeleteQuery, error)
-	// RestoreDBQuery builds a list of sql clauses needed to restore the db to a point in time
+	// RestoreDBQuery builds a SELECT query to get upserts & deletes logged up to a point in time
 	RestoreDBQuery() string
+	// RestoreHeightQuery builds a SELECT query to get upserts & deletes logged up to a given height
+	RestoreHeightQuery() string
 	// CleanDBQueries returns necessary queries to clean the database
 	CleanDBQueries() types.SQLCleanDBQuery
+	// SelectAbiQuery builds a SELECT query to get all abi specifications stored by contract address
+	SelectAbiQuery() string
 	// DropTableQuery builds a DROP TABLE query to delete a table
 	DropTableQuery(tableName string) string
+	// SelectByKeyQuery builds a SELECT query returning the current contents of rows matching the PK of given rows
+	SelectByKeyQuery(table types.SQLTable, rows []types.EventDataRow) (types.UpsertDeleteQuery, error)
+	// SelectLogAfterHeightQuery builds a SELECT query to get upserts & deletes logged after a given height
+	SelectLogAfterHeightQuery() string
+	// DeleteLogAfterHeightQuery builds a DELETE query to remove rows logged after a given height from Log table
+	DeleteLogAfterHeightQuery() string
+	// SelectLogUpToHeightQuery builds a SELECT query to get upserts & deletes logged up to a given height
+	SelectLogUpToHeightQuery() string
+	// CheckpointLogQuery builds an UPDATE query to turn the last row logged for each block up to a given height
+	// into a checkpoint without row data
+	CheckpointLogQuery() string
+	// DeleteLogUpToHeightQuery builds a DELETE query to remove upserts & deletes logged up to a given height
+	DeleteLogUpToHeightQuery() string
+	// CompactedHeightQuery builds a SELECT query to get the highest height compacted in Log table
+	CompactedHeightQuery() string
+	// RenameTableQuery builds a query to rename a table (along with its constraints if needed)
+	RenameTableQuery(tableName, newTableName string) string
+	// CreateHistoryViewQuery builds a CREATE VIEW query returning every version of the rows of a table
+	// with their validity heights, from current rows & before-images stored in the Log table
+	CreateHistoryViewQuery(table types.SQLTable) string
+	// CreateEnrichedViewQuery builds a CREATE VIEW query returning the rows of a table
+	// along with block time, tx type & caller from Block & Tx tables
+	CreateEnrichedViewQuery(tableName string) string
+	// DropViewQuery builds a DROP VIEW query to delete a view if it exists
+	DropViewQuery(viewName string) string
+	// SelectAsOfQuery builds a SELECT query to get the rows of a table valid at a given height from its history view
+	SelectAsOfQuery(table types.SQLTable) string
 }
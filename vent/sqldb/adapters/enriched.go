@@ -0,0 +1,25 @@
+package adapters
+
+import (
+	"fmt"
+
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// enrichedViewQuery builds a CREATE VIEW query returning the rows of a table along with the time of their block
+// and the type & caller of their transaction, joined on block & tx table primary keys
+func enrichedViewQuery(viewName, tableName, blockTableName, txTableName string) string {
+	return fmt.Sprintf(`CREATE VIEW %s AS SELECT t.*, b.%s, tx.%s, tx.%s FROM %s t
+		LEFT JOIN %s b ON b.%s = t.%s
+		LEFT JOIN %s tx ON tx.%s = t.%s AND tx.%s = t.%s;`,
+		viewName,
+		types.SQLColumnLabelBlockTime, types.SQLColumnLabelTxType, types.SQLColumnLabelCaller,
+		tableName,
+		blockTableName, types.SQLColumnLabelHeight, types.SQLColumnLabelHeight,
+		txTableName, types.SQLColumnLabelHeight, types.SQLColumnLabelHeight, types.SQLColumnLabelTxHash, types.SQLColumnLabelTxHash)
+}
+
+// EnrichedViewName returns the name of the view joining a table with block & tx data
+func EnrichedViewName(tableName string) string {
+	return tableName + types.SQLEnrichedViewSuffix
+}
@@ -0,0 +1,46 @@
+package adapters
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// FullTextAdapter is implemented by db adapters able to search text columns,
+// full-text declarations are ignored by adapters not implementing it
+type FullTextAdapter interface {
+	// FullTextQuery builds the queries creating (if they do not exist) the structures needed to search
+	// the full-text columns of a table, they run whenever the table is synchronized
+	FullTextQuery(tableName string, columnNames []string) string
+	// SearchQuery builds a query selecting the rows of a table matching every word of a search ($1)
+	// in a full-text column, best matches first & up to a number of rows ($2)
+	SearchQuery(table types.SQLTable, columnName string) string
+}
+
+// FullTextTableAdapter is implemented by full-text adapters indexing the full-text columns of a table in another table,
+// the index table (named by FullTextTableName) is dropped when the full-text columns change so FullTextQuery recreates it
+type FullTextTableAdapter interface {
+	// DropFullTextQuery builds the queries dropping the structures needed to search the full-text columns of a table
+	DropFullTextQuery(tableName string) string
+}
+
+// FullTextColumnName returns the name of the column holding the searchable form of a full-text column
+func FullTextColumnName(columnName string) string {
+	return columnName + "_tsv"
+}
+
+// FullTextTableName returns the name of the table indexing the full-text columns of a table
+func FullTextTableName(tableName string) string {
+	return tableName + "_fts"
+}
+
+// searchFields returns the columns of a table qualified by a table alias
+func searchFields(table types.SQLTable, alias string, secure func(string) string) string {
+	fields := make([]string, 0, len(table.Columns))
+	for _, column := range sortedColumns(table) {
+		fields = append(fields, fmt.Sprintf("%s.%s", alias, secure(column.Name)))
+	}
+
+	return strings.Join(fields, ", ")
+}
@@ -0,0 +1,68 @@
+package adapters
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// historyViewQuery builds a CREATE VIEW query returning every version of the rows of a table:
+// current rows are valid from their height on, before-images stored in the log are valid from
+// their height to the height of the change that replaced them,
+// imageColumn returns the expression extracting a column from a log before-image (l._beforeimage)
+func historyViewQuery(viewName, tableName, logTableName string, table types.SQLTable, secure func(string) string,
+	imageColumn func(column types.SQLTableColumn) string, nullHeight, notNullImage string) string {
+
+	current := make([]string, 0, len(table.Columns)+2)
+	images := make([]string, 0, len(table.Columns)+2)
+
+	for _, column := range sortedColumns(table) {
+		current = append(current, secure(column.Name))
+		images = append(images, fmt.Sprintf("%s AS %s", imageColumn(column), secure(column.Name)))
+	}
+
+	current = append(current,
+		fmt.Sprintf("t.%s AS %s", types.SQLColumnLabelHeight, types.SQLColumnLabelValidFromHeight),
+		fmt.Sprintf("%s AS %s", nullHeight, types.SQLColumnLabelValidToHeight))
+
+	images = append(images,
+		fmt.Sprintf("%s AS %s", imageColumn(types.SQLTableColumn{Name: types.SQLColumnLabelHeight, Type: types.SQLColumnTypeBigInt}), types.SQLColumnLabelValidFromHeight),
+		fmt.Sprintf("l.%s AS %s", types.SQLColumnLabelHeight, types.SQLColumnLabelValidToHeight))
+
+	return fmt.Sprintf(`CREATE VIEW %s AS SELECT %s FROM %s t UNION ALL SELECT %s FROM %s l WHERE l.%s = '%s' AND l.%s IN ('%s', '%s') AND %s;`,
+		viewName,
+		strings.Join(current, ", "), tableName,
+		strings.Join(images, ", "), logTableName,
+		types.SQLColumnLabelTableName, table.Name,
+		types.SQLColumnLabelAction, types.ActionUpsert, types.ActionDelete,
+		notNullImage)
+}
+
+// ImageBytesDecoder is implemented by db adapters whose history views return byte columns of
+// before-images as they are stored in the log (base64 json strings), as they can't be decoded in SQL,
+// their as of height queries also return _valid_to_height (not null for before-images)
+type ImageBytesDecoder interface {
+	// DecodeImageBytes returns the bytes of a byte column value read from a before-image
+	DecodeImageBytes(value []byte) ([]byte, error)
+}
+
+// selectAsOfQuery builds a SELECT query returning rows of a history view valid at a given height,
+// extra fields of the view are returned after the table columns
+func selectAsOfQuery(viewName string, table types.SQLTable, secure func(string) string, param func(int) string, extraFields ...string) string {
+	fields := make([]string, 0, len(table.Columns)+len(extraFields))
+	for _, column := range sortedColumns(table) {
+		fields = append(fields, secure(column.Name))
+	}
+	fields = append(fields, extraFields...)
+
+	return fmt.Sprintf(`SELECT %s FROM %s WHERE %s <= %s AND (%s IS NULL OR %s > %s);`,
+		strings.Join(fields, ", "), viewName,
+		types.SQLColumnLabelValidFromHeight, param(1),
+		types.SQLColumnLabelValidToHeight, types.SQLColumnLabelValidToHeight, param(2))
+}
+
+// HistoryViewName returns the name of the history view of a table
+func HistoryViewName(tableName string) string {
+	return tableName + types.SQLHistoryViewSuffix
+}
@@ -0,0 +1,86 @@
+package adapters
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// identifierRules are the naming rules of a database,
+// identifiers are always quoted so SQL keywords are valid names
+type identifierRules struct {
+	// quote delimits identifiers
+	quote string
+	// maxLength is the maximum length of an identifier in bytes (0 for no limit)
+	maxLength int
+	// reservedPrefixes are name prefixes the database keeps for itself, even when quoted
+	reservedPrefixes []string
+}
+
+var (
+	pgIdentifierRules     = identifierRules{quote: `"`, maxLength: 63}
+	mysqlIdentifierRules  = identifierRules{quote: "`", maxLength: 64}
+	sqliteIdentifierRules = identifierRules{quote: `"`, reservedPrefixes: []string{"sqlite_"}}
+)
+
+// quoteIdentifier returns a name between quotes, quotes found in the name are doubled
+func (rules identifierRules) quoteIdentifier(name string) string {
+	return rules.quote + strings.Replace(name, rules.quote, rules.quote+rules.quote, -1) + rules.quote
+}
+
+// validateIdentifier returns an error if a name (of a given kind: table, column...) breaks naming rules,
+// quotes & backslashes are not allowed either as names are also used in string literals
+func (rules identifierRules) validateIdentifier(kind, name string) error {
+	if name == "" {
+		return fmt.Errorf("error %s name is empty", kind)
+	}
+
+	if !utf8.ValidString(name) {
+		return fmt.Errorf("error %s name %q is not valid UTF-8", kind, name)
+	}
+
+	if rules.maxLength > 0 && len(name) > rules.maxLength {
+		return fmt.Errorf("error %s name %s is %d bytes long, the maximum is %d", kind, name, len(name), rules.maxLength)
+	}
+
+	for _, r := range name {
+		if unicode.IsControl(r) || strings.ContainsRune("\"'`\\", r) {
+			return fmt.Errorf("error %s name %q contains invalid character %q", kind, name, r)
+		}
+	}
+
+	for _, prefix := range rules.reservedPrefixes {
+		if strings.HasPrefix(strings.ToLower(name), prefix) {
+			return fmt.Errorf("error %s name %s uses reserved prefix %s", kind, name, prefix)
+		}
+	}
+
+	return nil
+}
+
+// validateTableName returns an error if a table name or any name derived from it breaks naming rules
+func (rules identifierRules) validateTableName(tableName string, derivedNames ...string) error {
+	if err := rules.validateIdentifier("table", tableName); err != nil {
+		return err
+	}
+
+	for _, name := range append(derivedNames, HistoryViewName(tableName), EnrichedViewName(tableName)) {
+		if rules.maxLength > 0 && len(name) > rules.maxLength {
+			return fmt.Errorf("error table name %s is too long, derived name %s is %d bytes long, the maximum is %d",
+				tableName, name, len(name), rules.maxLength)
+		}
+	}
+
+	return nil
+}
+
+// validateColumnName returns an error if a column name breaks naming rules
+func (rules identifierRules) validateColumnName(columnName string) error {
+	return rules.validateIdentifier("column", columnName)
+}
+
+// primaryKeyName returns the name of the primary key constraint of a table
+func primaryKeyName(tableName string) string {
+	return tableName + "_pkey"
+}
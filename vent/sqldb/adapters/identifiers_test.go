@@ -0,0 +1,61 @@
+package adapters_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/monax/bosmarmot/vent/logger"
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentifiers(t *testing.T) {
+	log := logger.NewLogger("debug")
+
+	for _, dbAdapter := range []adapters.DBAdapter{
+		adapters.NewPostgresAdapter("vent", log),
+		adapters.NewSQLiteAdapter(log),
+		adapters.NewMySQLAdapter("vent", log),
+	} {
+		t.Run(fmt.Sprintf("%T: successfully quotes table & column names", dbAdapter), func(t *testing.T) {
+			require.Contains(t, dbAdapter.SecureTableName("select"), dbAdapter.SecureColumnName("select"))
+			require.NotEqual(t, "select", dbAdapter.SecureColumnName("select"))
+
+			// quotes are doubled
+			quoted := dbAdapter.SecureColumnName(`a"b`)
+			quote := quoted[:1]
+			require.Equal(t, quote+strings.Replace(`a"b`, quote, quote+quote, -1)+quote, quoted)
+		})
+
+		t.Run(fmt.Sprintf("%T: successfully validates table & column names", dbAdapter), func(t *testing.T) {
+			require.NoError(t, dbAdapter.ValidateTableName("order"))
+			require.NoError(t, dbAdapter.ValidateTableName("user accounts-2018"))
+			require.NoError(t, dbAdapter.ValidateColumnName("from"))
+
+			require.Error(t, dbAdapter.ValidateTableName(""))
+			require.Error(t, dbAdapter.ValidateTableName("user'accounts"))
+			require.Error(t, dbAdapter.ValidateColumnName(`user"name`))
+			require.Error(t, dbAdapter.ValidateColumnName("user\x00name"))
+			require.Error(t, dbAdapter.ValidateColumnName("user\\name"))
+		})
+	}
+
+	t.Run("PostgresAdapter: returns an error if a name or a derived name is too long", func(t *testing.T) {
+		dbAdapter := adapters.NewPostgresAdapter("vent", log)
+
+		require.NoError(t, dbAdapter.ValidateColumnName(strings.Repeat("a", 63)))
+		require.Error(t, dbAdapter.ValidateColumnName(strings.Repeat("a", 64)))
+
+		// <table>_enriched view name is the longest derived name
+		require.NoError(t, dbAdapter.ValidateTableName(strings.Repeat("a", 54)))
+		require.Error(t, dbAdapter.ValidateTableName(strings.Repeat("a", 55)))
+	})
+
+	t.Run("SQLiteAdapter: returns an error if a name uses a reserved prefix", func(t *testing.T) {
+		dbAdapter := adapters.NewSQLiteAdapter(log)
+
+		require.Error(t, dbAdapter.ValidateTableName("SQLITE_accounts"))
+		require.NoError(t, dbAdapter.ValidateTableName(strings.Repeat("a", 100)))
+	})
+}
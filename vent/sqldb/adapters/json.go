@@ -0,0 +1,49 @@
+package adapters
+
+import (
+	"fmt"
+	"hash/crc32"
+	"unicode/utf8"
+
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// IndexAdapter is implemented by db adapters able to index JSON columns,
+// index declarations are ignored by adapters not implementing it
+type IndexAdapter interface {
+	// CreateIndexQuery builds a CREATE INDEX query (doing nothing if the index exists)
+	// to index a JSON column with a given method
+	CreateIndexQuery(tableName, columnName, method string) (string, error)
+	// RenameIndexQuery builds a query renaming the index of a column (if any) after its table has been renamed
+	RenameIndexQuery(tableName, newTableName, columnName string) string
+}
+
+// JSONPathAdapter is implemented by db adapters able to generate columns from JSON paths,
+// table JSON paths are ignored by adapters not implementing it
+type JSONPathAdapter interface {
+	// AddJSONPathQuery builds an ALTER TABLE query to add a column generated from a JSON path
+	AddJSONPathQuery(tableName string, jsonPath types.SQLJSONPath) string
+}
+
+// IndexName returns the name of the index of a table column
+func IndexName(tableName, columnName string) string {
+	return fmt.Sprintf("%s_%s_idx", tableName, columnName)
+}
+
+// shortenIdentifier returns a name fitting the identifier maximum length,
+// longer names are truncated & suffixed with a checksum of the whole name to keep them distinct
+func (rules identifierRules) shortenIdentifier(name string) string {
+	if rules.maxLength == 0 || len(name) <= rules.maxLength {
+		return name
+	}
+
+	suffix := fmt.Sprintf("_%08x", crc32.ChecksumIEEE([]byte(name)))
+
+	// do not split multi-byte characters
+	length := rules.maxLength - len(suffix)
+	for length > 0 && !utf8.RuneStart(name[length]) {
+		length--
+	}
+
+	return name[:length] + suffix
+}
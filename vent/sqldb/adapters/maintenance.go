@@ -0,0 +1,15 @@
+package adapters
+
+// CheckpointAdapter is implemented by db adapters keeping a write-ahead log that is copied back
+// to the database periodically, checkpoints are skipped for adapters not implementing it
+type CheckpointAdapter interface {
+	// CheckpointQuery builds a query copying the write-ahead log to the database & truncating it,
+	// it returns whether the checkpoint was blocked, the log size & the number of pages copied
+	CheckpointQuery() string
+}
+
+// BackupAdapter is implemented by db adapters able to copy a database while it is being written
+type BackupAdapter interface {
+	// Backup copies the database the adapter opened to a destination (such as a file path), replacing its content
+	Backup(destination string) error
+}
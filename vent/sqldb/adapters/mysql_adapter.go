@@ -0,0 +1,825 @@
+package adapters
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/monax/bosmarmot/vent/logger"
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// numeric columns (e.g. uint256) are stored as canonical decimal text,
+// as DECIMAL holds 65 digits at most and 256 bit integers need up to 78
+var mysqlDataTypes = map[types.SQLColumnType]string{
+	types.SQLColumnTypeBool:      "BOOLEAN",
+	types.SQLColumnTypeByteA:     "LONGBLOB",
+	types.SQLColumnTypeInt:       "INTEGER",
+	types.SQLColumnTypeSerial:    "BIGINT AUTO_INCREMENT",
+	types.SQLColumnTypeText:      "LONGTEXT",
+	types.SQLColumnTypeVarchar:   "VARCHAR",
+	types.SQLColumnTypeTimeStamp: "TIMESTAMP",
+	types.SQLColumnTypeNumeric:   "VARCHAR(78)",
+	types.SQLColumnTypeJSON:      "JSON",
+	types.SQLColumnTypeBigInt:    "BIGINT",
+	types.SQLColumnTypeJSONB:     "JSON",
+}
+
+// mysqlCatalogTypes are the data types reported by information_schema for each generic column type
+var mysqlCatalogTypes = map[types.SQLColumnType]string{
+	types.SQLColumnTypeBool:      "tinyint",
+	types.SQLColumnTypeByteA:     "longblob",
+	types.SQLColumnTypeInt:       "int",
+	types.SQLColumnTypeSerial:    "bigint",
+	types.SQLColumnTypeText:      "longtext",
+	types.SQLColumnTypeVarchar:   "varchar",
+	types.SQLColumnTypeTimeStamp: "timestamp",
+	types.SQLColumnTypeNumeric:   "varchar",
+	types.SQLColumnTypeJSON:      "json",
+	types.SQLColumnTypeBigInt:    "bigint",
+	types.SQLColumnTypeJSONB:     "json",
+}
+
+// mysql server error numbers
+const (
+	mysqlErrDuplicatedDatabase = 1007
+	mysqlErrDuplicatedTable    = 1050
+	mysqlErrUndefinedColumn    = 1054
+	mysqlErrDuplicatedColumn   = 1060
+	mysqlErrUndefinedTable     = 1146
+	mysqlErrInvalidValue       = 1366
+	mysqlErrNotSupported       = 1235
+)
+
+// mysqlKeyLength is the length of VARCHAR & VARBINARY primary key columns holding text & bytes,
+// as whole keys are indexed up to three of them fit in the InnoDB key size limit
+const mysqlKeyLength = 255
+
+// mysqlStrictMode makes MySQL reject values too long for their column instead of truncating them
+const mysqlStrictMode = "CONCAT(@@sql_mode, ',STRICT_ALL_TABLES')"
+
+func init() {
+	Register(types.MySQLDB, func(schema string, log *logger.Logger) DBAdapter {
+		return NewMySQLAdapter(schema, log)
+	})
+}
+
+// MySQLAdapter implements DBAdapter for MySQL (and MariaDB),
+// the Schema is a MySQL database
+type MySQLAdapter struct {
+	Log    *logger.Logger
+	Schema string
+}
+
+// NewMySQLAdapter constructs a new db adapter
+func NewMySQLAdapter(schema string, log *logger.Logger) *MySQLAdapter {
+	return &MySQLAdapter{
+		Log:    log,
+		Schema: schema,
+	}
+}
+
+// Open connects to a MySQL server, opens it & create the schema database if provided
+func (adapter *MySQLAdapter) Open(dbURL string) (*sql.DB, error) {
+	cfg, err := mysql.ParseDSN(dbURL)
+	if err != nil {
+		adapter.Log.Info("msg", "Error parsing database URL", "err", err)
+		return nil, err
+	}
+
+	// keys longer than their column must not be truncated into an existing key
+	if _, ok := cfg.Params["sql_mode"]; !ok {
+		if cfg.Params == nil {
+			cfg.Params = make(map[string]string)
+		}
+		cfg.Params["sql_mode"] = mysqlStrictMode
+	}
+
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		adapter.Log.Info("msg", "Error creating database connection", "err", err)
+		return nil, err
+	}
+
+	// if there is a supplied Schema
+	if adapter.Schema != "" {
+		if err = mysqlIdentifierRules.validateIdentifier("schema", adapter.Schema); err != nil {
+			return nil, err
+		}
+
+		if err = db.Ping(); err != nil {
+			adapter.Log.Info("msg", "Error opening database connection", "err", err)
+			return nil, err
+		}
+
+		query := fmt.Sprintf("CREATE DATABASE %s;", adapter.SecureColumnName(adapter.Schema))
+		adapter.Log.Info("msg", "CREATE SCHEMA", "query", query)
+
+		if _, err = db.Exec(query); err != nil {
+			if !adapter.ErrorEquals(err, types.SQLErrorTypeDuplicatedSchema) {
+				adapter.Log.Info("msg", "Error creating schema", "err", err)
+				return nil, err
+			}
+			adapter.Log.Warn("msg", "Duplicated schema")
+		}
+	}
+
+	return db, nil
+}
+
+// TypeMapping convert generic dataTypes to database dependent dataTypes
+func (adapter *MySQLAdapter) TypeMapping(sqlColumnType types.SQLColumnType) (string, error) {
+	if sqlDataType, ok := mysqlDataTypes[sqlColumnType]; ok {
+		return sqlDataType, nil
+	}
+
+	return "", fmt.Errorf("datatype %v not recognized", sqlColumnType)
+}
+
+// SecureColumnName return columns between appropriate security containers
+func (adapter *MySQLAdapter) SecureColumnName(columnName string) string {
+	return mysqlIdentifierRules.quoteIdentifier(columnName)
+}
+
+// SecureTableName returns a table name qualified by the schema database (if any) between appropriate security containers
+func (adapter *MySQLAdapter) SecureTableName(tableName string) string {
+	if adapter.Schema == "" {
+		return adapter.SecureColumnName(tableName)
+	}
+	return fmt.Sprintf("%s.%s", adapter.SecureColumnName(adapter.Schema), adapter.SecureColumnName(tableName))
+}
+
+// ValidateTableName returns an error if a table name breaks MySQL naming rules
+func (adapter *MySQLAdapter) ValidateTableName(tableName string) error {
+	return mysqlIdentifierRules.validateTableName(tableName)
+}
+
+// ValidateColumnName returns an error if a column name breaks MySQL naming rules
+func (adapter *MySQLAdapter) ValidateColumnName(columnName string) error {
+	return mysqlIdentifierRules.validateColumnName(columnName)
+}
+
+// columnType returns the column type definition including its length
+func (adapter *MySQLAdapter) columnType(sqlColumnType types.SQLColumnType, length int) string {
+	sqlType, _ := adapter.TypeMapping(sqlColumnType)
+	if length > 0 {
+		sqlType = fmt.Sprintf("%s(%d)", sqlType, length)
+	}
+	return sqlType
+}
+
+// tableColumnType returns the type definition of a table column, TEXT & BLOB columns can only be indexed
+// on a prefix so primary keys of these types are stored as VARCHAR & VARBINARY to index whole keys
+func (adapter *MySQLAdapter) tableColumnType(column types.SQLTableColumn) string {
+	if column.Primary {
+		switch column.Type {
+		case types.SQLColumnTypeText:
+			return fmt.Sprintf("VARCHAR(%d)", mysqlKeyLength)
+		case types.SQLColumnTypeByteA:
+			return fmt.Sprintf("VARBINARY(%d)", mysqlKeyLength)
+		}
+	}
+	return adapter.columnType(column.Type, column.Length)
+}
+
+// CreateTableQuery builds query for creating a new table
+func (adapter *MySQLAdapter) CreateTableQuery(tableName string, columns []types.SQLTableColumn) (string, string) {
+	// build query
+	columnsDef := ""
+	primaryKey := ""
+	dictionaryValues := ""
+
+	for i, tableColumn := range columns {
+		secureColumn := adapter.SecureColumnName(tableColumn.Name)
+		pKey := 0
+
+		if columnsDef != "" {
+			columnsDef += ", "
+			dictionaryValues += ", "
+		}
+
+		columnsDef += fmt.Sprintf("%s %s", secureColumn, adapter.tableColumnType(tableColumn))
+
+		if tableColumn.Primary {
+			pKey = 1
+			columnsDef += " NOT NULL"
+			if primaryKey != "" {
+				primaryKey += ", "
+			}
+			primaryKey += secureColumn
+		}
+
+		dictionaryValues += fmt.Sprintf("('%s','%s',%d,%d,%d,%d)",
+			tableName,
+			tableColumn.Name,
+			tableColumn.Type,
+			tableColumn.Length,
+			pKey,
+			i)
+	}
+
+	query := fmt.Sprintf("CREATE TABLE %s (%s", adapter.SecureTableName(tableName), columnsDef)
+	if primaryKey != "" {
+		query += "," + fmt.Sprintf("CONSTRAINT %s PRIMARY KEY (%s)", adapter.SecureColumnName(primaryKeyName(tableName)), primaryKey)
+	}
+	query += ");"
+
+	dictionaryQuery := fmt.Sprintf("INSERT INTO %s (%s,%s,%s,%s,%s,%s) VALUES %s;",
+		adapter.SecureTableName(types.SQLDictionaryTableName),
+		types.SQLColumnLabelTableName, types.SQLColumnLabelColumnName,
+		types.SQLColumnLabelColumnType, types.SQLColumnLabelColumnLength,
+		types.SQLColumnLabelPrimaryKey, types.SQLColumnLabelColumnOrder,
+		dictionaryValues)
+
+	return query, dictionaryQuery
+}
+
+// LastBlockIDQuery returns a query for last inserted blockId in log table
+func (adapter *MySQLAdapter) LastBlockIDQuery() string {
+	query := `
+		WITH ll AS (
+			SELECT MAX(%s) AS %s FROM %s WHERE %s IS NOT NULL
+		)
+		SELECT COALESCE(%s, '0') AS %s
+			FROM ll LEFT OUTER JOIN %s log ON (ll.%s = log.%s);`
+
+	return fmt.Sprintf(query,
+		types.SQLColumnLabelId,                         // max
+		types.SQLColumnLabelId,                         // as
+		adapter.SecureTableName(types.SQLLogTableName), // from
+		types.SQLColumnLabelHeight,                     // where
+		types.SQLColumnLabelHeight,                     // coalesce
+		types.SQLColumnLabelHeight,                     // as
+		adapter.SecureTableName(types.SQLLogTableName), // from
+		types.SQLColumnLabelId, types.SQLColumnLabelId) // on
+}
+
+// FindTableQuery returns a query that checks if a table exists
+func (adapter *MySQLAdapter) FindTableQuery() string {
+	query := "SELECT COUNT(*) found FROM %s WHERE %s = ?;"
+
+	return fmt.Sprintf(query,
+		adapter.SecureTableName(types.SQLDictionaryTableName), // from
+		types.SQLColumnLabelTableName)                         // where
+}
+
+// TableDefinitionQuery returns a query with table structure
+func (adapter *MySQLAdapter) TableDefinitionQuery() string {
+	query := `
+		SELECT
+			%s,%s,%s,%s
+		FROM
+			%s
+		WHERE
+			%s = ?
+		ORDER BY
+			%s;`
+
+	return fmt.Sprintf(query,
+		types.SQLColumnLabelColumnName, types.SQLColumnLabelColumnType, // select
+		types.SQLColumnLabelColumnLength, types.SQLColumnLabelPrimaryKey, // select
+		adapter.SecureTableName(types.SQLDictionaryTableName), // from
+		types.SQLColumnLabelTableName,                         // where
+		types.SQLColumnLabelColumnOrder)                       // order by
+}
+
+// TableColumnsQuery returns a query with the actual table structure from information_schema
+func (adapter *MySQLAdapter) TableColumnsQuery(tableName string) types.UpsertDeleteQuery {
+	query := `
+		SELECT
+			COLUMN_NAME, DATA_TYPE, CHARACTER_MAXIMUM_LENGTH
+		FROM
+			information_schema.COLUMNS
+		WHERE
+			TABLE_SCHEMA = COALESCE(?, DATABASE()) AND TABLE_NAME = ?
+		ORDER BY
+			ORDINAL_POSITION;`
+
+	// tables are in the current database if there is no schema
+	var schema interface{}
+	if adapter.Schema != "" {
+		schema = adapter.Schema
+	}
+
+	return types.UpsertDeleteQuery{
+		Query:    query,
+		Values:   fmt.Sprintf("%s, %s", adapter.Schema, tableName),
+		Pointers: []interface{}{schema, tableName},
+	}
+}
+
+// CatalogColumnType returns the information_schema data type of a generic column type
+func (adapter *MySQLAdapter) CatalogColumnType(sqlColumnType types.SQLColumnType) string {
+	return mysqlCatalogTypes[sqlColumnType]
+}
+
+// KeyCatalogColumnType returns the data type reported by information_schema for a primary key column
+func (adapter *MySQLAdapter) KeyCatalogColumnType(sqlColumnType types.SQLColumnType) string {
+	switch sqlColumnType {
+	case types.SQLColumnTypeText:
+		return "varchar"
+	case types.SQLColumnTypeByteA:
+		return "varbinary"
+	default:
+		return adapter.CatalogColumnType(sqlColumnType)
+	}
+}
+
+// AlterColumnQuery returns a query for adding a new column to a table
+func (adapter *MySQLAdapter) AlterColumnQuery(tableName, columnName string, sqlColumnType types.SQLColumnType, length, order int) (string, string) {
+	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;",
+		adapter.SecureTableName(tableName),
+		adapter.SecureColumnName(columnName),
+		adapter.columnType(sqlColumnType, length))
+
+	dictionaryQuery := fmt.Sprintf(`
+		INSERT INTO %s (%s,%s,%s,%s,%s,%s)
+		VALUES ('%s','%s',%d,%d,%d,%d);`,
+
+		adapter.SecureTableName(types.SQLDictionaryTableName),
+
+		types.SQLColumnLabelTableName, types.SQLColumnLabelColumnName,
+		types.SQLColumnLabelColumnType, types.SQLColumnLabelColumnLength,
+		types.SQLColumnLabelPrimaryKey, types.SQLColumnLabelColumnOrder,
+
+		tableName, columnName, sqlColumnType, length, 0, order)
+
+	return query, dictionaryQuery
+}
+
+// AlterColumnTypeQuery returns a query for changing the type of an existing column
+func (adapter *MySQLAdapter) AlterColumnTypeQuery(tableName string, columns []types.SQLTableColumn, column types.SQLTableColumn) (string, string) {
+	// MODIFY COLUMN redefines the whole column so primary keys must stay NOT NULL
+	nullable := ""
+	if column.Primary {
+		nullable = " NOT NULL"
+	}
+
+	query := fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s%s;",
+		adapter.SecureTableName(tableName),
+		adapter.SecureColumnName(column.Name),
+		adapter.tableColumnType(column),
+		nullable)
+
+	dictionaryQuery := fmt.Sprintf(`
+		UPDATE %s SET %s = %d, %s = %d
+		WHERE %s = '%s' AND %s = '%s';`,
+
+		adapter.SecureTableName(types.SQLDictionaryTableName),
+
+		types.SQLColumnLabelColumnType, column.Type,
+		types.SQLColumnLabelColumnLength, column.Length,
+
+		types.SQLColumnLabelTableName, tableName,
+		types.SQLColumnLabelColumnName, column.Name)
+
+	return query, dictionaryQuery
+}
+
+// RenameColumnQuery returns a query for renaming an existing column
+func (adapter *MySQLAdapter) RenameColumnQuery(tableName, columnName, newColumnName string) (string, string) {
+	query := fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;",
+		adapter.SecureTableName(tableName),
+		adapter.SecureColumnName(columnName),
+		adapter.SecureColumnName(newColumnName))
+
+	dictionaryQuery := fmt.Sprintf(`
+		UPDATE %s SET %s = '%s'
+		WHERE %s = '%s' AND %s = '%s';`,
+
+		adapter.SecureTableName(types.SQLDictionaryTableName),
+
+		types.SQLColumnLabelColumnName, newColumnName,
+
+		types.SQLColumnLabelTableName, tableName,
+		types.SQLColumnLabelColumnName, columnName)
+
+	return query, dictionaryQuery
+}
+
+// DropColumnQuery returns a query for removing an existing column
+func (adapter *MySQLAdapter) DropColumnQuery(tableName string, columns []types.SQLTableColumn, columnName string) (string, string) {
+	query := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;",
+		adapter.SecureTableName(tableName),
+		adapter.SecureColumnName(columnName))
+
+	dictionaryQuery := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE %s = '%s' AND %s = '%s';`,
+
+		adapter.SecureTableName(types.SQLDictionaryTableName),
+
+		types.SQLColumnLabelTableName, tableName,
+		types.SQLColumnLabelColumnName, columnName)
+
+	return query, dictionaryQuery
+}
+
+// SelectRowQuery returns a query for selecting row values
+func (adapter *MySQLAdapter) SelectRowQuery(tableName, fields, indexValue string) string {
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s = '%s';", fields, adapter.SecureTableName(tableName), types.SQLColumnLabelHeight, indexValue)
+}
+
+// SelectLogQuery returns a query for selecting all tables involved in a block trn
+func (adapter *MySQLAdapter) SelectLogQuery() string {
+	query := `
+		SELECT DISTINCT %s,%s FROM %s l WHERE %s = ?;`
+
+	return fmt.Sprintf(query,
+		types.SQLColumnLabelTableName, types.SQLColumnLabelEventName, // select
+		adapter.SecureTableName(types.SQLLogTableName), // from
+		types.SQLColumnLabelHeight)                     // where
+}
+
+// InsertLogQuery returns a query to insert a row in log table
+func (adapter *MySQLAdapter) InsertLogQuery() string {
+	query := `
+		INSERT INTO %s (%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s)
+		VALUES (CURRENT_TIMESTAMP, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	return fmt.Sprintf(query,
+		adapter.SecureTableName(types.SQLLogTableName), // insert
+		//fields
+		types.SQLColumnLabelTimeStamp, types.SQLColumnLabelTableName, types.SQLColumnLabelEventName, types.SQLColumnLabelEventFilter,
+		types.SQLColumnLabelHeight, types.SQLColumnLabelTxHash, types.SQLColumnLabelAction, types.SQLColumnLabelDataRow,
+		types.SQLColumnLabelSqlStmt, types.SQLColumnLabelSqlValues, types.SQLColumnLabelBeforeImage)
+}
+
+// ErrorEquals verify if an error is of a given SQL type
+func (adapter *MySQLAdapter) ErrorEquals(err error, sqlErrorType types.SQLErrorType) bool {
+	if err, ok := err.(*mysql.MySQLError); ok {
+		switch sqlErrorType {
+		case types.SQLErrorTypeGeneric:
+			return true
+		case types.SQLErrorTypeDuplicatedColumn:
+			return err.Number == mysqlErrDuplicatedColumn
+		case types.SQLErrorTypeDuplicatedTable:
+			return err.Number == mysqlErrDuplicatedTable
+		case types.SQLErrorTypeDuplicatedSchema:
+			return err.Number == mysqlErrDuplicatedDatabase
+		case types.SQLErrorTypeUndefinedTable:
+			return err.Number == mysqlErrUndefinedTable
+		case types.SQLErrorTypeUndefinedColumn:
+			return err.Number == mysqlErrUndefinedColumn
+		case types.SQLErrorTypeInvalidType:
+			return err.Number == mysqlErrInvalidValue
+		case types.SQLErrorTypeUnsupported:
+			return err.Number == mysqlErrNotSupported
+		}
+	}
+
+	return false
+}
+
+func (adapter *MySQLAdapter) UpsertQuery(table types.SQLTable, row types.EventDataRow) (types.UpsertDeleteQuery, interface{}, error) {
+
+	pointers := make([]interface{}, 0)
+	columns := ""
+	insValues := ""
+	updValues := ""
+	pkColumn := ""
+	values := ""
+	var txHash interface{} = nil
+
+	// for each column in table
+	for _, tableColumn := range sortedColumns(table) {
+		secureColumn := adapter.SecureColumnName(tableColumn.Name)
+
+		// INSERT INTO TABLE (*columns).........
+		if columns != "" {
+			columns += ", "
+			insValues += ", "
+			values += ", "
+		}
+		columns += secureColumn
+		insValues += "?"
+
+		//find data for column
+		if value, ok := row.RowData[tableColumn.Name]; ok {
+			//load hash value
+			if tableColumn.Name == types.SQLColumnLabelTxHash {
+				txHash = value
+			}
+
+			// column found (not null)
+			// load values
+			pointers = append(pointers, &value)
+			values += fmt.Sprint(value)
+
+			if !tableColumn.Primary {
+				// column is no PK
+				// add to update list
+				// INSERT........... ON DUPLICATE KEY UPDATE (*updValues)
+				if updValues != "" {
+					updValues += ", "
+				}
+				updValues += fmt.Sprintf("%s = VALUES(%s)", secureColumn, secureColumn)
+			}
+		} else if tableColumn.Primary {
+			// column NOT found (is null) and is PK
+			return types.UpsertDeleteQuery{}, nil, fmt.Errorf("error null primary key for column %s", secureColumn)
+		} else {
+			// column NOT found (is null) and is NOT PK
+			pointers = append(pointers, nil)
+			values += "null"
+		}
+
+		if tableColumn.Primary && pkColumn == "" {
+			pkColumn = secureColumn
+		}
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ", adapter.SecureTableName(table.Name), columns, insValues)
+
+	if updValues != "" {
+		query += fmt.Sprintf("ON DUPLICATE KEY UPDATE %s", updValues)
+	} else if pkColumn != "" {
+		// nothing to update, keep the existing row
+		query += fmt.Sprintf("ON DUPLICATE KEY UPDATE %s = %s", pkColumn, pkColumn)
+	}
+	query += ";"
+
+	return types.UpsertDeleteQuery{Query: query, Values: values, Pointers: pointers}, txHash, nil
+}
+
+func (adapter *MySQLAdapter) DeleteQuery(table types.SQLTable, row types.EventDataRow) (types.UpsertDeleteQuery, error) {
+
+	pointers := make([]interface{}, 0)
+	columns := ""
+	values := ""
+
+	// for each column in table
+	for _, tableColumn := range sortedColumns(table) {
+
+		//only PK for delete
+		if tableColumn.Primary {
+			secureColumn := adapter.SecureColumnName(tableColumn.Name)
+
+			// WHERE ..........
+			if columns != "" {
+				columns += "AND "
+				values += ", "
+			}
+
+			columns += fmt.Sprintf("%s = ? ", secureColumn)
+
+			//find data for column
+			if value, ok := row.RowData[tableColumn.Name]; ok {
+				// column found (not null)
+				// load values
+				pointers = append(pointers, &value)
+				values += fmt.Sprint(value)
+
+			} else {
+				// column NOT found (is null) and is PK
+				return types.UpsertDeleteQuery{}, fmt.Errorf("error null primary key for column %s", secureColumn)
+			}
+		}
+	}
+
+	if columns == "" {
+		return types.UpsertDeleteQuery{}, fmt.Errorf("error primary key not found for deletion")
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s;", adapter.SecureTableName(table.Name), columns)
+
+	return types.UpsertDeleteQuery{Query: query, Values: values, Pointers: pointers}, nil
+}
+
+// RestoreDBQuery returns a query for selecting upserts & deletes logged up to a given time
+func (adapter *MySQLAdapter) RestoreDBQuery() string {
+	return fmt.Sprintf(`SELECT %s, %s, %s FROM %s WHERE %s <= ? AND %s IN ('%s', '%s') ORDER BY %s;`,
+		types.SQLColumnLabelTableName, types.SQLColumnLabelAction, types.SQLColumnLabelDataRow,
+		adapter.SecureTableName(types.SQLLogTableName),
+		types.SQLColumnLabelTimeStamp,
+		types.SQLColumnLabelAction, types.ActionUpsert, types.ActionDelete,
+		types.SQLColumnLabelId)
+}
+
+// RestoreHeightQuery returns a query for selecting upserts & deletes logged up to a given height
+func (adapter *MySQLAdapter) RestoreHeightQuery() string {
+	return fmt.Sprintf(`SELECT %s, %s, %s FROM %s WHERE %s <= ? AND %s IN ('%s', '%s') ORDER BY %s;`,
+		types.SQLColumnLabelTableName, types.SQLColumnLabelAction, types.SQLColumnLabelDataRow,
+		adapter.SecureTableName(types.SQLLogTableName),
+		types.SQLColumnLabelHeight,
+		types.SQLColumnLabelAction, types.ActionUpsert, types.ActionDelete,
+		types.SQLColumnLabelId)
+}
+
+func (adapter *MySQLAdapter) CleanDBQueries() types.SQLCleanDBQuery {
+
+	// Chain info
+	selectChainIDQry := fmt.Sprintf(`
+		SELECT
+		COUNT(*) REGISTERS,
+		COALESCE(MAX(%s),'') CHAINID,
+		COALESCE(MAX(%s),'') BVERSION
+		FROM %s;`,
+		types.SQLColumnLabelChainID, types.SQLColumnLabelBurrowVer,
+		adapter.SecureTableName(types.SQLChainInfoTableName))
+
+	deleteChainIDQry := fmt.Sprintf(`
+		DELETE FROM %s;`,
+		adapter.SecureTableName(types.SQLChainInfoTableName))
+
+	insertChainIDQry := fmt.Sprintf(`
+		INSERT INTO %s (%s,%s) VALUES(?,?)`,
+		adapter.SecureTableName(types.SQLChainInfoTableName),
+		types.SQLColumnLabelChainID, types.SQLColumnLabelBurrowVer)
+
+	// Dictionary
+	selectDictionaryQry := fmt.Sprintf(`
+		SELECT DISTINCT %s
+		FROM %s
+ 		WHERE %s
+		NOT IN ('%s','%s','%s','%s');`,
+		types.SQLColumnLabelTableName,
+		adapter.SecureTableName(types.SQLDictionaryTableName),
+		types.SQLColumnLabelTableName,
+		types.SQLLogTableName, types.SQLDictionaryTableName, types.SQLChainInfoTableName, types.SQLAbiTableName)
+
+	deleteDictionaryQry := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE %s
+		NOT IN ('%s','%s','%s','%s');`,
+		adapter.SecureTableName(types.SQLDictionaryTableName),
+		types.SQLColumnLabelTableName,
+		types.SQLLogTableName, types.SQLDictionaryTableName, types.SQLChainInfoTableName, types.SQLAbiTableName)
+
+	// log
+	deleteLogQry := fmt.Sprintf(`
+		DELETE FROM %s;`,
+		adapter.SecureTableName(types.SQLLogTableName))
+
+	// abi registry
+	deleteAbiQry := fmt.Sprintf(`
+		DELETE FROM %s;`,
+		adapter.SecureTableName(types.SQLAbiTableName))
+
+	return types.SQLCleanDBQuery{
+		SelectChainIDQry:    selectChainIDQry,
+		DeleteChainIDQry:    deleteChainIDQry,
+		InsertChainIDQry:    insertChainIDQry,
+		SelectDictionaryQry: selectDictionaryQry,
+		DeleteDictionaryQry: deleteDictionaryQry,
+		DeleteLogQry:        deleteLogQry,
+		DeleteAbiQry:        deleteAbiQry,
+	}
+}
+
+// SelectAbiQuery returns a query for selecting all abi specifications stored by contract address
+func (adapter *MySQLAdapter) SelectAbiQuery() string {
+	return fmt.Sprintf("SELECT %s, %s FROM %s;",
+		types.SQLColumnLabelAddress, types.SQLColumnLabelAbi,
+		adapter.SecureTableName(types.SQLAbiTableName))
+}
+
+func (adapter *MySQLAdapter) DropTableQuery(tableName string) string {
+	//drop tables
+	return fmt.Sprintf(`DROP TABLE %s;`, adapter.SecureTableName(tableName))
+}
+
+// SelectByKeyQuery returns a query for selecting the current contents of rows by PK
+func (adapter *MySQLAdapter) SelectByKeyQuery(table types.SQLTable, rows []types.EventDataRow) (types.UpsertDeleteQuery, error) {
+	return selectByKeyQuery(adapter.SecureTableName(table.Name), table, rows, adapter.SecureColumnName, questionParam)
+}
+
+// SelectLogAfterHeightQuery returns a query for selecting upserts & deletes logged after a given height
+func (adapter *MySQLAdapter) SelectLogAfterHeightQuery() string {
+	return fmt.Sprintf(`SELECT %s, %s, %s, %s, %s FROM %s WHERE %s > ? AND %s IN ('%s', '%s') ORDER BY %s;`,
+		types.SQLColumnLabelId, types.SQLColumnLabelTableName, types.SQLColumnLabelAction, types.SQLColumnLabelDataRow, types.SQLColumnLabelBeforeImage,
+		adapter.SecureTableName(types.SQLLogTableName),
+		types.SQLColumnLabelHeight,
+		types.SQLColumnLabelAction, types.ActionUpsert, types.ActionDelete,
+		types.SQLColumnLabelId)
+}
+
+// DeleteLogAfterHeightQuery returns a query for deleting rows logged after a given height
+func (adapter *MySQLAdapter) DeleteLogAfterHeightQuery() string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE %s > ?;`, adapter.SecureTableName(types.SQLLogTableName), types.SQLColumnLabelHeight)
+}
+
+// SelectLogUpToHeightQuery returns a query for selecting upserts & deletes logged up to a given height
+func (adapter *MySQLAdapter) SelectLogUpToHeightQuery() string {
+	return selectLogUpToHeightQuery(adapter.SecureTableName(types.SQLLogTableName), "?")
+}
+
+// CheckpointLogQuery returns a query to keep a checkpoint for each block logged up to a given height
+func (adapter *MySQLAdapter) CheckpointLogQuery() string {
+	return checkpointLogQuery(adapter.SecureTableName(types.SQLLogTableName), "?")
+}
+
+// DeleteLogUpToHeightQuery returns a query for deleting upserts & deletes logged up to a given height
+func (adapter *MySQLAdapter) DeleteLogUpToHeightQuery() string {
+	return deleteLogUpToHeightQuery(adapter.SecureTableName(types.SQLLogTableName), "?")
+}
+
+// CompactedHeightQuery returns a query for selecting the highest compacted height
+func (adapter *MySQLAdapter) CompactedHeightQuery() string {
+	return compactedHeightQuery(adapter.SecureTableName(types.SQLLogTableName))
+}
+
+// RenameTableQuery returns a query to rename a table
+func (adapter *MySQLAdapter) RenameTableQuery(tableName, newTableName string) string {
+	return fmt.Sprintf(`RENAME TABLE %s TO %s;`, adapter.SecureTableName(tableName), adapter.SecureTableName(newTableName))
+}
+
+// CreateHistoryViewQuery returns a query to create the history view of a table
+func (adapter *MySQLAdapter) CreateHistoryViewQuery(table types.SQLTable) string {
+	imageColumn := func(column types.SQLTableColumn) string {
+		value := fmt.Sprintf(`JSON_EXTRACT(l.%s, '$."%s"')`, types.SQLColumnLabelBeforeImage, column.Name)
+
+		// json null values are not unquoted as sql NULL
+		unquoted := fmt.Sprintf("CASE WHEN JSON_TYPE(%s) = 'NULL' THEN NULL ELSE JSON_UNQUOTE(%s) END", value, value)
+		switch column.Type {
+		case types.SQLColumnTypeByteA:
+			return fmt.Sprintf("FROM_BASE64(%s)", unquoted)
+		case types.SQLColumnTypeInt, types.SQLColumnTypeBigInt:
+			return fmt.Sprintf("CAST(%s AS SIGNED)", unquoted)
+		default:
+			return unquoted
+		}
+	}
+
+	return historyViewQuery(
+		adapter.SecureTableName(HistoryViewName(table.Name)),
+		adapter.SecureTableName(table.Name),
+		adapter.SecureTableName(types.SQLLogTableName),
+		table, adapter.SecureColumnName, imageColumn,
+		"NULL",
+		fmt.Sprintf("JSON_TYPE(l.%s) <> 'NULL'", types.SQLColumnLabelBeforeImage))
+}
+
+// CreateEnrichedViewQuery returns a query to create the view joining a table with block & tx data
+func (adapter *MySQLAdapter) CreateEnrichedViewQuery(tableName string) string {
+	return enrichedViewQuery(
+		adapter.SecureTableName(EnrichedViewName(tableName)),
+		adapter.SecureTableName(tableName),
+		adapter.SecureTableName(types.SQLBlockTableName),
+		adapter.SecureTableName(types.SQLTxTableName))
+}
+
+// DropViewQuery returns a query to drop a view if it exists
+func (adapter *MySQLAdapter) DropViewQuery(viewName string) string {
+	return fmt.Sprintf(`DROP VIEW IF EXISTS %s;`, adapter.SecureTableName(viewName))
+}
+
+// SelectAsOfQuery returns a query for selecting the rows of a table at a given height from its history view
+func (adapter *MySQLAdapter) SelectAsOfQuery(table types.SQLTable) string {
+	return selectAsOfQuery(adapter.SecureTableName(HistoryViewName(table.Name)), table, adapter.SecureColumnName, questionParam)
+}
+
+// BulkUpsertQuery returns a multi-row VALUES query for upserting rows
+func (adapter *MySQLAdapter) BulkUpsertQuery(table types.SQLTable, rows []types.EventDataRow) (types.UpsertDeleteQuery, error) {
+	if len(rows) == 0 {
+		return types.UpsertDeleteQuery{}, fmt.Errorf("error no rows to upsert")
+	}
+
+	columns, err := bulkColumns(table, rows[0])
+	if err != nil {
+		return types.UpsertDeleteQuery{}, err
+	}
+
+	pkColumn := ""
+	updValues := ""
+	for _, column := range columns {
+		secureColumn := adapter.SecureColumnName(column.Name)
+		if column.Primary {
+			if pkColumn == "" {
+				pkColumn = secureColumn
+			}
+		} else {
+			if updValues != "" {
+				updValues += ", "
+			}
+			updValues += fmt.Sprintf("%s = VALUES(%s)", secureColumn, secureColumn)
+		}
+	}
+
+	queryVal := bulkInsertQuery(adapter.SecureTableName(table.Name), columns, rows, adapter.SecureColumnName, questionParam)
+
+	if updValues != "" {
+		queryVal.Query += fmt.Sprintf(" ON DUPLICATE KEY UPDATE %s", updValues)
+	} else if pkColumn != "" {
+		// nothing to update, keep the existing rows
+		queryVal.Query += fmt.Sprintf(" ON DUPLICATE KEY UPDATE %s = %s", pkColumn, pkColumn)
+	}
+	queryVal.Query += ";"
+
+	return queryVal, nil
+}
+
+// BulkDeleteQuery returns a query for deleting multiple rows
+func (adapter *MySQLAdapter) BulkDeleteQuery(table types.SQLTable, rows []types.EventDataRow) (types.UpsertDeleteQuery, error) {
+	return bulkDeleteQuery(adapter.SecureTableName(table.Name), table, rows, adapter.SecureColumnName, questionParam)
+}
+
+// BulkInsertLogQuery returns a query to insert multiple rows in log table
+func (adapter *MySQLAdapter) BulkInsertLogQuery(rows int) string {
+	return bulkInsertLogQuery(adapter.SecureTableName(types.SQLLogTableName), rows, questionParam)
+}
+
+// MaxQueryParams returns the maximum number of placeholders in a MySQL prepared statement
+func (adapter *MySQLAdapter) MaxQueryParams() int {
+	return 65535
+}
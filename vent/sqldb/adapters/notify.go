@@ -0,0 +1,9 @@
+package adapters
+
+// NotifyAdapter is implemented by db adapters able to notify listeners when a transaction commits,
+// notifications are skipped for adapters not implementing it
+type NotifyAdapter interface {
+	// NotifyQuery builds a query sending a payload ($2) to a notification channel ($1),
+	// listeners receive it once the transaction commits
+	NotifyQuery() string
+}
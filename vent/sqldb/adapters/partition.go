@@ -0,0 +1,28 @@
+package adapters
+
+import (
+	"fmt"
+
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// PartitionAdapter is implemented by db adapters able to partition tables by height ranges,
+// tables are not partitioned by adapters not implementing it
+type PartitionAdapter interface {
+	// CreatePartitionedTableQuery builds a CREATE TABLE query (& its dictionary query) for a table
+	// partitioned by height ranges, rows can't be written until partitions holding their heights are created
+	CreatePartitionedTableQuery(tableName string, columns []types.SQLTableColumn) (string, string)
+	// CreatePartitionQuery builds a query creating (if not exists) the partition of a table
+	// holding heights from the given lower bound (included) up to the upper bound (excluded)
+	CreatePartitionQuery(tableName string, from, to uint64) string
+	// SelectPartitionsQuery builds a query returning the name & lower height bound of the partitions of a table,
+	// along with its parameters
+	SelectPartitionsQuery(tableName string) types.UpsertDeleteQuery
+	// PartitionName returns the name of the partition of a table starting at a given height
+	PartitionName(tableName string, from uint64) string
+}
+
+// partitionName returns the name of the partition of a table starting at a given height
+func partitionName(tableName string, from uint64) string {
+	return fmt.Sprintf("%s_h%d", tableName, from)
+}
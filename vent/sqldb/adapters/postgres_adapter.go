@@ -3,6 +3,8 @@ package adapters
 import (
 	"database/sql"
 	"fmt"
+	"hash/fnv"
+	"strings"
 
 	"github.com/lib/pq"
 	"github.com/monax/bosmarmot/vent/logger"
@@ -17,9 +19,31 @@ var pgDataTypes = map[types.SQLColumnType]string{
 	types.SQLColumnTypeText:      "TEXT",
 	types.SQLColumnTypeVarchar:   "VARCHAR",
 	types.SQLColumnTypeTimeStamp: "TIMESTAMP",
-	types.SQLColumnTypeNumeric:   "NUMERIC",
+	types.SQLColumnTypeNumeric:   "NUMERIC(78,0)",
 	types.SQLColumnTypeJSON:      "JSON",
 	types.SQLColumnTypeBigInt:    "BIGINT",
+	types.SQLColumnTypeJSONB:     "JSONB",
+}
+
+// pgCatalogTypes are the data types reported by information_schema for each generic column type
+var pgCatalogTypes = map[types.SQLColumnType]string{
+	types.SQLColumnTypeBool:      "boolean",
+	types.SQLColumnTypeByteA:     "bytea",
+	types.SQLColumnTypeInt:       "integer",
+	types.SQLColumnTypeSerial:    "integer",
+	types.SQLColumnTypeText:      "text",
+	types.SQLColumnTypeVarchar:   "character varying",
+	types.SQLColumnTypeTimeStamp: "timestamp without time zone",
+	types.SQLColumnTypeNumeric:   "numeric",
+	types.SQLColumnTypeJSON:      "json",
+	types.SQLColumnTypeBigInt:    "bigint",
+	types.SQLColumnTypeJSONB:     "jsonb",
+}
+
+func init() {
+	Register(types.PostgresDB, func(schema string, log *logger.Logger) DBAdapter {
+		return NewPostgresAdapter(schema, log)
+	})
 }
 
 // PostgresAdapter implements DBAdapter for Postgres
@@ -46,6 +70,10 @@ func (adapter *PostgresAdapter) Open(dbURL string) (*sql.DB, error) {
 
 	// if there is a supplied Schema
 	if adapter.Schema != "" {
+		if err = adapter.validateSchemaName(); err != nil {
+			return nil, err
+		}
+
 		if err = db.Ping(); err != nil {
 			adapter.Log.Info("msg", "Error opening database connection", "err", err)
 			return nil, err
@@ -53,16 +81,16 @@ func (adapter *PostgresAdapter) Open(dbURL string) (*sql.DB, error) {
 
 		var found bool
 
-		query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM pg_catalog.pg_namespace n WHERE n.nspname = '%s');`, adapter.Schema)
-		adapter.Log.Info("msg", "FIND SCHEMA", "query", query)
+		query := `SELECT EXISTS (SELECT 1 FROM pg_catalog.pg_namespace n WHERE n.nspname = $1);`
+		adapter.Log.Info("msg", "FIND SCHEMA", "query", query, "value", adapter.Schema)
 
-		if err := db.QueryRow(query).Scan(&found); err == nil {
+		if err := db.QueryRow(query, adapter.Schema).Scan(&found); err == nil {
 			if !found {
 				adapter.Log.Warn("msg", "Schema not found")
 			}
 			adapter.Log.Info("msg", "Creating schema")
 
-			query = fmt.Sprintf("CREATE SCHEMA %s;", adapter.Schema)
+			query = fmt.Sprintf("CREATE SCHEMA %s;", adapter.SecureColumnName(adapter.Schema))
 			adapter.Log.Info("msg", "CREATE SCHEMA", "query", query)
 
 			if _, err = db.Exec(query); err != nil {
@@ -91,9 +119,39 @@ func (adapter *PostgresAdapter) TypeMapping(sqlColumnType types.SQLColumnType) (
 	return "", fmt.Errorf("datatype %v not recognized", sqlColumnType)
 }
 
+// validateSchemaName returns an error if the schema name breaks PostgreSQL naming rules
+func (adapter *PostgresAdapter) validateSchemaName() error {
+	if err := pgIdentifierRules.validateIdentifier("schema", adapter.Schema); err != nil {
+		return err
+	}
+
+	// pg_ schemas are reserved for the system catalogs
+	if strings.HasPrefix(strings.ToLower(adapter.Schema), "pg_") {
+		return fmt.Errorf("error schema name %s uses reserved prefix pg_", adapter.Schema)
+	}
+
+	return nil
+}
+
 // SecureColumnName return columns between appropriate security containers
 func (adapter *PostgresAdapter) SecureColumnName(columnName string) string {
-	return `"` + columnName + `"`
+	return pgIdentifierRules.quoteIdentifier(columnName)
+}
+
+// SecureTableName returns a table name qualified by the schema between appropriate security containers
+func (adapter *PostgresAdapter) SecureTableName(tableName string) string {
+	return pgIdentifierRules.quoteIdentifier(adapter.Schema) + "." + pgIdentifierRules.quoteIdentifier(tableName)
+}
+
+// ValidateTableName returns an error if a table name breaks PostgreSQL naming rules,
+// (longer names would be silently truncated)
+func (adapter *PostgresAdapter) ValidateTableName(tableName string) error {
+	return pgIdentifierRules.validateTableName(tableName, primaryKeyName(tableName))
+}
+
+// ValidateColumnName returns an error if a column name breaks PostgreSQL naming rules
+func (adapter *PostgresAdapter) ValidateColumnName(columnName string) error {
+	return pgIdentifierRules.validateColumnName(columnName)
 }
 
 // CreateTableQuery builds query for creating a new table
@@ -137,14 +195,14 @@ func (adapter *PostgresAdapter) CreateTableQuery(tableName string, columns []typ
 			i)
 	}
 
-	query := fmt.Sprintf("CREATE TABLE %s.%s (%s", adapter.Schema, tableName, columnsDef)
+	query := fmt.Sprintf("CREATE TABLE %s (%s", adapter.SecureTableName(tableName), columnsDef)
 	if primaryKey != "" {
-		query += "," + fmt.Sprintf("CONSTRAINT %s_pkey PRIMARY KEY (%s)", tableName, primaryKey)
+		query += "," + fmt.Sprintf("CONSTRAINT %s PRIMARY KEY (%s)", adapter.SecureColumnName(primaryKeyName(tableName)), primaryKey)
 	}
 	query += ");"
 
-	dictionaryQuery := fmt.Sprintf("INSERT INTO %s.%s (%s,%s,%s,%s,%s,%s) VALUES %s;",
-		adapter.Schema, types.SQLDictionaryTableName,
+	dictionaryQuery := fmt.Sprintf("INSERT INTO %s (%s,%s,%s,%s,%s,%s) VALUES %s;",
+		adapter.SecureTableName(types.SQLDictionaryTableName),
 		types.SQLColumnLabelTableName, types.SQLColumnLabelColumnName,
 		types.SQLColumnLabelColumnType, types.SQLColumnLabelColumnLength,
 		types.SQLColumnLabelPrimaryKey, types.SQLColumnLabelColumnOrder,
@@ -157,29 +215,30 @@ func (adapter *PostgresAdapter) CreateTableQuery(tableName string, columns []typ
 func (adapter *PostgresAdapter) LastBlockIDQuery() string {
 	query := `
 		WITH ll AS (
-			SELECT MAX(%s) AS %s FROM %s.%s
+			SELECT MAX(%s) AS %s FROM %s WHERE %s IS NOT NULL
 		)
 		SELECT COALESCE(%s, '0') AS %s
-			FROM ll LEFT OUTER JOIN %s.%s log ON (ll.%s = log.%s);`
+			FROM ll LEFT OUTER JOIN %s log ON (ll.%s = log.%s);`
 
 	return fmt.Sprintf(query,
-		types.SQLColumnLabelId,                // max
-		types.SQLColumnLabelId,                // as
-		adapter.Schema, types.SQLLogTableName, // from
-		types.SQLColumnLabelHeight,            // coalesce
-		types.SQLColumnLabelHeight,            // as
-		adapter.Schema, types.SQLLogTableName, // from
+		types.SQLColumnLabelId,                         // max
+		types.SQLColumnLabelId,                         // as
+		adapter.SecureTableName(types.SQLLogTableName), // from
+		types.SQLColumnLabelHeight,                     // where
+		types.SQLColumnLabelHeight,                     // coalesce
+		types.SQLColumnLabelHeight,                     // as
+		adapter.SecureTableName(types.SQLLogTableName), // from
 		types.SQLColumnLabelId, types.SQLColumnLabelId) // on
 
 }
 
 // FindTableQuery returns a query that checks if a table exists
 func (adapter *PostgresAdapter) FindTableQuery() string {
-	query := "SELECT COUNT(*) found FROM %s.%s WHERE %s = $1;"
+	query := "SELECT COUNT(*) found FROM %s WHERE %s = $1;"
 
 	return fmt.Sprintf(query,
-		adapter.Schema, types.SQLDictionaryTableName, // from
-		types.SQLColumnLabelTableName) // where
+		adapter.SecureTableName(types.SQLDictionaryTableName), // from
+		types.SQLColumnLabelTableName)                         // where
 }
 
 // TableDefinitionQuery returns a query with table structure
@@ -188,7 +247,7 @@ func (adapter *PostgresAdapter) TableDefinitionQuery() string {
 		SELECT
 			%s,%s,%s,%s
 		FROM
-			%s.%s
+			%s
 		WHERE
 			%s = $1
 		ORDER BY
@@ -197,12 +256,36 @@ func (adapter *PostgresAdapter) TableDefinitionQuery() string {
 	return fmt.Sprintf(query,
 		types.SQLColumnLabelColumnName, types.SQLColumnLabelColumnType, // select
 		types.SQLColumnLabelColumnLength, types.SQLColumnLabelPrimaryKey, // select
-		adapter.Schema, types.SQLDictionaryTableName, // from
-		types.SQLColumnLabelTableName,   // where
-		types.SQLColumnLabelColumnOrder) // order by
+		adapter.SecureTableName(types.SQLDictionaryTableName), // from
+		types.SQLColumnLabelTableName,                         // where
+		types.SQLColumnLabelColumnOrder)                       // order by
 
 }
 
+// TableColumnsQuery returns a query with the actual table structure from information_schema
+func (adapter *PostgresAdapter) TableColumnsQuery(tableName string) types.UpsertDeleteQuery {
+	query := `
+		SELECT
+			column_name, data_type, character_maximum_length
+		FROM
+			information_schema.columns
+		WHERE
+			table_schema = $1 AND table_name = $2 AND data_type <> 'tsvector'
+		ORDER BY
+			ordinal_position;`
+
+	return types.UpsertDeleteQuery{
+		Query:    query,
+		Values:   fmt.Sprintf("%s, %s", adapter.Schema, tableName),
+		Pointers: []interface{}{adapter.Schema, tableName},
+	}
+}
+
+// CatalogColumnType returns the information_schema data type of a generic column type
+func (adapter *PostgresAdapter) CatalogColumnType(sqlColumnType types.SQLColumnType) string {
+	return pgCatalogTypes[sqlColumnType]
+}
+
 // AlterColumnQuery returns a query for adding a new column to a table
 func (adapter *PostgresAdapter) AlterColumnQuery(tableName, columnName string, sqlColumnType types.SQLColumnType, length, order int) (string, string) {
 	sqlType, _ := adapter.TypeMapping(sqlColumnType)
@@ -210,17 +293,16 @@ func (adapter *PostgresAdapter) AlterColumnQuery(tableName, columnName string, s
 		sqlType = fmt.Sprintf("%s(%d)", sqlType, length)
 	}
 
-	query := fmt.Sprintf("ALTER TABLE %s.%s ADD COLUMN %s %s;",
-		adapter.Schema,
-		tableName,
+	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;",
+		adapter.SecureTableName(tableName),
 		adapter.SecureColumnName(columnName),
 		sqlType)
 
 	dictionaryQuery := fmt.Sprintf(`
-		INSERT INTO %s.%s (%s,%s,%s,%s,%s,%s)
+		INSERT INTO %s (%s,%s,%s,%s,%s,%s)
 		VALUES ('%s','%s',%d,%d,%d,%d);`,
 
-		adapter.Schema, types.SQLDictionaryTableName,
+		adapter.SecureTableName(types.SQLDictionaryTableName),
 
 		types.SQLColumnLabelTableName, types.SQLColumnLabelColumnName,
 		types.SQLColumnLabelColumnType, types.SQLColumnLabelColumnLength,
@@ -231,34 +313,104 @@ func (adapter *PostgresAdapter) AlterColumnQuery(tableName, columnName string, s
 	return query, dictionaryQuery
 }
 
+// AlterColumnTypeQuery returns a query for changing the type of an existing column
+func (adapter *PostgresAdapter) AlterColumnTypeQuery(tableName string, columns []types.SQLTableColumn, column types.SQLTableColumn) (string, string) {
+	sqlType, _ := adapter.TypeMapping(column.Type)
+	if column.Length > 0 {
+		sqlType = fmt.Sprintf("%s(%d)", sqlType, column.Length)
+	}
+
+	secureColumn := adapter.SecureColumnName(column.Name)
+
+	query := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s;",
+		adapter.SecureTableName(tableName),
+		secureColumn,
+		sqlType,
+		secureColumn,
+		sqlType)
+
+	dictionaryQuery := fmt.Sprintf(`
+		UPDATE %s SET %s = %d, %s = %d
+		WHERE %s = '%s' AND %s = '%s';`,
+
+		adapter.SecureTableName(types.SQLDictionaryTableName),
+
+		types.SQLColumnLabelColumnType, column.Type,
+		types.SQLColumnLabelColumnLength, column.Length,
+
+		types.SQLColumnLabelTableName, tableName,
+		types.SQLColumnLabelColumnName, column.Name)
+
+	return query, dictionaryQuery
+}
+
+// RenameColumnQuery returns a query for renaming an existing column
+func (adapter *PostgresAdapter) RenameColumnQuery(tableName, columnName, newColumnName string) (string, string) {
+	query := fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;",
+		adapter.SecureTableName(tableName),
+		adapter.SecureColumnName(columnName),
+		adapter.SecureColumnName(newColumnName))
+
+	dictionaryQuery := fmt.Sprintf(`
+		UPDATE %s SET %s = '%s'
+		WHERE %s = '%s' AND %s = '%s';`,
+
+		adapter.SecureTableName(types.SQLDictionaryTableName),
+
+		types.SQLColumnLabelColumnName, newColumnName,
+
+		types.SQLColumnLabelTableName, tableName,
+		types.SQLColumnLabelColumnName, columnName)
+
+	return query, dictionaryQuery
+}
+
+// DropColumnQuery returns a query for removing an existing column
+func (adapter *PostgresAdapter) DropColumnQuery(tableName string, columns []types.SQLTableColumn, columnName string) (string, string) {
+	query := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;",
+		adapter.SecureTableName(tableName),
+		adapter.SecureColumnName(columnName))
+
+	dictionaryQuery := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE %s = '%s' AND %s = '%s';`,
+
+		adapter.SecureTableName(types.SQLDictionaryTableName),
+
+		types.SQLColumnLabelTableName, tableName,
+		types.SQLColumnLabelColumnName, columnName)
+
+	return query, dictionaryQuery
+}
+
 // SelectRowQuery returns a query for selecting row values
 func (adapter *PostgresAdapter) SelectRowQuery(tableName, fields, indexValue string) string {
-	return fmt.Sprintf("SELECT %s FROM %s.%s WHERE %s = '%s';", fields, adapter.Schema, tableName, types.SQLColumnLabelHeight, indexValue)
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s = '%s';", fields, adapter.SecureTableName(tableName), types.SQLColumnLabelHeight, indexValue)
 }
 
 // SelectLogQuery returns a query for selecting all tables involved in a block trn
 func (adapter *PostgresAdapter) SelectLogQuery() string {
 	query := `
-		SELECT DISTINCT %s,%s FROM %s.%s l WHERE %s = $1;`
+		SELECT DISTINCT %s,%s FROM %s l WHERE %s = $1;`
 
 	return fmt.Sprintf(query,
 		types.SQLColumnLabelTableName, types.SQLColumnLabelEventName, // select
-		adapter.Schema, types.SQLLogTableName, // from
-		types.SQLColumnLabelHeight) // where
+		adapter.SecureTableName(types.SQLLogTableName), // from
+		types.SQLColumnLabelHeight)                     // where
 }
 
 // InsertLogQuery returns a query to insert a row in log table
 func (adapter *PostgresAdapter) InsertLogQuery() string {
 	query := `
-		INSERT INTO %s.%s (%s,%s,%s,%s,%s,%s,%s,%s,%s,%s)
-		VALUES (CURRENT_TIMESTAMP, $1, $2, $3, $4, $5, $6 ,$7, $8, $9);`
+		INSERT INTO %s (%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s)
+		VALUES (CURRENT_TIMESTAMP, $1, $2, $3, $4, $5, $6 ,$7, $8, $9, $10);`
 
 	return fmt.Sprintf(query,
-		adapter.Schema, types.SQLLogTableName, // insert
+		adapter.SecureTableName(types.SQLLogTableName), // insert
 		//fields
 		types.SQLColumnLabelTimeStamp, types.SQLColumnLabelTableName, types.SQLColumnLabelEventName, types.SQLColumnLabelEventFilter,
 		types.SQLColumnLabelHeight, types.SQLColumnLabelTxHash, types.SQLColumnLabelAction, types.SQLColumnLabelDataRow,
-		types.SQLColumnLabelSqlStmt, types.SQLColumnLabelSqlValues)
+		types.SQLColumnLabelSqlStmt, types.SQLColumnLabelSqlValues, types.SQLColumnLabelBeforeImage)
 }
 
 // ErrorEquals verify if an error is of a given SQL type
@@ -279,6 +431,8 @@ func (adapter *PostgresAdapter) ErrorEquals(err error, sqlErrorType types.SQLErr
 			return err.Code == "42703"
 		case types.SQLErrorTypeInvalidType:
 			return err.Code == "42704"
+		case types.SQLErrorTypeUnsupported:
+			return err.Code == "0A000"
 		}
 	}
 
@@ -298,7 +452,7 @@ func (adapter *PostgresAdapter) UpsertQuery(table types.SQLTable, row types.Even
 	i := 0
 
 	// for each column in table
-	for _, tableColumn := range table.Columns {
+	for _, tableColumn := range sortedColumns(table) {
 		secureColumn := adapter.SecureColumnName(tableColumn.Name)
 
 		i++
@@ -344,12 +498,15 @@ func (adapter *PostgresAdapter) UpsertQuery(table types.SQLTable, row types.Even
 		}
 	}
 
-	query := fmt.Sprintf("INSERT INTO %s.%s (%s) VALUES (%s) ", adapter.Schema, table.Name, columns, insValues)
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ", adapter.SecureTableName(table.Name), columns, insValues)
 
-	if updValues != "" {
-		query += fmt.Sprintf("ON CONFLICT ON CONSTRAINT %s_pkey DO UPDATE SET %s", table.Name, updValues)
-	} else {
-		query += fmt.Sprintf("ON CONFLICT ON CONSTRAINT %s_pkey DO NOTHING", table.Name)
+	// tables without primary key have no constraint to conflict on
+	if len(primaryKeyColumns(table)) > 0 {
+		if updValues != "" {
+			query += fmt.Sprintf("ON CONFLICT ON CONSTRAINT %s DO UPDATE SET %s", adapter.SecureColumnName(primaryKeyName(table.Name)), updValues)
+		} else {
+			query += fmt.Sprintf("ON CONFLICT ON CONSTRAINT %s DO NOTHING", adapter.SecureColumnName(primaryKeyName(table.Name)))
+		}
 	}
 	query += ";"
 
@@ -364,7 +521,7 @@ func (adapter *PostgresAdapter) DeleteQuery(table types.SQLTable, row types.Even
 	i := 0
 
 	// for each column in table
-	for _, tableColumn := range table.Columns {
+	for _, tableColumn := range sortedColumns(table) {
 
 		//only PK for delete
 		if tableColumn.Primary {
@@ -398,18 +555,28 @@ func (adapter *PostgresAdapter) DeleteQuery(table types.SQLTable, row types.Even
 		return types.UpsertDeleteQuery{}, fmt.Errorf("error primary key not found for deletion")
 	}
 
-	query := fmt.Sprintf("DELETE FROM %s.%s WHERE %s;", adapter.Schema, table.Name, columns)
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s;", adapter.SecureTableName(table.Name), columns)
 
 	return types.UpsertDeleteQuery{Query: query, Values: values, Pointers: pointers}, nil
 }
 
+// RestoreDBQuery returns a query for selecting upserts & deletes logged up to a given time
 func (adapter *PostgresAdapter) RestoreDBQuery() string {
-	return fmt.Sprintf(`SELECT %s, %s, %s, %s FROM %s.%s 
-								WHERE to_char(%s,'YYYY-MM-DD HH24:MI:SS')<=$1 
-								ORDER BY %s;`,
-		types.SQLColumnLabelTableName, types.SQLColumnLabelAction, types.SQLColumnLabelSqlStmt, types.SQLColumnLabelSqlValues,
-		adapter.Schema, types.SQLLogTableName,
+	return fmt.Sprintf(`SELECT %s, %s, %s FROM %s WHERE %s <= $1 AND %s IN ('%s', '%s') ORDER BY %s;`,
+		types.SQLColumnLabelTableName, types.SQLColumnLabelAction, types.SQLColumnLabelDataRow,
+		adapter.SecureTableName(types.SQLLogTableName),
 		types.SQLColumnLabelTimeStamp,
+		types.SQLColumnLabelAction, types.ActionUpsert, types.ActionDelete,
+		types.SQLColumnLabelId)
+}
+
+// RestoreHeightQuery returns a query for selecting upserts & deletes logged up to a given height
+func (adapter *PostgresAdapter) RestoreHeightQuery() string {
+	return fmt.Sprintf(`SELECT %s, %s, %s FROM %s WHERE %s <= $1 AND %s IN ('%s', '%s') ORDER BY %s;`,
+		types.SQLColumnLabelTableName, types.SQLColumnLabelAction, types.SQLColumnLabelDataRow,
+		adapter.SecureTableName(types.SQLLogTableName),
+		types.SQLColumnLabelHeight,
+		types.SQLColumnLabelAction, types.ActionUpsert, types.ActionDelete,
 		types.SQLColumnLabelId)
 }
 
@@ -421,42 +588,47 @@ func (adapter *PostgresAdapter) CleanDBQueries() types.SQLCleanDBQuery {
 		COUNT(*) REGISTERS,
 		COALESCE(MAX(%s),'') CHAINID,
 		COALESCE(MAX(%s),'') BVERSION 
-		FROM %s.%s;`,
+		FROM %s;`,
 		types.SQLColumnLabelChainID, types.SQLColumnLabelBurrowVer,
-		adapter.Schema, types.SQLChainInfoTableName)
+		adapter.SecureTableName(types.SQLChainInfoTableName))
 
 	deleteChainIDQry := fmt.Sprintf(`
-		DELETE FROM %s.%s;`,
-		adapter.Schema, types.SQLChainInfoTableName)
+		DELETE FROM %s;`,
+		adapter.SecureTableName(types.SQLChainInfoTableName))
 
 	insertChainIDQry := fmt.Sprintf(`
-		INSERT INTO %s.%s (%s,%s) VALUES($1,$2)`,
-		adapter.Schema, types.SQLChainInfoTableName,
+		INSERT INTO %s (%s,%s) VALUES($1,$2)`,
+		adapter.SecureTableName(types.SQLChainInfoTableName),
 		types.SQLColumnLabelChainID, types.SQLColumnLabelBurrowVer)
 
 	// Dictionary
 	selectDictionaryQry := fmt.Sprintf(`
 		SELECT DISTINCT %s 
-		FROM %s.%s 
+		FROM %s 
  		WHERE %s
-		NOT IN ('%s','%s','%s');`,
+		NOT IN ('%s','%s','%s','%s');`,
 		types.SQLColumnLabelTableName,
-		adapter.Schema, types.SQLDictionaryTableName,
+		adapter.SecureTableName(types.SQLDictionaryTableName),
 		types.SQLColumnLabelTableName,
-		types.SQLLogTableName, types.SQLDictionaryTableName, types.SQLChainInfoTableName)
+		types.SQLLogTableName, types.SQLDictionaryTableName, types.SQLChainInfoTableName, types.SQLAbiTableName)
 
 	deleteDictionaryQry := fmt.Sprintf(`
-		DELETE FROM %s.%s 
+		DELETE FROM %s 
 		WHERE %s 
-		NOT IN ('%s','%s','%s');`,
-		adapter.Schema, types.SQLDictionaryTableName,
+		NOT IN ('%s','%s','%s','%s');`,
+		adapter.SecureTableName(types.SQLDictionaryTableName),
 		types.SQLColumnLabelTableName,
-		types.SQLLogTableName, types.SQLDictionaryTableName, types.SQLChainInfoTableName)
+		types.SQLLogTableName, types.SQLDictionaryTableName, types.SQLChainInfoTableName, types.SQLAbiTableName)
 
 	// log
 	deleteLogQry := fmt.Sprintf(`
-		DELETE FROM %s.%s;`,
-		adapter.Schema, types.SQLLogTableName)
+		DELETE FROM %s;`,
+		adapter.SecureTableName(types.SQLLogTableName))
+
+	// abi registry
+	deleteAbiQry := fmt.Sprintf(`
+		DELETE FROM %s;`,
+		adapter.SecureTableName(types.SQLAbiTableName))
 
 	return types.SQLCleanDBQuery{
 		SelectChainIDQry:    selectChainIDQry,
@@ -465,10 +637,362 @@ func (adapter *PostgresAdapter) CleanDBQueries() types.SQLCleanDBQuery {
 		SelectDictionaryQry: selectDictionaryQry,
 		DeleteDictionaryQry: deleteDictionaryQry,
 		DeleteLogQry:        deleteLogQry,
+		DeleteAbiQry:        deleteAbiQry,
 	}
 }
 
+// SelectAbiQuery returns a query for selecting all abi specifications stored by contract address
+func (adapter *PostgresAdapter) SelectAbiQuery() string {
+	return fmt.Sprintf("SELECT %s, %s FROM %s;",
+		types.SQLColumnLabelAddress, types.SQLColumnLabelAbi,
+		adapter.SecureTableName(types.SQLAbiTableName))
+}
+
 func (adapter *PostgresAdapter) DropTableQuery(tableName string) string {
 	//drop tables
-	return fmt.Sprintf(`DROP TABLE %s.%s;`, adapter.Schema, tableName)
+	return fmt.Sprintf(`DROP TABLE %s;`, adapter.SecureTableName(tableName))
+}
+
+// SelectByKeyQuery returns a query for selecting the current contents of rows by PK
+func (adapter *PostgresAdapter) SelectByKeyQuery(table types.SQLTable, rows []types.EventDataRow) (types.UpsertDeleteQuery, error) {
+	return selectByKeyQuery(adapter.SecureTableName(table.Name), table, rows, adapter.SecureColumnName, dollarParam)
+}
+
+// SelectLogAfterHeightQuery returns a query for selecting upserts & deletes logged after a given height
+func (adapter *PostgresAdapter) SelectLogAfterHeightQuery() string {
+	return fmt.Sprintf(`SELECT %s, %s, %s, %s, %s FROM %s WHERE %s > $1 AND %s IN ('%s', '%s') ORDER BY %s;`,
+		types.SQLColumnLabelId, types.SQLColumnLabelTableName, types.SQLColumnLabelAction, types.SQLColumnLabelDataRow, types.SQLColumnLabelBeforeImage,
+		adapter.SecureTableName(types.SQLLogTableName),
+		types.SQLColumnLabelHeight,
+		types.SQLColumnLabelAction, types.ActionUpsert, types.ActionDelete,
+		types.SQLColumnLabelId)
+}
+
+// DeleteLogAfterHeightQuery returns a query for deleting rows logged after a given height
+func (adapter *PostgresAdapter) DeleteLogAfterHeightQuery() string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE %s > $1;`, adapter.SecureTableName(types.SQLLogTableName), types.SQLColumnLabelHeight)
+}
+
+// SelectLogUpToHeightQuery returns a query for selecting upserts & deletes logged up to a given height
+func (adapter *PostgresAdapter) SelectLogUpToHeightQuery() string {
+	return selectLogUpToHeightQuery(adapter.SecureTableName(types.SQLLogTableName), "$1")
+}
+
+// CheckpointLogQuery returns a query to keep a checkpoint for each block logged up to a given height
+func (adapter *PostgresAdapter) CheckpointLogQuery() string {
+	return checkpointLogQuery(adapter.SecureTableName(types.SQLLogTableName), "$1")
+}
+
+// DeleteLogUpToHeightQuery returns a query for deleting upserts & deletes logged up to a given height
+func (adapter *PostgresAdapter) DeleteLogUpToHeightQuery() string {
+	return deleteLogUpToHeightQuery(adapter.SecureTableName(types.SQLLogTableName), "$1")
+}
+
+// CompactedHeightQuery returns a query for selecting the highest compacted height
+func (adapter *PostgresAdapter) CompactedHeightQuery() string {
+	return compactedHeightQuery(adapter.SecureTableName(types.SQLLogTableName))
+}
+
+// RenameTableQuery returns a query to rename a table, primary key constraints are renamed as well
+// because upserts refer to them by name
+func (adapter *PostgresAdapter) RenameTableQuery(tableName, newTableName string) string {
+	return fmt.Sprintf(`ALTER TABLE %s RENAME TO %s; ALTER INDEX IF EXISTS %s RENAME TO %s;`,
+		adapter.SecureTableName(tableName), adapter.SecureColumnName(newTableName),
+		adapter.SecureTableName(primaryKeyName(tableName)), adapter.SecureColumnName(primaryKeyName(newTableName)))
+}
+
+// CreateIndexQuery returns a query to create a GIN index on a JSONB column
+func (adapter *PostgresAdapter) CreateIndexQuery(tableName, columnName, method string) (string, error) {
+	if method != types.SQLIndexGIN {
+		return "", fmt.Errorf("index method %s not supported", method)
+	}
+
+	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING GIN (%s);",
+		adapter.indexName(tableName, columnName),
+		adapter.SecureTableName(tableName),
+		adapter.SecureColumnName(columnName)), nil
+}
+
+// RenameIndexQuery returns a query to rename the index of a column after renaming its table,
+// so that the index can be created again for a new table with the previous name
+func (adapter *PostgresAdapter) RenameIndexQuery(tableName, newTableName, columnName string) string {
+	return fmt.Sprintf("ALTER INDEX IF EXISTS %s.%s RENAME TO %s;",
+		pgIdentifierRules.quoteIdentifier(adapter.Schema),
+		adapter.indexName(tableName, columnName),
+		adapter.indexName(newTableName, columnName))
+}
+
+// indexName returns the quoted name of the index of a column, shortened to the identifier maximum length
+func (adapter *PostgresAdapter) indexName(tableName, columnName string) string {
+	return pgIdentifierRules.quoteIdentifier(pgIdentifierRules.shortenIdentifier(IndexName(tableName, columnName)))
+}
+
+// CreatePartitionedTableQuery builds query for creating a new table partitioned by height ranges
+func (adapter *PostgresAdapter) CreatePartitionedTableQuery(tableName string, columns []types.SQLTableColumn) (string, string) {
+	query, dictionary := adapter.CreateTableQuery(tableName, columns)
+
+	return fmt.Sprintf("%s PARTITION BY RANGE (%s);",
+		strings.TrimSuffix(query, ";"),
+		adapter.SecureColumnName(types.SQLColumnLabelHeight)), dictionary
+}
+
+// CreatePartitionQuery returns a query to create the partition of a table holding a height range
+func (adapter *PostgresAdapter) CreatePartitionQuery(tableName string, from, to uint64) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM (%d) TO (%d);",
+		adapter.SecureTableName(adapter.PartitionName(tableName, from)),
+		adapter.SecureTableName(tableName),
+		from, to)
+}
+
+// SelectPartitionsQuery returns a query for selecting the partitions of a table from the catalog,
+// lower bounds are read from the partition bound expression
+func (adapter *PostgresAdapter) SelectPartitionsQuery(tableName string) types.UpsertDeleteQuery {
+	query := `
+		SELECT
+			c.relname, CAST(substring(pg_get_expr(c.relpartbound, c.oid) FROM 'FROM \(''?([0-9]+)') AS BIGINT)
+		FROM
+			pg_inherits i
+			JOIN pg_class c ON c.oid = i.inhrelid
+			JOIN pg_class p ON p.oid = i.inhparent
+			JOIN pg_namespace n ON n.oid = p.relnamespace
+		WHERE
+			n.nspname = $1 AND p.relname = $2
+		ORDER BY
+			2;`
+
+	return types.UpsertDeleteQuery{
+		Query:    query,
+		Values:   fmt.Sprintf("%s, %s", adapter.Schema, tableName),
+		Pointers: []interface{}{adapter.Schema, tableName},
+	}
+}
+
+// PartitionName returns the name of the partition of a table, shortened to the identifier maximum length
+func (adapter *PostgresAdapter) PartitionName(tableName string, from uint64) string {
+	return pgIdentifierRules.shortenIdentifier(partitionName(tableName, from))
+}
+
+// FullTextQuery returns a query adding a tsvector column, its GIN index & its trigger for each full-text column,
+// existing columns & indexes are kept, rows written before the column was added are filled in
+func (adapter *PostgresAdapter) FullTextQuery(tableName string, columnNames []string) string {
+	queries := make([]string, len(columnNames))
+	for i, columnName := range columnNames {
+		tsvColumn := pgIdentifierRules.shortenIdentifier(FullTextColumnName(columnName))
+
+		// generated columns need PostgreSQL v12, the built-in trigger (named after the column as
+		// trigger names are scoped by table) keeps the tsvector column up to date on older versions too
+		queries[i] = fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s TSVECTOR;
+			CREATE INDEX IF NOT EXISTS %s ON %s USING GIN (%s);
+			DROP TRIGGER IF EXISTS %s ON %s;
+			CREATE TRIGGER %s BEFORE INSERT OR UPDATE ON %s FOR EACH ROW EXECUTE PROCEDURE tsvector_update_trigger('%s', 'pg_catalog.simple', '%s');
+			UPDATE %s SET %s = to_tsvector('pg_catalog.simple', COALESCE(%s, '')) WHERE %s IS NULL;`,
+			adapter.SecureTableName(tableName), adapter.SecureColumnName(tsvColumn),
+			adapter.indexName(tableName, FullTextColumnName(columnName)), adapter.SecureTableName(tableName), adapter.SecureColumnName(tsvColumn),
+			adapter.SecureColumnName(tsvColumn), adapter.SecureTableName(tableName),
+			adapter.SecureColumnName(tsvColumn), adapter.SecureTableName(tableName), tsvColumn, columnName,
+			adapter.SecureTableName(tableName), adapter.SecureColumnName(tsvColumn), adapter.SecureColumnName(columnName), adapter.SecureColumnName(tsvColumn))
+	}
+
+	return strings.Join(queries, " ")
+}
+
+// SearchQuery returns a query for searching the words of a text in a full-text column ranked by relevance
+func (adapter *PostgresAdapter) SearchQuery(table types.SQLTable, columnName string) string {
+	tsvColumn := adapter.SecureColumnName(pgIdentifierRules.shortenIdentifier(FullTextColumnName(columnName)))
+
+	return fmt.Sprintf(`SELECT %s FROM %s t WHERE t.%s @@ plainto_tsquery('simple', $1)
+		ORDER BY ts_rank(t.%s, plainto_tsquery('simple', $1)) DESC LIMIT $2;`,
+		searchFields(table, "t", adapter.SecureColumnName),
+		adapter.SecureTableName(table.Name),
+		tsvColumn, tsvColumn)
+}
+
+// CreateHistoryViewQuery returns a query to create the history view of a table
+func (adapter *PostgresAdapter) CreateHistoryViewQuery(table types.SQLTable) string {
+	imageColumn := func(column types.SQLTableColumn) string {
+		value := fmt.Sprintf("l.%s->>'%s'", types.SQLColumnLabelBeforeImage, column.Name)
+
+		switch column.Type {
+		case types.SQLColumnTypeByteA:
+			return fmt.Sprintf("decode(%s, 'base64')", value)
+		case types.SQLColumnTypeSerial:
+			return fmt.Sprintf("CAST(%s AS INTEGER)", value)
+		default:
+			sqlType, _ := adapter.TypeMapping(column.Type)
+			return fmt.Sprintf("CAST(%s AS %s)", value, sqlType)
+		}
+	}
+
+	return historyViewQuery(
+		adapter.SecureTableName(HistoryViewName(table.Name)),
+		adapter.SecureTableName(table.Name),
+		adapter.SecureTableName(types.SQLLogTableName),
+		table, adapter.SecureColumnName, imageColumn,
+		"CAST(NULL AS BIGINT)",
+		fmt.Sprintf("CAST(l.%s AS TEXT) <> 'null'", types.SQLColumnLabelBeforeImage))
+}
+
+// CreateEnrichedViewQuery returns a query to create the view joining a table with block & tx data
+func (adapter *PostgresAdapter) CreateEnrichedViewQuery(tableName string) string {
+	return enrichedViewQuery(
+		adapter.SecureTableName(EnrichedViewName(tableName)),
+		adapter.SecureTableName(tableName),
+		adapter.SecureTableName(types.SQLBlockTableName),
+		adapter.SecureTableName(types.SQLTxTableName))
+}
+
+// DropViewQuery returns a query to drop a view if it exists
+func (adapter *PostgresAdapter) DropViewQuery(viewName string) string {
+	return fmt.Sprintf(`DROP VIEW IF EXISTS %s;`, adapter.SecureTableName(viewName))
+}
+
+// SelectAsOfQuery returns a query for selecting the rows of a table at a given height from its history view
+func (adapter *PostgresAdapter) SelectAsOfQuery(table types.SQLTable) string {
+	return selectAsOfQuery(adapter.SecureTableName(HistoryViewName(table.Name)), table, adapter.SecureColumnName, dollarParam)
+}
+
+// NotifyQuery returns a query for sending a notification, delivered on commit
+func (adapter *PostgresAdapter) NotifyQuery() string {
+	return "SELECT pg_notify($1, $2);"
+}
+
+// postgresCopyThreshold is the number of rows from which upserts are copied into a temporary table,
+// smaller sets of rows are upserted with multi-row VALUES queries
+const postgresCopyThreshold = 1000
+
+// BulkUpsertQuery returns a multi-row VALUES query for upserting rows
+func (adapter *PostgresAdapter) BulkUpsertQuery(table types.SQLTable, rows []types.EventDataRow) (types.UpsertDeleteQuery, error) {
+	if len(rows) == 0 {
+		return types.UpsertDeleteQuery{}, fmt.Errorf("error no rows to upsert")
+	}
+
+	columns, err := bulkColumns(table, rows[0])
+	if err != nil {
+		return types.UpsertDeleteQuery{}, err
+	}
+
+	queryVal := bulkInsertQuery(adapter.SecureTableName(table.Name), columns, rows, adapter.SecureColumnName, dollarParam)
+	queryVal.Query += adapter.conflictClause(table, columns) + ";"
+
+	return queryVal, nil
+}
+
+// CopyUpsert copies rows into a temporary table and merges them into the table with a single upsert
+func (adapter *PostgresAdapter) CopyUpsert(tx *sql.Tx, table types.SQLTable, rows []types.EventDataRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	columns, err := bulkColumns(table, rows[0])
+	if err != nil {
+		return err
+	}
+
+	// the temporary table lives as long as the connection and is emptied by each merge,
+	// its name changes with the table structure so that a stale copy is never reused
+	bulkTable := bulkTableName(table)
+	secureBulkTable := pq.QuoteIdentifier(bulkTable)
+
+	query := fmt.Sprintf("CREATE TEMP TABLE IF NOT EXISTS %s (LIKE %s INCLUDING DEFAULTS) ON COMMIT DELETE ROWS;",
+		secureBulkTable, adapter.SecureTableName(table.Name))
+
+	adapter.Log.Info("msg", "BULK TABLE", "query", query)
+	if _, err = tx.Exec(query); err != nil {
+		return err
+	}
+
+	// COPY rows into the temporary table
+	fields := ""
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+		if fields != "" {
+			fields += ", "
+		}
+		fields += adapter.SecureColumnName(column.Name)
+	}
+
+	stmt, err := tx.Prepare(pq.CopyIn(bulkTable, names...))
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if _, err = stmt.Exec(bulkValues(columns, row)...); err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+
+	if _, err = stmt.Exec(); err != nil {
+		stmt.Close()
+		return err
+	}
+
+	if err = stmt.Close(); err != nil {
+		return err
+	}
+
+	// merge copied rows, emptying the temporary table for the next rows of the transaction
+	query = fmt.Sprintf("WITH copied AS (DELETE FROM pg_temp.%s RETURNING %s) INSERT INTO %s (%s) SELECT %s FROM copied%s;",
+		secureBulkTable, fields, adapter.SecureTableName(table.Name), fields, fields, adapter.conflictClause(table, columns))
+
+	adapter.Log.Info("msg", "BULK UPSERT", "query", query, "value", len(rows))
+	_, err = tx.Exec(query)
+	return err
+}
+
+// CopyThreshold returns the number of rows from which upserts are copied
+func (adapter *PostgresAdapter) CopyThreshold() int {
+	return postgresCopyThreshold
+}
+
+// conflictClause returns the ON CONFLICT clause of an upsert of the given columns,
+// tables without primary key have no constraint to conflict on
+func (adapter *PostgresAdapter) conflictClause(table types.SQLTable, columns []types.SQLTableColumn) string {
+	if len(primaryKeyColumns(table)) == 0 {
+		return ""
+	}
+
+	updValues := ""
+	for _, column := range columns {
+		if !column.Primary {
+			secureColumn := adapter.SecureColumnName(column.Name)
+			if updValues != "" {
+				updValues += ", "
+			}
+			updValues += fmt.Sprintf("%s = EXCLUDED.%s", secureColumn, secureColumn)
+		}
+	}
+
+	if updValues == "" {
+		return fmt.Sprintf(" ON CONFLICT ON CONSTRAINT %s DO NOTHING", adapter.SecureColumnName(primaryKeyName(table.Name)))
+	}
+	return fmt.Sprintf(" ON CONFLICT ON CONSTRAINT %s DO UPDATE SET %s", adapter.SecureColumnName(primaryKeyName(table.Name)), updValues)
+}
+
+// bulkTableName returns the name of the temporary table rows of a table are copied into,
+// derived from the table name & column definitions
+func bulkTableName(table types.SQLTable) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(table.Name))
+	for _, column := range sortedColumns(table) {
+		fmt.Fprintf(hash, "\x00%s %d %d", column.Name, column.Type, column.Length)
+	}
+	return fmt.Sprintf("_vent_bulk_%08x", hash.Sum32())
+}
+
+// BulkDeleteQuery returns a query for deleting multiple rows
+func (adapter *PostgresAdapter) BulkDeleteQuery(table types.SQLTable, rows []types.EventDataRow) (types.UpsertDeleteQuery, error) {
+	return bulkDeleteQuery(adapter.SecureTableName(table.Name), table, rows, adapter.SecureColumnName, dollarParam)
+}
+
+// BulkInsertLogQuery returns a query to insert multiple rows in log table
+func (adapter *PostgresAdapter) BulkInsertLogQuery(rows int) string {
+	return bulkInsertLogQuery(adapter.SecureTableName(types.SQLLogTableName), rows, dollarParam)
+}
+
+// MaxQueryParams returns the maximum number of parameters of a PostgreSQL query
+func (adapter *PostgresAdapter) MaxQueryParams() int {
+	return 65535
 }
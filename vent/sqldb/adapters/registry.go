@@ -0,0 +1,113 @@
+package adapters
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/monax/bosmarmot/vent/logger"
+)
+
+// Factory builds a db adapter for a given schema
+type Factory func(schema string, log *logger.Logger) DBAdapter
+
+// ConnectionOptions stores adapter specific connection options
+type ConnectionOptions struct {
+	// URLParams are query parameters appended to the db url when opening connections
+	URLParams []string
+	// NoSchemas is set for adapters that do not support schemas (the db schema is ignored)
+	NoSchemas bool
+}
+
+// Option sets an adapter specific connection option
+type Option func(*ConnectionOptions)
+
+// WithURLParams appends query parameters (e.g. "_journal_mode=WAL") to the db url
+func WithURLParams(params ...string) Option {
+	return func(options *ConnectionOptions) {
+		options.URLParams = append(options.URLParams, params...)
+	}
+}
+
+// WithoutSchemas marks adapters that do not support schemas
+func WithoutSchemas() Option {
+	return func(options *ConnectionOptions) {
+		options.NoSchemas = true
+	}
+}
+
+// ConnectionURL returns the db url with adapter specific parameters
+func (options ConnectionOptions) ConnectionURL(dbURL string) string {
+	if len(options.URLParams) == 0 {
+		return dbURL
+	}
+
+	separator := "?"
+	if strings.Contains(dbURL, "?") {
+		separator = "&"
+	}
+
+	return dbURL + separator + strings.Join(options.URLParams, "&")
+}
+
+type registration struct {
+	factory Factory
+	options ConnectionOptions
+}
+
+var (
+	registryMtx sync.RWMutex
+	registry    = make(map[string]registration)
+)
+
+// Register makes a db adapter available by name (to be selected with --db-adapter),
+// it panics if the factory is nil or the name is already registered
+func Register(name string, factory Factory, options ...Option) {
+	registryMtx.Lock()
+	defer registryMtx.Unlock()
+
+	if factory == nil {
+		panic("adapters: Register factory is nil")
+	}
+	if _, ok := registry[name]; ok {
+		panic("adapters: Register called twice for adapter " + name)
+	}
+
+	reg := registration{factory: factory}
+	for _, option := range options {
+		option(&reg.options)
+	}
+
+	registry[name] = reg
+}
+
+// New builds a registered db adapter and returns its connection options
+func New(name, schema string, log *logger.Logger) (DBAdapter, ConnectionOptions, error) {
+	registryMtx.RLock()
+	defer registryMtx.RUnlock()
+
+	reg, ok := registry[name]
+	if !ok {
+		return nil, ConnectionOptions{}, fmt.Errorf("invalid database adapter %s, registered adapters are: %s", name, strings.Join(names(), ", "))
+	}
+
+	return reg.factory(schema, log), reg.options, nil
+}
+
+// Registered returns the sorted names of registered db adapters
+func Registered() []string {
+	registryMtx.RLock()
+	defer registryMtx.RUnlock()
+
+	return names()
+}
+
+func names() []string {
+	list := make([]string, 0, len(registry))
+	for name := range registry {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
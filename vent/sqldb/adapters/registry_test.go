@@ -0,0 +1,50 @@
+package adapters_test
+
+import (
+	"testing"
+
+	"github.com/monax/bosmarmot/vent/logger"
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
+	"github.com/monax/bosmarmot/vent/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	log := logger.NewLogger("debug")
+
+	t.Run("successfully builds registered adapters with their connection options", func(t *testing.T) {
+		require.Subset(t, adapters.Registered(), []string{types.PostgresDB, types.SQLiteDB, types.MySQLDB})
+
+		dbAdapter, options, err := adapters.New(types.SQLiteDB, "", log)
+		require.NoError(t, err)
+		require.IsType(t, &adapters.SQLiteAdapter{}, dbAdapter)
+		require.Equal(t, "./vent.sqlite?_journal_mode=WAL", options.ConnectionURL("./vent.sqlite"))
+		require.Equal(t, "file:vent.sqlite?cache=shared&_journal_mode=WAL", options.ConnectionURL("file:vent.sqlite?cache=shared"))
+
+		dbAdapter, options, err = adapters.New(types.PostgresDB, "vent", log)
+		require.NoError(t, err)
+		require.Equal(t, "vent", dbAdapter.(*adapters.PostgresAdapter).Schema)
+		require.Equal(t, "postgres://localhost/vent", options.ConnectionURL("postgres://localhost/vent"))
+	})
+
+	t.Run("successfully registers a custom adapter", func(t *testing.T) {
+		adapters.Register("custom", func(schema string, log *logger.Logger) adapters.DBAdapter {
+			return adapters.NewPostgresAdapter(schema, log)
+		}, adapters.WithURLParams("a=1", "b=2"))
+
+		_, options, err := adapters.New("custom", "vent", log)
+		require.NoError(t, err)
+		require.Equal(t, "db?a=1&b=2", options.ConnectionURL("db"))
+
+		require.Panics(t, func() {
+			adapters.Register("custom", func(schema string, log *logger.Logger) adapters.DBAdapter {
+				return nil
+			})
+		})
+	})
+
+	t.Run("returns an error if the adapter is not registered", func(t *testing.T) {
+		_, _, err := adapters.New("unknown", "", log)
+		require.Error(t, err)
+	})
+}
@@ -2,14 +2,19 @@ package adapters
 
 import (
 	"database/sql"
+	"encoding/base64"
 	"fmt"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/mattn/go-sqlite3"
 	"github.com/monax/bosmarmot/vent/logger"
 	"github.com/monax/bosmarmot/vent/types"
 )
 
+// numeric columns (e.g. uint256) are stored as canonical decimal text,
+// as NUMERIC affinity would turn big integers into lossy reals
 var sqliteDataTypes = map[types.SQLColumnType]string{
 	types.SQLColumnTypeBool:      "BOOLEAN",
 	types.SQLColumnTypeByteA:     "BLOB",
@@ -18,14 +23,48 @@ var sqliteDataTypes = map[types.SQLColumnType]string{
 	types.SQLColumnTypeText:      "TEXT",
 	types.SQLColumnTypeVarchar:   "VARCHAR",
 	types.SQLColumnTypeTimeStamp: "TIMESTAMP",
-	types.SQLColumnTypeNumeric:   "NUMERIC",
+	types.SQLColumnTypeNumeric:   "TEXT",
 	types.SQLColumnTypeJSON:      "TEXT",
 	types.SQLColumnTypeBigInt:    "BIGINT",
+	types.SQLColumnTypeJSONB:     "TEXT",
 }
 
+func init() {
+	// "_journal_mode=WAL" parameter is necessary to prevent database locking
+	Register(types.SQLiteDB, func(schema string, log *logger.Logger) DBAdapter {
+		return NewSQLiteAdapter(log)
+	}, WithURLParams("_journal_mode=WAL"), WithoutSchemas())
+}
+
+// SQLiteMemory is the db url of in-memory SQLite databases, each connection opened with it gets a new database
+const SQLiteMemory = ":memory:"
+
+// SQLite synchronous levels, the database default is FULL
+const (
+	SQLiteSynchronousOff    = "OFF"
+	SQLiteSynchronousNormal = "NORMAL"
+	SQLiteSynchronousFull   = "FULL"
+	SQLiteSynchronousExtra  = "EXTRA"
+)
+
+// SQLiteSynchronousLevels lists valid synchronous levels
+var SQLiteSynchronousLevels = []string{SQLiteSynchronousOff, SQLiteSynchronousNormal, SQLiteSynchronousFull, SQLiteSynchronousExtra}
+
+// sqliteGeneratedColumnsVersion is the first library version (3.31.0) supporting generated columns
+const sqliteGeneratedColumnsVersion = 3031000
+
+// sqliteMemoryDatabases numbers in-memory databases
+var sqliteMemoryDatabases uint64
+
 // SQLiteAdapter implements DBAdapter for SQLiteDB
 type SQLiteAdapter struct {
 	Log *logger.Logger
+	// BusyTimeout is how long connections wait for locks held by other connections (0 keeps the driver default)
+	BusyTimeout time.Duration
+	// Synchronous is the synchronous level of connections (empty keeps the database default)
+	Synchronous string
+	// dbURL is the url of the database opened last, backups open their own connections
+	dbURL string
 }
 
 // NewSQLiteAdapter constructs a new db adapter
@@ -37,15 +76,67 @@ func NewSQLiteAdapter(log *logger.Logger) *SQLiteAdapter {
 
 // Open connects to a SQLiteQL database, opens it & create default schema if provided
 func (adapter *SQLiteAdapter) Open(dbURL string) (*sql.DB, error) {
+	dbURL, err := adapter.connectionURL(dbURL)
+	if err != nil {
+		adapter.Log.Info("msg", "Error building database url", "err", err)
+		return nil, err
+	}
+
 	db, err := sql.Open("sqlite3", dbURL)
 	if err != nil {
 		adapter.Log.Info("msg", "Error creating database connection", "err", err)
 		return nil, err
 	}
+	adapter.dbURL = dbURL
 
 	return db, nil
 }
 
+// connectionURL adds the busy timeout & synchronous level to a db url,
+// in-memory databases get a unique name shared by every connection of the pool
+// (the database lives as long as one of them is open)
+func (adapter *SQLiteAdapter) connectionURL(dbURL string) (string, error) {
+	params := make([]string, 0)
+
+	path := dbURL
+	if i := strings.Index(dbURL, "?"); i >= 0 {
+		path = dbURL[:i]
+		params = append(params, dbURL[i+1:])
+	}
+
+	if path == SQLiteMemory {
+		path = fmt.Sprintf("file:vent_memory_%d", atomic.AddUint64(&sqliteMemoryDatabases, 1))
+		params = append([]string{"mode=memory&cache=shared"}, params...)
+	}
+
+	if adapter.BusyTimeout > 0 {
+		params = append(params, fmt.Sprintf("_busy_timeout=%d", adapter.BusyTimeout/time.Millisecond))
+	}
+
+	if adapter.Synchronous != "" {
+		synchronous := strings.ToUpper(adapter.Synchronous)
+		if !isSQLiteSynchronousLevel(synchronous) {
+			return "", fmt.Errorf("invalid SQLite synchronous level %s, valid levels are: %s", adapter.Synchronous, strings.Join(SQLiteSynchronousLevels, ", "))
+		}
+		params = append(params, "_synchronous="+synchronous)
+	}
+
+	if len(params) == 0 {
+		return path, nil
+	}
+
+	return path + "?" + strings.Join(params, "&"), nil
+}
+
+func isSQLiteSynchronousLevel(synchronous string) bool {
+	for _, level := range SQLiteSynchronousLevels {
+		if level == synchronous {
+			return true
+		}
+	}
+	return false
+}
+
 // TypeMapping convert generic dataTypes to database dependent dataTypes
 func (adapter *SQLiteAdapter) TypeMapping(sqlColumnType types.SQLColumnType) (string, error) {
 	if sqlDataType, ok := sqliteDataTypes[sqlColumnType]; ok {
@@ -57,7 +148,22 @@ func (adapter *SQLiteAdapter) TypeMapping(sqlColumnType types.SQLColumnType) (st
 
 // SecureColumnName return columns between appropriate security containers
 func (adapter *SQLiteAdapter) SecureColumnName(columnName string) string {
-	return fmt.Sprintf("[%s]", columnName)
+	return sqliteIdentifierRules.quoteIdentifier(columnName)
+}
+
+// SecureTableName returns a table name between appropriate security containers
+func (adapter *SQLiteAdapter) SecureTableName(tableName string) string {
+	return sqliteIdentifierRules.quoteIdentifier(tableName)
+}
+
+// ValidateTableName returns an error if a table name breaks SQLite naming rules
+func (adapter *SQLiteAdapter) ValidateTableName(tableName string) error {
+	return sqliteIdentifierRules.validateTableName(tableName)
+}
+
+// ValidateColumnName returns an error if a column name breaks SQLite naming rules
+func (adapter *SQLiteAdapter) ValidateColumnName(columnName string) error {
+	return sqliteIdentifierRules.validateColumnName(columnName)
 }
 
 // CreateTableQuery builds query for creating a new table
@@ -108,19 +214,19 @@ func (adapter *SQLiteAdapter) CreateTableQuery(tableName string, columns []types
 			i)
 	}
 
-	query := fmt.Sprintf("CREATE TABLE %s (%s", tableName, columnsDef)
+	query := fmt.Sprintf("CREATE TABLE %s (%s", adapter.SecureTableName(tableName), columnsDef)
 	if primaryKey != "" {
 		if hasSerial {
 			// SQLITE AUTOINCREMENT LIMITATION
 			query += "," + fmt.Sprintf("UNIQUE (%s)", primaryKey)
 		} else {
-			query += "," + fmt.Sprintf("CONSTRAINT %s_pkey PRIMARY KEY (%s)", tableName, primaryKey)
+			query += "," + fmt.Sprintf("CONSTRAINT %s PRIMARY KEY (%s)", adapter.SecureColumnName(primaryKeyName(tableName)), primaryKey)
 		}
 	}
 	query += ");"
 
 	dictionaryQuery := fmt.Sprintf("INSERT INTO %s (%s,%s,%s,%s,%s,%s) VALUES %s;",
-		types.SQLDictionaryTableName,
+		adapter.SecureTableName(types.SQLDictionaryTableName),
 		types.SQLColumnLabelTableName, types.SQLColumnLabelColumnName,
 		types.SQLColumnLabelColumnType, types.SQLColumnLabelColumnLength,
 		types.SQLColumnLabelPrimaryKey, types.SQLColumnLabelColumnOrder,
@@ -133,7 +239,7 @@ func (adapter *SQLiteAdapter) CreateTableQuery(tableName string, columns []types
 func (adapter *SQLiteAdapter) LastBlockIDQuery() string {
 	query := `
 		WITH ll AS (
-			SELECT MAX(%s) AS %s FROM %s
+			SELECT MAX(%s) AS %s FROM %s WHERE %s IS NOT NULL
 		)
 		SELECT COALESCE(%s, '0') AS %s
 			FROM ll LEFT OUTER JOIN %s log ON (ll.%s = log.%s);`
@@ -141,10 +247,11 @@ func (adapter *SQLiteAdapter) LastBlockIDQuery() string {
 	return fmt.Sprintf(query,
 		types.SQLColumnLabelId,                         // max
 		types.SQLColumnLabelId,                         // as
-		types.SQLLogTableName,                          // from
+		adapter.SecureTableName(types.SQLLogTableName), // from
+		types.SQLColumnLabelHeight,                     // where
 		types.SQLColumnLabelHeight,                     // coalesce
 		types.SQLColumnLabelHeight,                     // as
-		types.SQLLogTableName,                          // from
+		adapter.SecureTableName(types.SQLLogTableName), // from
 		types.SQLColumnLabelId, types.SQLColumnLabelId) // on
 }
 
@@ -153,8 +260,8 @@ func (adapter *SQLiteAdapter) FindTableQuery() string {
 	query := "SELECT COUNT(*) found FROM %s WHERE %s = $1;"
 
 	return fmt.Sprintf(query,
-		types.SQLDictionaryTableName,  // from
-		types.SQLColumnLabelTableName) // where
+		adapter.SecureTableName(types.SQLDictionaryTableName), // from
+		types.SQLColumnLabelTableName)                         // where
 }
 
 // TableDefinitionQuery returns a query with table structure
@@ -172,9 +279,34 @@ func (adapter *SQLiteAdapter) TableDefinitionQuery() string {
 	return fmt.Sprintf(query,
 		types.SQLColumnLabelColumnName, types.SQLColumnLabelColumnType, // select
 		types.SQLColumnLabelColumnLength, types.SQLColumnLabelPrimaryKey, // select
-		types.SQLDictionaryTableName,    // from
-		types.SQLColumnLabelTableName,   // where
-		types.SQLColumnLabelColumnOrder) // order by
+		adapter.SecureTableName(types.SQLDictionaryTableName), // from
+		types.SQLColumnLabelTableName,                         // where
+		types.SQLColumnLabelColumnOrder)                       // order by
+}
+
+// TableColumnsQuery returns a query with the actual table structure from table_info pragma,
+// declared types are split into type name & length
+func (adapter *SQLiteAdapter) TableColumnsQuery(tableName string) types.UpsertDeleteQuery {
+	query := `
+		SELECT
+			name,
+			CASE WHEN instr(type, '(') > 0 THEN substr(type, 1, instr(type, '(') - 1) ELSE type END,
+			CASE WHEN instr(type, '(') > 0 THEN CAST(substr(type, instr(type, '(') + 1) AS INTEGER) END
+		FROM
+			pragma_table_info($1)
+		ORDER BY
+			cid;`
+
+	return types.UpsertDeleteQuery{Query: query, Values: tableName, Pointers: []interface{}{tableName}}
+}
+
+// CatalogColumnType returns the declared type of a generic column type
+func (adapter *SQLiteAdapter) CatalogColumnType(sqlColumnType types.SQLColumnType) string {
+	// SQLITE AUTOINCREMENT LIMITATION
+	if sqlColumnType == types.SQLColumnTypeSerial {
+		return "INTEGER"
+	}
+	return sqliteDataTypes[sqlColumnType]
 }
 
 // AlterColumnQuery returns a query for adding a new column to a table
@@ -185,7 +317,7 @@ func (adapter *SQLiteAdapter) AlterColumnQuery(tableName, columnName string, sql
 	}
 
 	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;",
-		tableName,
+		adapter.SecureTableName(tableName),
 		adapter.SecureColumnName(columnName),
 		sqlType)
 
@@ -193,7 +325,7 @@ func (adapter *SQLiteAdapter) AlterColumnQuery(tableName, columnName string, sql
 		INSERT INTO %s (%s,%s,%s,%s,%s,%s)
 		VALUES ('%s','%s',%d,%d,%d,%d);`,
 
-		types.SQLDictionaryTableName,
+		adapter.SecureTableName(types.SQLDictionaryTableName),
 
 		types.SQLColumnLabelTableName, types.SQLColumnLabelColumnName,
 		types.SQLColumnLabelColumnType, types.SQLColumnLabelColumnLength,
@@ -204,9 +336,206 @@ func (adapter *SQLiteAdapter) AlterColumnQuery(tableName, columnName string, sql
 	return query, dictionaryQuery
 }
 
+// AlterColumnTypeQuery returns a query for changing the type of an existing column,
+// SQLite does not support altering column types so the table is rebuilt
+func (adapter *SQLiteAdapter) AlterColumnTypeQuery(tableName string, columns []types.SQLTableColumn, column types.SQLTableColumn) (string, string) {
+	query := adapter.rebuildTableQuery(tableName, columns)
+
+	dictionaryQuery := fmt.Sprintf(`
+		UPDATE %s SET %s = %d, %s = %d
+		WHERE %s = '%s' AND %s = '%s';`,
+
+		adapter.SecureTableName(types.SQLDictionaryTableName),
+
+		types.SQLColumnLabelColumnType, column.Type,
+		types.SQLColumnLabelColumnLength, column.Length,
+
+		types.SQLColumnLabelTableName, tableName,
+		types.SQLColumnLabelColumnName, column.Name)
+
+	return query, dictionaryQuery
+}
+
+// RenameColumnQuery returns a query for renaming an existing column
+func (adapter *SQLiteAdapter) RenameColumnQuery(tableName, columnName, newColumnName string) (string, string) {
+	query := fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;",
+		adapter.SecureTableName(tableName),
+		adapter.SecureColumnName(columnName),
+		adapter.SecureColumnName(newColumnName))
+
+	dictionaryQuery := fmt.Sprintf(`
+		UPDATE %s SET %s = '%s'
+		WHERE %s = '%s' AND %s = '%s';`,
+
+		adapter.SecureTableName(types.SQLDictionaryTableName),
+
+		types.SQLColumnLabelColumnName, newColumnName,
+
+		types.SQLColumnLabelTableName, tableName,
+		types.SQLColumnLabelColumnName, columnName)
+
+	return query, dictionaryQuery
+}
+
+// DropColumnQuery returns a query for removing an existing column,
+// SQLite does not support dropping columns so the table is rebuilt
+func (adapter *SQLiteAdapter) DropColumnQuery(tableName string, columns []types.SQLTableColumn, columnName string) (string, string) {
+	query := adapter.rebuildTableQuery(tableName, columns)
+
+	dictionaryQuery := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE %s = '%s' AND %s = '%s';`,
+
+		adapter.SecureTableName(types.SQLDictionaryTableName),
+
+		types.SQLColumnLabelTableName, tableName,
+		types.SQLColumnLabelColumnName, columnName)
+
+	return query, dictionaryQuery
+}
+
+// rebuildTableQuery returns a query that recreates a table with the given columns
+// and copies existing data into it, numeric values stored as reals (NUMERIC affinity
+// of previous versions) are copied as decimal text
+func (adapter *SQLiteAdapter) rebuildTableQuery(tableName string, columns []types.SQLTableColumn) string {
+	oldTable := fmt.Sprintf("_vent_old_%s", tableName)
+	createQuery, _ := adapter.CreateTableQuery(tableName, columns)
+
+	fields := ""
+	values := ""
+	for _, column := range columns {
+		if fields != "" {
+			fields += ", "
+			values += ", "
+		}
+		secureColumn := adapter.SecureColumnName(column.Name)
+		fields += secureColumn
+
+		if column.Type == types.SQLColumnTypeNumeric {
+			values += fmt.Sprintf("CASE WHEN typeof(%s) = 'real' THEN printf('%%.0f', %s) ELSE %s END", secureColumn, secureColumn, secureColumn)
+		} else {
+			values += secureColumn
+		}
+	}
+
+	return fmt.Sprintf("ALTER TABLE %s RENAME TO %s; %s INSERT INTO %s (%s) SELECT %s FROM %s; DROP TABLE %s;",
+		adapter.SecureTableName(tableName), adapter.SecureTableName(oldTable),
+		createQuery,
+		adapter.SecureTableName(tableName), fields, values, adapter.SecureTableName(oldTable),
+		adapter.SecureTableName(oldTable))
+}
+
+// AddJSONPathQuery returns a query to add a virtual column generated from a JSON path,
+// generated columns need SQLite v3.31 (and above) with JSON functions
+func (adapter *SQLiteAdapter) AddJSONPathQuery(tableName string, jsonPath types.SQLJSONPath) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s GENERATED ALWAYS AS (json_extract(%s, '%s')) VIRTUAL;",
+		adapter.SecureTableName(tableName),
+		adapter.SecureColumnName(jsonPath.Name),
+		adapter.SecureColumnName(jsonPath.Column),
+		jsonPath.Path)
+}
+
+// FullTextQuery returns a query creating an external content FTS5 table indexing the full-text columns,
+// kept in sync with the table by triggers, the index is rebuilt only if the triggers are missing: when the
+// FTS5 table has just been created or the table has been rebuilt (dropping the old table drops its triggers)
+func (adapter *SQLiteAdapter) FullTextQuery(tableName string, columnNames []string) string {
+	ftsTable := FullTextTableName(tableName)
+
+	columns := make([]string, len(columnNames))
+	newValues := make([]string, len(columnNames))
+	oldValues := make([]string, len(columnNames))
+	for i, columnName := range columnNames {
+		columns[i] = adapter.SecureColumnName(columnName)
+		newValues[i] = "new." + columns[i]
+		oldValues[i] = "old." + columns[i]
+	}
+
+	insert := fmt.Sprintf("INSERT INTO %s (rowid, %s) VALUES (new.rowid, %s);",
+		adapter.SecureTableName(ftsTable), strings.Join(columns, ", "), strings.Join(newValues, ", "))
+	remove := fmt.Sprintf("INSERT INTO %s (%s, rowid, %s) VALUES ('delete', old.rowid, %s);",
+		adapter.SecureTableName(ftsTable), adapter.SecureColumnName(ftsTable), strings.Join(columns, ", "), strings.Join(oldValues, ", "))
+
+	return fmt.Sprintf(`CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(%s, content='%s', content_rowid='rowid');
+		INSERT INTO %s (%s) SELECT 'rebuild' WHERE NOT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'trigger' AND name = '%s');
+		CREATE TRIGGER IF NOT EXISTS %s AFTER INSERT ON %s BEGIN %s END;
+		CREATE TRIGGER IF NOT EXISTS %s AFTER DELETE ON %s BEGIN %s END;
+		CREATE TRIGGER IF NOT EXISTS %s AFTER UPDATE ON %s BEGIN %s %s END;`,
+		adapter.SecureTableName(ftsTable), strings.Join(columns, ", "), strings.Replace(tableName, "'", "''", -1),
+		adapter.SecureTableName(ftsTable), adapter.SecureColumnName(ftsTable), strings.Replace(ftsTable+"_ai", "'", "''", -1),
+		adapter.SecureColumnName(ftsTable+"_ai"), adapter.SecureTableName(tableName), insert,
+		adapter.SecureColumnName(ftsTable+"_ad"), adapter.SecureTableName(tableName), remove,
+		adapter.SecureColumnName(ftsTable+"_au"), adapter.SecureTableName(tableName), remove, insert)
+}
+
+// DropFullTextQuery returns a query dropping the FTS5 table of a table & its triggers
+func (adapter *SQLiteAdapter) DropFullTextQuery(tableName string) string {
+	ftsTable := FullTextTableName(tableName)
+
+	return fmt.Sprintf("DROP TRIGGER IF EXISTS %s; DROP TRIGGER IF EXISTS %s; DROP TRIGGER IF EXISTS %s; DROP TABLE IF EXISTS %s;",
+		adapter.SecureColumnName(ftsTable+"_ai"), adapter.SecureColumnName(ftsTable+"_ad"), adapter.SecureColumnName(ftsTable+"_au"),
+		adapter.SecureTableName(ftsTable))
+}
+
+// SearchQuery returns a query for searching the words of a text in a full-text column ranked by relevance,
+// words are quoted so that they are not read as FTS5 operators
+func (adapter *SQLiteAdapter) SearchQuery(table types.SQLTable, columnName string) string {
+	ftsTable := FullTextTableName(table.Name)
+
+	return fmt.Sprintf(`SELECT %s FROM %s t JOIN %s f ON f.rowid = t.rowid
+		WHERE f.%s MATCH '"' || replace(replace($1, '"', '""'), ' ', '" "') || '"'
+		ORDER BY f.rank LIMIT $2;`,
+		searchFields(table, "t", adapter.SecureColumnName),
+		adapter.SecureTableName(table.Name),
+		adapter.SecureTableName(ftsTable),
+		adapter.SecureColumnName(columnName))
+}
+
+// CheckpointQuery returns a query copying the write-ahead log to the database file & truncating it
+func (adapter *SQLiteAdapter) CheckpointQuery() string {
+	return "PRAGMA wal_checkpoint(TRUNCATE);"
+}
+
+// Backup copies the database opened last to a file with the SQLite online backup API,
+// every page is copied in a single step holding one read transaction on the source, so the copy is
+// a consistent snapshot that writes from other connections can't restart (in WAL mode they go on meanwhile)
+func (adapter *SQLiteAdapter) Backup(destination string) error {
+	// driver connections are opened directly, database/sql doesn't expose them
+	driver := &sqlite3.SQLiteDriver{}
+
+	src, err := driver.Open(adapter.dbURL)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dest, err := driver.Open(destination)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	backup, err := dest.(*sqlite3.SQLiteConn).Backup("main", src.(*sqlite3.SQLiteConn), "main")
+	if err != nil {
+		return err
+	}
+
+	// a negative page count copies every remaining page
+	done, err := backup.Step(-1)
+	if err != nil {
+		backup.Close()
+		return err
+	}
+	if !done {
+		backup.Close()
+		return fmt.Errorf("error backing up database, %d pages remaining", backup.Remaining())
+	}
+
+	return backup.Finish()
+}
+
 // SelectRowQuery returns a query for selecting row values
 func (adapter *SQLiteAdapter) SelectRowQuery(tableName, fields, indexValue string) string {
-	return fmt.Sprintf("SELECT %s FROM %s WHERE %s = '%s';", fields, tableName, types.SQLColumnLabelHeight, indexValue)
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s = '%s';", fields, adapter.SecureTableName(tableName), types.SQLColumnLabelHeight, indexValue)
 }
 
 // SelectLogQuery returns a query for selecting all tables involved in a block trn
@@ -216,22 +545,22 @@ func (adapter *SQLiteAdapter) SelectLogQuery() string {
 
 	return fmt.Sprintf(query,
 		types.SQLColumnLabelTableName, types.SQLColumnLabelEventName, // select
-		types.SQLLogTableName,      // from
-		types.SQLColumnLabelHeight) // where
+		adapter.SecureTableName(types.SQLLogTableName), // from
+		types.SQLColumnLabelHeight)                     // where
 }
 
 // InsertLogQuery returns a query to insert a row in log table
 func (adapter *SQLiteAdapter) InsertLogQuery() string {
 	query := `
-		INSERT INTO %s (%s,%s,%s,%s,%s,%s,%s,%s,%s,%s)
-		VALUES (CURRENT_TIMESTAMP, $1, $2, $3, $4, $5, $6 ,$7, $8, $9);`
+		INSERT INTO %s (%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s)
+		VALUES (CURRENT_TIMESTAMP, $1, $2, $3, $4, $5, $6 ,$7, $8, $9, $10);`
 
 	return fmt.Sprintf(query,
-		types.SQLLogTableName, // insert
+		adapter.SecureTableName(types.SQLLogTableName), // insert
 		//fields
 		types.SQLColumnLabelTimeStamp, types.SQLColumnLabelTableName, types.SQLColumnLabelEventName, types.SQLColumnLabelEventFilter,
 		types.SQLColumnLabelHeight, types.SQLColumnLabelTxHash, types.SQLColumnLabelAction, types.SQLColumnLabelDataRow,
-		types.SQLColumnLabelSqlStmt, types.SQLColumnLabelSqlValues)
+		types.SQLColumnLabelSqlStmt, types.SQLColumnLabelSqlValues, types.SQLColumnLabelBeforeImage)
 }
 
 // ErrorEquals verify if an error is of a given SQL type
@@ -253,6 +582,12 @@ func (adapter *SQLiteAdapter) ErrorEquals(err error, sqlErrorType types.SQLError
 		case types.SQLErrorTypeInvalidType:
 			// NOT SUPPORTED
 			return false
+		case types.SQLErrorTypeUnsupported:
+			// missing fts5 or json1 extensions, generated columns are a syntax error before v3.31
+			_, libVersion, _ := sqlite3.Version()
+			return err.Code == 1 && (strings.Contains(errDescription, "no such module") ||
+				strings.Contains(errDescription, "no such function: json") ||
+				(libVersion < sqliteGeneratedColumnsVersion && strings.Contains(errDescription, "syntax error")))
 		}
 	}
 
@@ -272,7 +607,7 @@ func (adapter *SQLiteAdapter) UpsertQuery(table types.SQLTable, row types.EventD
 	i := 0
 
 	// for each column in table
-	for _, tableColumn := range table.Columns {
+	for _, tableColumn := range sortedColumns(table) {
 		secureColumn := adapter.SecureColumnName(tableColumn.Name)
 
 		i++
@@ -326,7 +661,7 @@ func (adapter *SQLiteAdapter) UpsertQuery(table types.SQLTable, row types.EventD
 
 	}
 
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ", table.Name, columns, insValues)
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ", adapter.SecureTableName(table.Name), columns, insValues)
 
 	if pkColumns != "" {
 		if updValues != "" {
@@ -348,7 +683,7 @@ func (adapter *SQLiteAdapter) DeleteQuery(table types.SQLTable, row types.EventD
 	i := 0
 
 	// for each column in table
-	for _, tableColumn := range table.Columns {
+	for _, tableColumn := range sortedColumns(table) {
 
 		//only PK for delete
 		if tableColumn.Primary {
@@ -382,24 +717,29 @@ func (adapter *SQLiteAdapter) DeleteQuery(table types.SQLTable, row types.EventD
 		return types.UpsertDeleteQuery{}, fmt.Errorf("error primary key not found for deletion")
 	}
 
-	query := fmt.Sprintf("DELETE FROM %s WHERE %s;", table.Name, columns)
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s;", adapter.SecureTableName(table.Name), columns)
 
 	return types.UpsertDeleteQuery{Query: query, Values: values, Pointers: pointers}, nil
 }
 
+// RestoreDBQuery returns a query for selecting upserts & deletes logged up to a given time
 func (adapter *SQLiteAdapter) RestoreDBQuery() string {
+	return fmt.Sprintf(`SELECT %s, %s, %s FROM %s WHERE %s <= $1 AND %s IN ('%s', '%s') ORDER BY %s;`,
+		types.SQLColumnLabelTableName, types.SQLColumnLabelAction, types.SQLColumnLabelDataRow,
+		adapter.SecureTableName(types.SQLLogTableName),
+		types.SQLColumnLabelTimeStamp,
+		types.SQLColumnLabelAction, types.ActionUpsert, types.ActionDelete,
+		types.SQLColumnLabelId)
+}
 
-	query := fmt.Sprintf("SELECT %s, %s, %s, %s FROM %s",
-		types.SQLColumnLabelTableName, types.SQLColumnLabelAction, types.SQLColumnLabelSqlStmt, types.SQLColumnLabelSqlValues,
-		types.SQLLogTableName)
-
-	query += " WHERE strftime('%Y-%m-%d %H:%M:%S',"
-
-	query += fmt.Sprintf("%s)<=$1 ORDER BY %s;",
-		types.SQLColumnLabelTimeStamp, types.SQLColumnLabelId)
-
-	return query
-
+// RestoreHeightQuery returns a query for selecting upserts & deletes logged up to a given height
+func (adapter *SQLiteAdapter) RestoreHeightQuery() string {
+	return fmt.Sprintf(`SELECT %s, %s, %s FROM %s WHERE %s <= $1 AND %s IN ('%s', '%s') ORDER BY %s;`,
+		types.SQLColumnLabelTableName, types.SQLColumnLabelAction, types.SQLColumnLabelDataRow,
+		adapter.SecureTableName(types.SQLLogTableName),
+		types.SQLColumnLabelHeight,
+		types.SQLColumnLabelAction, types.ActionUpsert, types.ActionDelete,
+		types.SQLColumnLabelId)
 }
 
 func (adapter *SQLiteAdapter) CleanDBQueries() types.SQLCleanDBQuery {
@@ -412,15 +752,15 @@ func (adapter *SQLiteAdapter) CleanDBQueries() types.SQLCleanDBQuery {
 		COALESCE(MAX(%s),'') BVERSION 
 		FROM %s;`,
 		types.SQLColumnLabelChainID, types.SQLColumnLabelBurrowVer,
-		types.SQLChainInfoTableName)
+		adapter.SecureTableName(types.SQLChainInfoTableName))
 
 	deleteChainIDQry := fmt.Sprintf(`
 		DELETE FROM %s;`,
-		types.SQLChainInfoTableName)
+		adapter.SecureTableName(types.SQLChainInfoTableName))
 
 	insertChainIDQry := fmt.Sprintf(`
 		INSERT INTO %s (%s,%s) VALUES($1,$2)`,
-		types.SQLChainInfoTableName,
+		adapter.SecureTableName(types.SQLChainInfoTableName),
 		types.SQLColumnLabelChainID, types.SQLColumnLabelBurrowVer)
 
 	// Dictionary
@@ -428,24 +768,29 @@ func (adapter *SQLiteAdapter) CleanDBQueries() types.SQLCleanDBQuery {
 		SELECT DISTINCT %s 
 		FROM %s 
  		WHERE %s
-		NOT IN ('%s','%s','%s');`,
+		NOT IN ('%s','%s','%s','%s');`,
 		types.SQLColumnLabelTableName,
-		types.SQLDictionaryTableName,
+		adapter.SecureTableName(types.SQLDictionaryTableName),
 		types.SQLColumnLabelTableName,
-		types.SQLLogTableName, types.SQLDictionaryTableName, types.SQLChainInfoTableName)
+		types.SQLLogTableName, types.SQLDictionaryTableName, types.SQLChainInfoTableName, types.SQLAbiTableName)
 
 	deleteDictionaryQry := fmt.Sprintf(`
 		DELETE FROM %s 
 		WHERE %s 
-		NOT IN ('%s','%s','%s');`,
-		types.SQLDictionaryTableName,
+		NOT IN ('%s','%s','%s','%s');`,
+		adapter.SecureTableName(types.SQLDictionaryTableName),
 		types.SQLColumnLabelTableName,
-		types.SQLLogTableName, types.SQLDictionaryTableName, types.SQLChainInfoTableName)
+		types.SQLLogTableName, types.SQLDictionaryTableName, types.SQLChainInfoTableName, types.SQLAbiTableName)
 
 	// log
 	deleteLogQry := fmt.Sprintf(`
 		DELETE FROM %s;`,
-		types.SQLLogTableName)
+		adapter.SecureTableName(types.SQLLogTableName))
+
+	// abi registry
+	deleteAbiQry := fmt.Sprintf(`
+		DELETE FROM %s;`,
+		adapter.SecureTableName(types.SQLAbiTableName))
 
 	return types.SQLCleanDBQuery{
 		SelectChainIDQry:    selectChainIDQry,
@@ -454,10 +799,158 @@ func (adapter *SQLiteAdapter) CleanDBQueries() types.SQLCleanDBQuery {
 		SelectDictionaryQry: selectDictionaryQry,
 		DeleteDictionaryQry: deleteDictionaryQry,
 		DeleteLogQry:        deleteLogQry,
+		DeleteAbiQry:        deleteAbiQry,
 	}
 }
 
+// SelectAbiQuery returns a query for selecting all abi specifications stored by contract address
+func (adapter *SQLiteAdapter) SelectAbiQuery() string {
+	return fmt.Sprintf("SELECT %s, %s FROM %s;",
+		types.SQLColumnLabelAddress, types.SQLColumnLabelAbi,
+		adapter.SecureTableName(types.SQLAbiTableName))
+}
+
 func (adapter *SQLiteAdapter) DropTableQuery(tableName string) string {
 	//drop tables
-	return fmt.Sprintf(`DROP TABLE %s;`, tableName)
+	return fmt.Sprintf(`DROP TABLE %s;`, adapter.SecureTableName(tableName))
+}
+
+// SelectByKeyQuery returns a query for selecting the current contents of rows by PK
+func (adapter *SQLiteAdapter) SelectByKeyQuery(table types.SQLTable, rows []types.EventDataRow) (types.UpsertDeleteQuery, error) {
+	return selectByKeyQuery(adapter.SecureTableName(table.Name), table, rows, adapter.SecureColumnName, dollarParam)
+}
+
+// SelectLogAfterHeightQuery returns a query for selecting upserts & deletes logged after a given height
+func (adapter *SQLiteAdapter) SelectLogAfterHeightQuery() string {
+	return fmt.Sprintf(`SELECT %s, %s, %s, %s, %s FROM %s WHERE %s > $1 AND %s IN ('%s', '%s') ORDER BY %s;`,
+		types.SQLColumnLabelId, types.SQLColumnLabelTableName, types.SQLColumnLabelAction, types.SQLColumnLabelDataRow, types.SQLColumnLabelBeforeImage,
+		adapter.SecureTableName(types.SQLLogTableName),
+		types.SQLColumnLabelHeight,
+		types.SQLColumnLabelAction, types.ActionUpsert, types.ActionDelete,
+		types.SQLColumnLabelId)
+}
+
+// DeleteLogAfterHeightQuery returns a query for deleting rows logged after a given height
+func (adapter *SQLiteAdapter) DeleteLogAfterHeightQuery() string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE %s > $1;`, adapter.SecureTableName(types.SQLLogTableName), types.SQLColumnLabelHeight)
+}
+
+// SelectLogUpToHeightQuery returns a query for selecting upserts & deletes logged up to a given height
+func (adapter *SQLiteAdapter) SelectLogUpToHeightQuery() string {
+	return selectLogUpToHeightQuery(adapter.SecureTableName(types.SQLLogTableName), "$1")
+}
+
+// CheckpointLogQuery returns a query to keep a checkpoint for each block logged up to a given height
+func (adapter *SQLiteAdapter) CheckpointLogQuery() string {
+	return checkpointLogQuery(adapter.SecureTableName(types.SQLLogTableName), "$1")
+}
+
+// DeleteLogUpToHeightQuery returns a query for deleting upserts & deletes logged up to a given height
+func (adapter *SQLiteAdapter) DeleteLogUpToHeightQuery() string {
+	return deleteLogUpToHeightQuery(adapter.SecureTableName(types.SQLLogTableName), "$1")
+}
+
+// CompactedHeightQuery returns a query for selecting the highest compacted height
+func (adapter *SQLiteAdapter) CompactedHeightQuery() string {
+	return compactedHeightQuery(adapter.SecureTableName(types.SQLLogTableName))
+}
+
+// RenameTableQuery returns a query to rename a table
+func (adapter *SQLiteAdapter) RenameTableQuery(tableName, newTableName string) string {
+	return fmt.Sprintf(`ALTER TABLE %s RENAME TO %s;`, adapter.SecureTableName(tableName), adapter.SecureTableName(newTableName))
+}
+
+// CreateHistoryViewQuery returns a query to create the history view of a table
+func (adapter *SQLiteAdapter) CreateHistoryViewQuery(table types.SQLTable) string {
+	imageColumn := func(column types.SQLTableColumn) string {
+		return fmt.Sprintf(`json_extract(l.%s, '$."%s"')`, types.SQLColumnLabelBeforeImage, column.Name)
+	}
+
+	return historyViewQuery(adapter.SecureTableName(HistoryViewName(table.Name)), adapter.SecureTableName(table.Name), adapter.SecureTableName(types.SQLLogTableName),
+		table, adapter.SecureColumnName, imageColumn,
+		"NULL",
+		fmt.Sprintf("l.%s <> 'null'", types.SQLColumnLabelBeforeImage))
+}
+
+// CreateEnrichedViewQuery returns a query to create the view joining a table with block & tx data
+func (adapter *SQLiteAdapter) CreateEnrichedViewQuery(tableName string) string {
+	return enrichedViewQuery(
+		adapter.SecureTableName(EnrichedViewName(tableName)),
+		adapter.SecureTableName(tableName),
+		adapter.SecureTableName(types.SQLBlockTableName),
+		adapter.SecureTableName(types.SQLTxTableName))
+}
+
+// DropViewQuery returns a query to drop a view if it exists
+func (adapter *SQLiteAdapter) DropViewQuery(viewName string) string {
+	return fmt.Sprintf(`DROP VIEW IF EXISTS %s;`, adapter.SecureTableName(viewName))
+}
+
+// SelectAsOfQuery returns a query for selecting the rows of a table at a given height from its history view
+func (adapter *SQLiteAdapter) SelectAsOfQuery(table types.SQLTable) string {
+	return selectAsOfQuery(adapter.SecureTableName(HistoryViewName(table.Name)), table, adapter.SecureColumnName, dollarParam,
+		types.SQLColumnLabelValidToHeight)
+}
+
+// DecodeImageBytes decodes byte columns of before-images read from history views,
+// SQLite has no base64 decoding function so views return them as stored in the log
+func (adapter *SQLiteAdapter) DecodeImageBytes(value []byte) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(string(value))
+}
+
+// BulkUpsertQuery returns a multi-row VALUES query for upserting rows
+func (adapter *SQLiteAdapter) BulkUpsertQuery(table types.SQLTable, rows []types.EventDataRow) (types.UpsertDeleteQuery, error) {
+	if len(rows) == 0 {
+		return types.UpsertDeleteQuery{}, fmt.Errorf("error no rows to upsert")
+	}
+
+	columns, err := bulkColumns(table, rows[0])
+	if err != nil {
+		return types.UpsertDeleteQuery{}, err
+	}
+
+	pkColumns := ""
+	updValues := ""
+	for _, column := range columns {
+		secureColumn := adapter.SecureColumnName(column.Name)
+		if column.Primary {
+			if pkColumns != "" {
+				pkColumns += ", "
+			}
+			pkColumns += secureColumn
+		} else {
+			if updValues != "" {
+				updValues += ", "
+			}
+			updValues += fmt.Sprintf("%s = excluded.%s", secureColumn, secureColumn)
+		}
+	}
+
+	queryVal := bulkInsertQuery(adapter.SecureTableName(table.Name), columns, rows, adapter.SecureColumnName, dollarParam)
+
+	if pkColumns != "" {
+		if updValues != "" {
+			queryVal.Query += fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", pkColumns, updValues)
+		} else {
+			queryVal.Query += fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", pkColumns)
+		}
+	}
+	queryVal.Query += ";"
+
+	return queryVal, nil
+}
+
+// BulkDeleteQuery returns a query for deleting multiple rows
+func (adapter *SQLiteAdapter) BulkDeleteQuery(table types.SQLTable, rows []types.EventDataRow) (types.UpsertDeleteQuery, error) {
+	return bulkDeleteQuery(adapter.SecureTableName(table.Name), table, rows, adapter.SecureColumnName, dollarParam)
+}
+
+// BulkInsertLogQuery returns a query to insert multiple rows in log table
+func (adapter *SQLiteAdapter) BulkInsertLogQuery(rows int) string {
+	return bulkInsertLogQuery(adapter.SecureTableName(types.SQLLogTableName), rows, dollarParam)
+}
+
+// MaxQueryParams returns the default SQLITE_MAX_VARIABLE_NUMBER
+func (adapter *SQLiteAdapter) MaxQueryParams() int {
+	return 999
 }
@@ -0,0 +1,149 @@
+package sqldb
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// defaultMaxQueryParams limits query parameters for adapters not implementing adapters.BulkAdapter
+const defaultMaxQueryParams = 999
+
+// beforeImages returns the json encoded contents of each row before its action is applied
+// (json null if the row does not exist), current contents are read within the block transaction
+// and rows with the same primary key see the changes made by previous ones,
+// rows of tables without primary key cannot be identified so their images are always json null
+func (db *SQLDB) beforeImages(tx *sql.Tx, table types.SQLTable, rows []types.EventDataRow) ([][]byte, error) {
+	pkColumns := primaryKeys(table)
+
+	images := make([][]byte, len(rows))
+
+	if len(pkColumns) == 0 {
+		for i := range images {
+			images[i] = []byte("null")
+		}
+		return images, nil
+	}
+
+	current, err := db.selectByKey(tx, table, pkColumns, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, row := range rows {
+		key := primaryKeyValue(pkColumns, row)
+
+		if images[i], err = json.Marshal(current[key]); err != nil {
+			db.Log.Info("msg", "error marshaling before image", "err", err)
+			return nil, err
+		}
+
+		switch row.Action {
+		case types.ActionUpsert:
+			image := make(map[string]interface{}, len(table.Columns))
+			for column, value := range current[key] {
+				image[column] = value
+			}
+			for _, column := range table.Columns {
+				if value, ok := row.RowData[column.Name]; ok {
+					image[column.Name] = value
+				}
+			}
+			current[key] = image
+
+		case types.ActionDelete:
+			delete(current, key)
+		}
+	}
+
+	return images, nil
+}
+
+// selectByKey returns the current contents of rows mapped by primary key values,
+// byte columns are kept as []byte (base64 in json) and any other column as string
+func (db *SQLDB) selectByKey(tx *sql.Tx, table types.SQLTable, pkColumns []string, rows []types.EventDataRow) (map[string]map[string]interface{}, error) {
+	current := make(map[string]map[string]interface{})
+
+	maxParams := defaultMaxQueryParams
+	if bulkAdapter, ok := db.DBAdapter.(adapters.BulkAdapter); ok {
+		maxParams = bulkAdapter.MaxQueryParams()
+	}
+
+	byteColumns := make(map[string]bool)
+	for _, column := range table.Columns {
+		if column.Type == types.SQLColumnTypeByteA {
+			byteColumns[column.Name] = true
+		}
+	}
+
+	for _, chunk := range adapters.BulkChunks(rows, len(pkColumns), maxParams) {
+		queryVal, err := db.DBAdapter.SelectByKeyQuery(table, chunk)
+		if err != nil {
+			db.Log.Info("msg", "Error building select query", "err", err)
+			return nil, err
+		}
+
+		query := clean(queryVal.Query)
+		db.Log.Info("msg", "SELECT BEFORE IMAGE", "query", query, "value", queryVal.Values)
+
+		if err = db.scanImages(tx, query, queryVal.Pointers, byteColumns, func(image map[string]interface{}) {
+			current[primaryKeyValue(pkColumns, types.EventDataRow{RowData: image})] = image
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return current, nil
+}
+
+// scanImages runs a query and calls fn with the contents of each returned row
+func (db *SQLDB) scanImages(tx *sql.Tx, query string, args []interface{}, byteColumns map[string]bool, fn func(map[string]interface{})) error {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		db.Log.Info("msg", "Error querying before image", "err", err)
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		db.Log.Info("msg", "Error getting row columns", "err", err)
+		return err
+	}
+
+	containers := make([]interface{}, len(cols))
+	pointers := make([]interface{}, len(cols))
+	for i := range pointers {
+		pointers[i] = &containers[i]
+	}
+
+	for rows.Next() {
+		if err = rows.Scan(pointers...); err != nil {
+			db.Log.Info("msg", "Error scanning data", "err", err)
+			return err
+		}
+
+		image := make(map[string]interface{}, len(cols))
+		for i, col := range cols {
+			value := containers[i]
+			if b, ok := value.([]byte); ok {
+				if byteColumns[col] {
+					value = append([]byte{}, b...)
+				} else {
+					value = string(b)
+				}
+			}
+			image[col] = value
+		}
+		fn(image)
+	}
+
+	if err = rows.Err(); err != nil {
+		db.Log.Info("msg", "Error during rows iteration", "err", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,257 @@
+package sqldb
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// setBulkRows performs row actions of a table using multi-row queries,
+// consecutive rows with the same action are written together (so interleaved upserts & deletes
+// on the same key keep their order) and one log row is stored for each event data row
+func (db *SQLDB) setBulkRows(tx *sql.Tx, bulkAdapter adapters.BulkAdapter, eventName string, table types.SQLTable, rows []types.EventDataRow, block string) error {
+	safeTable := table.Name
+	logValues := make([]interface{}, 0, len(rows)*adapters.LogQueryParams)
+
+	for start := 0; start < len(rows); {
+		action := rows[start].Action
+
+		end := start + 1
+		for end < len(rows) && rows[end].Action == action {
+			end++
+		}
+		run := rows[start:end]
+		start = end
+
+		// read row contents before the run is applied
+		images, err := db.beforeImages(tx, table, run)
+		if err != nil {
+			db.Log.Info("msg", "error reading before images", "err", err, "value", safeTable)
+			return err
+		}
+
+		switch action {
+		case types.ActionUpsert:
+			for _, group := range groupByColumns(mergeByPrimaryKey(table, run)) {
+				if copyAdapter, ok := bulkAdapter.(adapters.CopyAdapter); ok && len(group) >= copyAdapter.CopyThreshold() {
+					if err := copyAdapter.CopyUpsert(tx, table, group); err != nil {
+						db.Log.Info("msg", "error performing bulk upsert", "err", err, "value", safeTable)
+						return err
+					}
+					continue
+				}
+
+				for _, chunk := range adapters.BulkChunks(group, countColumns(table, group[0]), bulkAdapter.MaxQueryParams()) {
+					queryVal, err := bulkAdapter.BulkUpsertQuery(table, chunk)
+					if err != nil {
+						db.Log.Info("msg", "Error building bulk upsert query", "err", err, "value", safeTable)
+						return err
+					}
+
+					query := clean(queryVal.Query)
+					db.Log.Info("msg", action, "query", query, "value", queryVal.Values)
+					if err = db.execBulk(tx, safeTable, query, queryVal.Pointers); err != nil {
+						db.Log.Info("msg", "error performing bulk upsert", "err", err, "value", queryVal.Values)
+						return err
+					}
+				}
+			}
+
+		case types.ActionDelete:
+			for _, chunk := range adapters.BulkChunks(run, len(primaryKeys(table)), bulkAdapter.MaxQueryParams()) {
+				queryVal, err := bulkAdapter.BulkDeleteQuery(table, chunk)
+				if err != nil {
+					db.Log.Info("msg", "Error building bulk delete query", "err", err, "value", safeTable)
+					return err
+				}
+
+				query := clean(queryVal.Query)
+				db.Log.Info("msg", action, "query", query, "value", queryVal.Values)
+				if err = db.execBulk(tx, safeTable, query, queryVal.Pointers); err != nil {
+					db.Log.Info("msg", "error performing bulk delete", "err", err, "value", queryVal.Values)
+					return err
+				}
+			}
+
+		default:
+			db.Log.Info("msg", "invalid action", "value", action)
+			return fmt.Errorf("invalid row action %s", action)
+		}
+
+		// log the single row statement to keep log replay independent from bulk writes
+		for i, row := range run {
+			values, err := db.logValues(table, eventName, row, block, images[i])
+			if err != nil {
+				return err
+			}
+			logValues = append(logValues, values...)
+		}
+	}
+
+	// Insert in log
+	chunkStart := 0
+	for _, size := range adapters.BulkChunkSizes(len(logValues)/adapters.LogQueryParams, adapters.LogQueryParams, bulkAdapter.MaxQueryParams()) {
+		chunkEnd := chunkStart + size*adapters.LogQueryParams
+
+		logQuery := clean(bulkAdapter.BulkInsertLogQuery(size))
+		db.Log.Info("msg", "INSERT LOG", "query", logQuery, "value", fmt.Sprintf("tableName = %s eventName = %s filter = %s block = %s", safeTable, eventName, table.Filter, block))
+		if err := db.execBulk(tx, types.SQLLogTableName, logQuery, logValues[chunkStart:chunkEnd]); err != nil {
+			db.Log.Info("msg", "Error inserting into log", "err", err)
+			return err
+		}
+		chunkStart = chunkEnd
+	}
+
+	return nil
+}
+
+// execBulk executes a multi-row query with a cached prepared statement, rows are split by adapters.BulkChunks
+// so that queries of a table have a few distinct shapes reused from one block to the next
+func (db *SQLDB) execBulk(tx *sql.Tx, tableName, query string, values []interface{}) error {
+	stmt, err := db.stmts.prepare(db.DB, tableName, query)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Stmt(stmt).Exec(values...)
+	return err
+}
+
+// logValues returns log table values for a row
+func (db *SQLDB) logValues(table types.SQLTable, eventName string, row types.EventDataRow, block string, beforeImage []byte) ([]interface{}, error) {
+	var queryVal types.UpsertDeleteQuery
+	var txHash interface{}
+	var err error
+
+	switch row.Action {
+	case types.ActionUpsert:
+		if queryVal, txHash, err = db.DBAdapter.UpsertQuery(table, row); err != nil {
+			db.Log.Info("msg", "Error building upsert query", "err", err, "value", fmt.Sprintf("%v %v", table, row))
+			return nil, err
+		}
+
+	case types.ActionDelete:
+		if queryVal, err = db.DBAdapter.DeleteQuery(table, row); err != nil {
+			db.Log.Info("msg", "Error building delete query", "err", err, "value", fmt.Sprintf("%v %v", table, row))
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("invalid row action %s", row.Action)
+	}
+
+	// Marshal the rowData map
+	jsonData, err := db.getJSON(row.RowData)
+	if err != nil {
+		db.Log.Info("msg", "error marshaling rowData", "err", err, "value", fmt.Sprintf("%v", row.RowData))
+		return nil, err
+	}
+
+	// Marshal sql values
+	sqlValues, err := db.getJSONFromValues(queryVal.Pointers)
+	if err != nil {
+		db.Log.Info("msg", "error marshaling rowdata", "err", err, "value", fmt.Sprintf("%v", row.RowData))
+		return nil, err
+	}
+
+	return []interface{}{table.Name, eventName, table.Filter, block, txHash, row.Action, jsonData, clean(queryVal.Query), sqlValues, beforeImage}, nil
+}
+
+// mergeByPrimaryKey merges upserted rows having the same primary key,
+// later values override earlier ones and rows keep the order of the first upsert,
+// rows of tables without primary key are all inserted so they are not merged
+func mergeByPrimaryKey(table types.SQLTable, rows []types.EventDataRow) []types.EventDataRow {
+	pkColumns := primaryKeys(table)
+	if len(pkColumns) == 0 {
+		return rows
+	}
+
+	merged := make([]types.EventDataRow, 0, len(rows))
+	index := make(map[string]int)
+
+	for _, row := range rows {
+		key := primaryKeyValue(pkColumns, row)
+
+		i, ok := index[key]
+		if !ok {
+			rowData := make(map[string]interface{}, len(row.RowData))
+			for column, value := range row.RowData {
+				rowData[column] = value
+			}
+			index[key] = len(merged)
+			merged = append(merged, types.EventDataRow{Action: row.Action, RowData: rowData})
+			continue
+		}
+
+		for column, value := range row.RowData {
+			merged[i].RowData[column] = value
+		}
+	}
+
+	return merged
+}
+
+// groupByColumns groups rows having the same set of columns, so that absent columns are not updated
+func groupByColumns(rows []types.EventDataRow) [][]types.EventDataRow {
+	groups := make([][]types.EventDataRow, 0)
+	index := make(map[string]int)
+
+	for _, row := range rows {
+		columns := make([]string, 0, len(row.RowData))
+		for column := range row.RowData {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+		key := strings.Join(columns, "\x00")
+
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], row)
+	}
+
+	return groups
+}
+
+// countColumns returns the number of table columns set by a row
+func countColumns(table types.SQLTable, row types.EventDataRow) int {
+	count := 0
+	for _, column := range table.Columns {
+		if _, ok := row.RowData[column.Name]; ok {
+			count++
+		}
+	}
+	return count
+}
+
+// primaryKeys returns the primary key column names of a table
+func primaryKeys(table types.SQLTable) []string {
+	columns := make([]string, 0)
+	for _, column := range table.Columns {
+		if column.Primary {
+			columns = append(columns, column.Name)
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// primaryKeyValue returns a string identifying the primary key values of a row
+func primaryKeyValue(pkColumns []string, row types.EventDataRow) string {
+	values := make([]string, len(pkColumns))
+	for i, column := range pkColumns {
+		value := row.RowData[column]
+		if rv := reflect.ValueOf(value); rv.Kind() == reflect.Ptr && !rv.IsNil() {
+			value = rv.Elem().Interface()
+		}
+		values[i] = fmt.Sprint(value)
+	}
+	return strings.Join(values, "\x00")
+}
@@ -0,0 +1,194 @@
+package sqldb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// archiveTables renames event & system tables with the stored chain ID as suffix,
+// then creates new system tables & stores the new chainID,
+// renames are done in a single transaction (MySQL commits each of them implicitly)
+func (db *SQLDB) archiveTables(savedChainID, chainID, burrowVersion string) error {
+	cleanQueries := db.DBAdapter.CleanDBQueries()
+	suffix := chainIDSuffix(savedChainID)
+
+	// Load Tables
+	eventTables, err := db.getEventTableNames(cleanQueries.SelectDictionaryQry)
+	if err != nil {
+		return err
+	}
+	isEventTable := make(map[string]bool, len(eventTables))
+	for _, tableName := range eventTables {
+		isEventTable[tableName] = true
+	}
+	tables := append(eventTables, sysTableNames...)
+
+	// Find existing tables, their indexed columns & partitions, renamed along with tables
+	existing := make([]string, 0, len(tables))
+	indexColumns := make(map[string][]string)
+	partitions := make(map[string]map[uint64]string)
+	partitionAdapter, partitioned := db.DBAdapter.(adapters.PartitionAdapter)
+	for _, tableName := range tables {
+		columns, err := db.getCatalogColumns(tableName)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			db.Log.Warn("msg", "Table to archive not found", "value", tableName)
+			continue
+		}
+		existing = append(existing, tableName)
+
+		// archived names are checked before any change, so that tables are not left half renamed
+		newTableName := fmt.Sprintf("%s_%s", tableName, suffix)
+		if err = db.DBAdapter.ValidateTableName(newTableName); err != nil {
+			db.Log.Info("msg", "error validating archived table name", "err", err, "value", tableName)
+			return err
+		}
+
+		if !isEventTable[tableName] {
+			continue
+		}
+
+		jsonColumns, err := db.getJSONColumns(tableName)
+		if err != nil {
+			return err
+		}
+		fullTextColumns, err := db.getFullTextColumns(tableName)
+		if err != nil {
+			return err
+		}
+		indexColumns[tableName] = append(jsonColumns, fullTextColumns...)
+		if partitioned {
+			if partitions[tableName], err = db.getPartitions(partitionAdapter, tableName); err != nil {
+				return err
+			}
+			for from := range partitions[tableName] {
+				if err = db.DBAdapter.ValidateTableName(partitionAdapter.PartitionName(newTableName, from)); err != nil {
+					db.Log.Info("msg", "error validating archived partition name", "err", err, "value", tableName)
+					return err
+				}
+			}
+		}
+	}
+
+	// Drop views (recreated on synchronization)
+	for _, tableName := range existing {
+		if !isEventTable[tableName] {
+			continue
+		}
+		if err = db.dropHistoryView(tableName); err != nil {
+			return err
+		}
+		if err = db.dropEnrichedView(tableName); err != nil {
+			return err
+		}
+	}
+
+	db.Log.Info("msg", "Archiving tables", "value", suffix)
+	db.stmts.invalidateAll()
+	db.partitions = nil
+
+	// Begin tx
+	tx, err := db.DB.Begin()
+	if err != nil {
+		db.Log.Info("msg", "Error beginning transaction", "err", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	// Rename database tables
+	for _, tableName := range existing {
+		newTableName := fmt.Sprintf("%s_%s", tableName, suffix)
+		query := clean(db.DBAdapter.RenameTableQuery(tableName, newTableName))
+
+		db.Log.Info("msg", "RENAME TABLE", "query", query)
+		if _, err = tx.Exec(query); err != nil {
+			db.Log.Info("msg", "error renaming tables", "err", err, "value", tableName, "query", query)
+			return err
+		}
+
+		if err = db.renameIndexes(tx, tableName, newTableName, indexColumns[tableName]); err != nil {
+			return err
+		}
+
+		if err = db.renamePartitions(tx, newTableName, partitions[tableName]); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		db.Log.Info("msg", "Error on commit", "err", err)
+		return err
+	}
+
+	// Create new system tables
+	sysTables := db.getSysTablesDefinition()
+	for _, tableName := range sysTableNames {
+		if err = db.createTable(sysTables[tableName], string(types.ActionInitialize)); err != nil {
+			db.Log.Info("msg", "Error creating system table", "err", err, "value", tableName)
+			return err
+		}
+	}
+
+	// Insert chainID
+	query := clean(cleanQueries.InsertChainIDQry)
+	if _, err = db.DB.Exec(query, chainID, burrowVersion); err != nil {
+		db.Log.Info("msg", "Error inserting CHAIN ID", "err", err, "query", query)
+		return err
+	}
+
+	return nil
+}
+
+// getEventTableNames returns event table names stored in the dictionary
+func (db *SQLDB) getEventTableNames(selectDictionaryQry string) ([]string, error) {
+	tables := make([]string, 0)
+
+	query := clean(selectDictionaryQry)
+	rows, err := db.DB.Query(query)
+	if err != nil {
+		db.Log.Info("msg", "error querying dictionary", "err", err, "query", query)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tableName string
+
+		if err = rows.Scan(&tableName); err != nil {
+			db.Log.Info("msg", "error scanning table structure", "err", err)
+			return nil, err
+		}
+		tables = append(tables, tableName)
+	}
+
+	if err = rows.Err(); err != nil {
+		db.Log.Info("msg", "error scanning table structure", "err", err)
+		return nil, err
+	}
+
+	return tables, nil
+}
+
+// NamespacedSchema returns the schema holding the tables of a chain with chain ID policy namespace
+func NamespacedSchema(schema, chainID string) string {
+	return fmt.Sprintf("%s_%s", schema, chainIDSuffix(chainID))
+}
+
+// chainIDSuffix returns a chain ID usable as table or schema name suffix
+func chainIDSuffix(chainID string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '_'
+		}
+	}, chainID)
+}
@@ -0,0 +1,174 @@
+package sqldb
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// CompactLog compacts upserts & deletes logged up to a given height down to one checkpoint per block,
+// table structure changes are kept, compacted entries are archived to a json lines file if archiveDir is given
+func (db *SQLDB) CompactLog(height uint64, archiveDir string) error {
+	compacted, err := db.compactedHeight()
+	if err != nil {
+		return err
+	}
+
+	if height <= compacted {
+		return nil
+	}
+
+	db.Log.Info("msg", "COMPACTING LOG..................................", "value", height)
+
+	// Begin tx
+	tx, err := db.DB.Begin()
+	if err != nil {
+		db.Log.Info("msg", "Error beginning transaction", "err", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if archiveDir != "" {
+		path := filepath.Join(archiveDir, fmt.Sprintf("%s_%d_%d.jsonl", types.SQLLogTableName, compacted+1, height))
+		if err = db.archiveLog(tx, height, path); err != nil {
+			return err
+		}
+	}
+
+	// Keep a checkpoint for each block
+	query := clean(db.DBAdapter.CheckpointLogQuery())
+	db.Log.Info("msg", "CHECKPOINT LOG", "query", query, "value", height)
+	if _, err = tx.Exec(query, int64(height)); err != nil {
+		db.Log.Info("msg", "Error storing log checkpoints", "err", err)
+		return err
+	}
+
+	// Delete row data
+	query = clean(db.DBAdapter.DeleteLogUpToHeightQuery())
+	db.Log.Info("msg", "DELETE LOG", "query", query, "value", height)
+	if _, err = tx.Exec(query, int64(height)); err != nil {
+		db.Log.Info("msg", "Error deleting log", "err", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		db.Log.Info("msg", "Error on commit", "err", err)
+		return err
+	}
+
+	return nil
+}
+
+// archiveLog writes upserts & deletes logged up to a given height to a json lines file,
+// the file is only created if there are entries to archive
+func (db *SQLDB) archiveLog(tx *sql.Tx, height uint64, path string) error {
+	query := clean(db.DBAdapter.SelectLogUpToHeightQuery())
+	db.Log.Info("msg", "ARCHIVE LOG", "query", query, "value", path)
+
+	rows, err := tx.Query(query, int64(height))
+	if err != nil {
+		db.Log.Info("msg", "Error querying log", "err", err)
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		db.Log.Info("msg", "Error getting row columns", "err", err)
+		return err
+	}
+
+	containers := make([]sql.NullString, len(cols))
+	pointers := make([]interface{}, len(cols))
+	for i := range pointers {
+		pointers[i] = &containers[i]
+	}
+
+	var file *os.File
+	var writer *bufio.Writer
+	var encoder *json.Encoder
+
+	for rows.Next() {
+		if err = rows.Scan(pointers...); err != nil {
+			db.Log.Info("msg", "Error scanning log", "err", err)
+			return err
+		}
+
+		if file == nil {
+			if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if file, err = os.Create(path); err != nil {
+				db.Log.Info("msg", "Error creating log archive", "err", err, "value", path)
+				return err
+			}
+			defer file.Close()
+
+			writer = bufio.NewWriter(file)
+			encoder = json.NewEncoder(writer)
+		}
+
+		entry := make(map[string]interface{}, len(cols))
+		for i, col := range cols {
+			switch {
+			case !containers[i].Valid:
+				entry[col] = nil
+			case json.Valid([]byte(containers[i].String)):
+				entry[col] = json.RawMessage(containers[i].String)
+			default:
+				entry[col] = containers[i].String
+			}
+		}
+
+		if err = encoder.Encode(entry); err != nil {
+			db.Log.Info("msg", "Error writing log archive", "err", err, "value", path)
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		db.Log.Info("msg", "Error during rows iteration", "err", err)
+		return err
+	}
+
+	if file == nil {
+		return nil
+	}
+
+	if err = writer.Flush(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// compactedHeight returns the highest height compacted in the log table (0 if none)
+func (db *SQLDB) compactedHeight() (uint64, error) {
+	var height uint64
+
+	query := clean(db.DBAdapter.CompactedHeightQuery())
+	if err := db.DB.QueryRow(query).Scan(&height); err != nil {
+		db.Log.Info("msg", "Error selecting compacted height", "err", err, "query", query)
+		return 0, err
+	}
+
+	return height, nil
+}
+
+// requireLogDetail returns an error if log entries after a given height have been compacted
+func (db *SQLDB) requireLogDetail(height uint64) error {
+	compacted, err := db.compactedHeight()
+	if err != nil {
+		return err
+	}
+
+	if height < compacted {
+		return fmt.Errorf("error height %d has been compacted, the log keeps full detail after height %d", height, compacted)
+	}
+
+	return nil
+}
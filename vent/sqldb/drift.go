@@ -0,0 +1,285 @@
+package sqldb
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// SchemaDriftKind identifies the kind of difference between the dictionary & the actual table structure
+type SchemaDriftKind string
+
+const (
+	SchemaDriftMissingTable     SchemaDriftKind = "missing table"
+	SchemaDriftMissingColumn    SchemaDriftKind = "missing column"
+	SchemaDriftUnexpectedColumn SchemaDriftKind = "unexpected column"
+	SchemaDriftColumnType       SchemaDriftKind = "column type"
+)
+
+// SchemaDrift describes a single difference between the dictionary & the actual structure of a table,
+// Expected & Found hold column types (if any)
+type SchemaDrift struct {
+	Kind     SchemaDriftKind
+	Table    string
+	Column   string
+	Expected string
+	Found    string
+}
+
+// String returns a readable description of the drift
+func (drift SchemaDrift) String() string {
+	switch drift.Kind {
+	case SchemaDriftMissingTable:
+		return fmt.Sprintf("%s %s", drift.Kind, drift.Table)
+	case SchemaDriftColumnType:
+		return fmt.Sprintf("%s of %s.%s is %s, expected %s", drift.Kind, drift.Table, drift.Column, drift.Found, drift.Expected)
+	default:
+		return fmt.Sprintf("%s %s.%s", drift.Kind, drift.Table, drift.Column)
+	}
+}
+
+// driftError returns a SQL error along with the schema drift found (if any),
+// synchronizing cannot fix tables altered behind the dictionary's back
+func (db *SQLDB) driftError(err error) error {
+	drifts, errCheck := db.CheckSchema()
+	if errCheck != nil || len(drifts) == 0 {
+		return err
+	}
+
+	descriptions := make([]string, len(drifts))
+	for i, drift := range drifts {
+		descriptions[i] = drift.String()
+	}
+
+	return fmt.Errorf("%v, schema drift found: %s", err, strings.Join(descriptions, "; "))
+}
+
+// catalogColumn is a column as reported by the database catalog
+type catalogColumn struct {
+	name     string
+	typeName string
+	length   sql.NullInt64
+}
+
+// CheckSchema compares every table stored in the dictionary with the database catalog
+// and returns the differences found
+func (db *SQLDB) CheckSchema() ([]SchemaDrift, error) {
+	db.Log.Info("msg", "Checking schema")
+
+	tableNames, err := db.getEventTableNames(db.DBAdapter.CleanDBQueries().SelectDictionaryQry)
+	if err != nil {
+		return nil, err
+	}
+
+	drifts := make([]SchemaDrift, 0)
+
+	for _, tableName := range append(append([]string{}, sysTableNames...), tableNames...) {
+		table, err := db.getTableDef(tableName)
+		if err != nil {
+			return nil, err
+		}
+
+		columns, err := db.getCatalogColumns(tableName)
+		if err != nil {
+			return nil, err
+		}
+
+		drifts = append(drifts, db.diffCatalog(table, columns)...)
+	}
+
+	for _, drift := range drifts {
+		db.Log.Warn("msg", "Schema drift", "value", drift.String())
+	}
+
+	return drifts, nil
+}
+
+// RepairSchema brings tables back to the structure stored in the dictionary,
+// unexpected columns are only dropped if destructive changes are allowed,
+// returns the drift left after repairing
+func (db *SQLDB) RepairSchema() ([]SchemaDrift, error) {
+	drifts, err := db.CheckSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	tableNames := make([]string, 0)
+	tableDrifts := make(map[string][]SchemaDrift)
+	for _, drift := range drifts {
+		if _, ok := tableDrifts[drift.Table]; !ok {
+			tableNames = append(tableNames, drift.Table)
+		}
+		tableDrifts[drift.Table] = append(tableDrifts[drift.Table], drift)
+	}
+
+	for _, tableName := range tableNames {
+		if err = db.repairTable(tableName, tableDrifts[tableName]); err != nil {
+			return nil, err
+		}
+	}
+
+	return db.CheckSchema()
+}
+
+// repairTable executes the DDL needed to fix the drift of a table, dictionary is left untouched
+func (db *SQLDB) repairTable(tableName string, drifts []SchemaDrift) error {
+	db.Log.Info("msg", "Repairing table", "value", tableName)
+
+	table, err := db.getTableDef(tableName)
+	if err != nil {
+		return err
+	}
+
+	eventTable := true
+	for _, sysTableName := range sysTableNames {
+		if tableName == sysTableName {
+			eventTable = false
+		}
+	}
+
+	// views depend on the table structure
+	if eventTable {
+		if err = db.dropHistoryView(tableName); err != nil {
+			return err
+		}
+		if err = db.dropEnrichedView(tableName); err != nil {
+			return err
+		}
+	}
+
+	// missing columns go first as adapters may rebuild tables from the whole structure
+	queries := make([]string, 0)
+	for _, kind := range []SchemaDriftKind{SchemaDriftMissingTable, SchemaDriftMissingColumn, SchemaDriftUnexpectedColumn, SchemaDriftColumnType} {
+		for _, drift := range drifts {
+			if drift.Kind != kind {
+				continue
+			}
+
+			var query string
+			column := table.Columns[drift.Column]
+
+			switch kind {
+			case SchemaDriftMissingTable:
+				query, _ = db.DBAdapter.CreateTableQuery(tableName, sortColumns(table.Columns))
+
+			case SchemaDriftMissingColumn:
+				query, _ = db.DBAdapter.AlterColumnQuery(tableName, column.Name, column.Type, column.Length, column.Order)
+
+			case SchemaDriftUnexpectedColumn:
+				if !db.AllowDestructive {
+					db.Log.Warn("msg", "Destructive change not allowed", "value", drift.String())
+					continue
+				}
+				query, _ = db.DBAdapter.DropColumnQuery(tableName, sortColumns(table.Columns), drift.Column)
+
+			case SchemaDriftColumnType:
+				query, _ = db.DBAdapter.AlterColumnTypeQuery(tableName, sortColumns(table.Columns), column)
+			}
+
+			queries = append(queries, clean(query))
+		}
+	}
+
+	for _, query := range queries {
+		db.Log.Info("msg", "REPAIR TABLE", "query", query)
+		if _, err = db.DB.Exec(query); err != nil {
+			db.Log.Info("msg", "Error repairing table", "err", err, "value", tableName)
+			return err
+		}
+	}
+
+	// cached statements may not match the table structure anymore
+	db.stmts.invalidate(tableName)
+
+	if eventTable {
+		if err = db.createHistoryView(tableName); err != nil {
+			return err
+		}
+		return db.createEnrichedView(tableName)
+	}
+	return nil
+}
+
+// getCatalogColumns returns the columns of a table as reported by the database catalog,
+// no columns are returned if the table does not exist
+func (db *SQLDB) getCatalogColumns(tableName string) ([]catalogColumn, error) {
+	queryVal := db.DBAdapter.TableColumnsQuery(tableName)
+	query := clean(queryVal.Query)
+
+	db.Log.Info("msg", "QUERY CATALOG", "query", query, "value", queryVal.Values)
+	rows, err := db.DB.Query(query, queryVal.Pointers...)
+	if err != nil {
+		db.Log.Info("msg", "Error querying catalog", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make([]catalogColumn, 0)
+	for rows.Next() {
+		var column catalogColumn
+
+		if err = rows.Scan(&column.name, &column.typeName, &column.length); err != nil {
+			db.Log.Info("msg", "Error scanning catalog", "err", err)
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+
+	if err = rows.Err(); err != nil {
+		db.Log.Info("msg", "Error during rows iteration", "err", err)
+		return nil, err
+	}
+
+	return columns, nil
+}
+
+// diffCatalog compares a table structure stored in the dictionary with its catalog columns,
+// lengths are only compared for columns with a length in the dictionary
+func (db *SQLDB) diffCatalog(table types.SQLTable, columns []catalogColumn) []SchemaDrift {
+	drifts := make([]SchemaDrift, 0)
+
+	if len(columns) == 0 {
+		return append(drifts, SchemaDrift{Kind: SchemaDriftMissingTable, Table: table.Name})
+	}
+
+	found := make(map[string]catalogColumn)
+	for _, column := range columns {
+		found[column.name] = column
+	}
+
+	for _, column := range sortColumns(table.Columns) {
+		catalog, ok := found[column.Name]
+		if !ok {
+			drifts = append(drifts, SchemaDrift{Kind: SchemaDriftMissingColumn, Table: table.Name, Column: column.Name})
+			continue
+		}
+
+		expected := db.DBAdapter.CatalogColumnType(column.Type)
+		if keyAdapter, ok := db.DBAdapter.(adapters.KeyColumnAdapter); ok && column.Primary {
+			expected = keyAdapter.KeyCatalogColumnType(column.Type)
+		}
+		if !strings.EqualFold(expected, catalog.typeName) ||
+			(column.Length > 0 && (!catalog.length.Valid || catalog.length.Int64 != int64(column.Length))) {
+
+			drift := SchemaDrift{Kind: SchemaDriftColumnType, Table: table.Name, Column: column.Name, Expected: expected, Found: catalog.typeName}
+			if column.Length > 0 {
+				drift.Expected = fmt.Sprintf("%s(%d)", expected, column.Length)
+			}
+			if catalog.length.Valid && column.Length > 0 {
+				drift.Found = fmt.Sprintf("%s(%d)", catalog.typeName, catalog.length.Int64)
+			}
+			drifts = append(drifts, drift)
+		}
+	}
+
+	for _, column := range columns {
+		if _, ok := table.Columns[column.name]; !ok {
+			drifts = append(drifts, SchemaDrift{Kind: SchemaDriftUnexpectedColumn, Table: table.Name, Column: column.name})
+		}
+	}
+
+	return drifts
+}
@@ -0,0 +1,59 @@
+package sqldb
+
+import (
+	"strings"
+
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// createEnrichedView (re)creates the view joining a table with block & tx data,
+// skipped for system tables, tables without height & tx hash columns or if block & tx tables don't exist
+func (db *SQLDB) createEnrichedView(tableName string) error {
+	if strings.HasPrefix(tableName, types.SQLSysTablePrefix) {
+		return nil
+	}
+
+	table, err := db.getTableDef(tableName)
+	if err != nil {
+		return err
+	}
+
+	for _, columnName := range []string{types.SQLColumnLabelHeight, types.SQLColumnLabelTxHash} {
+		if _, ok := table.Columns[columnName]; !ok {
+			return nil
+		}
+	}
+
+	for _, joinedTable := range []string{types.SQLBlockTableName, types.SQLTxTableName} {
+		found, err := db.findTable(joinedTable)
+		if err != nil || !found {
+			return err
+		}
+	}
+
+	if err = db.dropEnrichedView(tableName); err != nil {
+		return err
+	}
+
+	query := clean(db.DBAdapter.CreateEnrichedViewQuery(tableName))
+	db.Log.Info("msg", "CREATE VIEW", "query", query)
+	if _, err = db.DB.Exec(query); err != nil {
+		db.Log.Info("msg", "Error creating enriched view", "err", err, "value", tableName)
+		return err
+	}
+
+	return nil
+}
+
+// dropEnrichedView drops the view joining a table with block & tx data if it exists
+func (db *SQLDB) dropEnrichedView(tableName string) error {
+	query := clean(db.DBAdapter.DropViewQuery(adapters.EnrichedViewName(tableName)))
+	db.Log.Info("msg", "DROP VIEW", "query", query)
+	if _, err := db.DB.Exec(query); err != nil {
+		db.Log.Info("msg", "Error dropping enriched view", "err", err, "value", tableName)
+		return err
+	}
+
+	return nil
+}
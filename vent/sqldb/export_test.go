@@ -0,0 +1,11 @@
+// +build integration
+
+package sqldb
+
+// CachedStmts returns the number of prepared statements cached for a table
+func (db *SQLDB) CachedStmts(tableName string) int {
+	db.stmts.Lock()
+	defer db.stmts.Unlock()
+
+	return len(db.stmts.tables[tableName])
+}
@@ -0,0 +1,118 @@
+package sqldb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// createFullText creates the structures needed to search the full-text columns of a table if the adapter supports it,
+// full-text search is optional so databases missing the feature are only warned about
+func (db *SQLDB) createFullText(table types.SQLTable) error {
+	columnNames := make([]string, 0)
+	for _, column := range sortColumns(table.Columns) {
+		if column.FullText {
+			columnNames = append(columnNames, column.Name)
+		}
+	}
+
+	if len(columnNames) == 0 {
+		return nil
+	}
+
+	fullTextAdapter, ok := db.DBAdapter.(adapters.FullTextAdapter)
+	if !ok {
+		db.Log.Warn("msg", "Full-text search not supported by adapter", "value", table.Name)
+		return nil
+	}
+
+	// index tables are recreated (& rebuilt) with the full-text columns
+	if tableAdapter, ok := fullTextAdapter.(adapters.FullTextTableAdapter); ok {
+		if err := db.dropChangedFullText(tableAdapter, table.Name, columnNames); err != nil {
+			return err
+		}
+	}
+
+	query := clean(fullTextAdapter.FullTextQuery(table.Name, columnNames))
+	db.Log.Info("msg", "CREATE FULL-TEXT", "query", query)
+	if _, err := db.DB.Exec(query); err != nil {
+		if db.DBAdapter.ErrorEquals(err, types.SQLErrorTypeUnsupported) {
+			db.Log.Warn("msg", "Full-text search not supported by database", "err", err, "value", table.Name)
+			return nil
+		}
+		db.Log.Info("msg", "Error creating full-text search", "err", err, "value", table.Name)
+		return err
+	}
+
+	return nil
+}
+
+// dropChangedFullText drops the index table of a table if it does not index the given full-text columns
+func (db *SQLDB) dropChangedFullText(tableAdapter adapters.FullTextTableAdapter, tableName string, columnNames []string) error {
+	columns, err := db.getCatalogColumns(adapters.FullTextTableName(tableName))
+	if err != nil {
+		return err
+	}
+
+	indexed := make([]string, len(columns))
+	for i, column := range columns {
+		indexed[i] = column.name
+	}
+
+	if len(columns) == 0 || strings.Join(indexed, "\x00") == strings.Join(columnNames, "\x00") {
+		return nil
+	}
+
+	query := clean(tableAdapter.DropFullTextQuery(tableName))
+	db.Log.Info("msg", "DROP FULL-TEXT", "query", query)
+	if _, err = db.DB.Exec(query); err != nil {
+		db.Log.Info("msg", "Error dropping full-text search", "err", err, "value", tableName)
+		return err
+	}
+
+	return nil
+}
+
+// getFullTextColumns returns the names of the columns that may hold the searchable form of text columns of a table,
+// full-text declarations are not stored in the dictionary
+func (db *SQLDB) getFullTextColumns(tableName string) ([]string, error) {
+	table, err := db.getTableDef(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	columnNames := make([]string, 0)
+	for _, column := range sortColumns(table.Columns) {
+		if column.Type == types.SQLColumnTypeText {
+			columnNames = append(columnNames, adapters.FullTextColumnName(column.Name))
+		}
+	}
+
+	return columnNames, nil
+}
+
+// Search returns up to limit rows of a table matching every word of a text in a full-text column, best matches first
+func (db *SQLDB) Search(tableName, columnName, text string, limit int) (types.EventDataTable, error) {
+	fullTextAdapter, ok := db.DBAdapter.(adapters.FullTextAdapter)
+	if !ok {
+		return nil, fmt.Errorf("error full-text search of table %s not supported by adapter", tableName)
+	}
+
+	table, err := db.getTableDef(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	// words are separated by single spaces
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return nil, nil
+	}
+
+	query := clean(fullTextAdapter.SearchQuery(table, columnName))
+	db.Log.Info("msg", "Search table", "query", query, "value", text)
+
+	return db.selectRows(table, query, text, limit)
+}
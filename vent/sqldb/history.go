@@ -0,0 +1,102 @@
+package sqldb
+
+import (
+	"fmt"
+
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// GetTableAsOf returns the rows of a table as they were at a given height,
+// rows are read from the table history view (current rows & before-images stored in the log table)
+func (db *SQLDB) GetTableAsOf(tableName string, height uint64) (types.EventDataTable, error) {
+	if err := db.requireLogDetail(height); err != nil {
+		return nil, err
+	}
+
+	table, err := db.getTableDef(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = requirePrimaryKey(table, "as of height queries"); err != nil {
+		return nil, err
+	}
+
+	query := clean(db.DBAdapter.SelectAsOfQuery(table))
+	db.Log.Info("msg", "Query table as of height", "query", query, "value", height)
+
+	rows, err := db.selectRows(table, query, int64(height), int64(height))
+	if err != nil {
+		return nil, err
+	}
+
+	if decoder, ok := db.DBAdapter.(adapters.ImageBytesDecoder); ok {
+		if err = decodeImageBytes(decoder, table, rows); err != nil {
+			return nil, err
+		}
+	}
+
+	return rows, nil
+}
+
+// decodeImageBytes decodes byte columns of rows read from before-images (_valid_to_height not null)
+// and removes _valid_to_height from every row
+func decodeImageBytes(decoder adapters.ImageBytesDecoder, table types.SQLTable, rows []types.EventDataRow) error {
+	for _, row := range rows {
+		validTo := row.RowData[types.SQLColumnLabelValidToHeight]
+		delete(row.RowData, types.SQLColumnLabelValidToHeight)
+		if validTo == nil {
+			continue
+		}
+
+		for _, column := range table.Columns {
+			if value, ok := row.RowData[column.Name].([]byte); ok && column.Type == types.SQLColumnTypeByteA {
+				b, err := decoder.DecodeImageBytes(value)
+				if err != nil {
+					return fmt.Errorf("error decoding column %s: %v", column.Name, err)
+				}
+				row.RowData[column.Name] = b
+			}
+		}
+	}
+
+	return nil
+}
+
+// createHistoryView (re)creates the history view of a table, tables without height column are skipped
+func (db *SQLDB) createHistoryView(tableName string) error {
+	table, err := db.getTableDef(tableName)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := table.Columns[types.SQLColumnLabelHeight]; !ok {
+		return nil
+	}
+
+	if err = db.dropHistoryView(tableName); err != nil {
+		return err
+	}
+
+	query := clean(db.DBAdapter.CreateHistoryViewQuery(table))
+	db.Log.Info("msg", "CREATE VIEW", "query", query)
+	if _, err = db.DB.Exec(query); err != nil {
+		db.Log.Info("msg", "Error creating history view", "err", err, "value", tableName)
+		return err
+	}
+
+	return nil
+}
+
+// dropHistoryView drops the history view of a table if it exists
+func (db *SQLDB) dropHistoryView(tableName string) error {
+	query := clean(db.DBAdapter.DropViewQuery(adapters.HistoryViewName(tableName)))
+	db.Log.Info("msg", "DROP VIEW", "query", query)
+	if _, err := db.DB.Exec(query); err != nil {
+		db.Log.Info("msg", "Error dropping history view", "err", err, "value", tableName)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package sqldb
+
+import (
+	"database/sql"
+
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// createIndexes indexes the JSON columns of a table declaring an index method,
+// existing indexes are kept & declarations are skipped if the adapter can't index JSON columns
+func (db *SQLDB) createIndexes(table types.SQLTable) error {
+	indexAdapter, ok := db.DBAdapter.(adapters.IndexAdapter)
+
+	for _, column := range sortColumns(table.Columns) {
+		if column.Index == "" {
+			continue
+		}
+
+		if !ok {
+			db.Log.Warn("msg", "Index not supported by adapter", "value", column.Name)
+			continue
+		}
+
+		query, err := indexAdapter.CreateIndexQuery(table.Name, column.Name, column.Index)
+		if err != nil {
+			db.Log.Info("msg", "Error building index query", "err", err, "value", column.Name)
+			return err
+		}
+
+		query = clean(query)
+		db.Log.Info("msg", "CREATE INDEX", "query", query)
+		if _, err = db.DB.Exec(query); err != nil {
+			db.Log.Info("msg", "Error creating index", "err", err, "value", column.Name)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// renameIndexes renames the indexes of JSON columns after renaming their table
+func (db *SQLDB) renameIndexes(tx *sql.Tx, tableName, newTableName string, columnNames []string) error {
+	indexAdapter, ok := db.DBAdapter.(adapters.IndexAdapter)
+	if !ok {
+		return nil
+	}
+
+	for _, columnName := range columnNames {
+		query := clean(indexAdapter.RenameIndexQuery(tableName, newTableName, columnName))
+		db.Log.Info("msg", "RENAME INDEX", "query", query)
+		if _, err := tx.Exec(query); err != nil {
+			db.Log.Info("msg", "Error renaming index", "err", err, "value", columnName)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getJSONColumns returns the names of the JSON columns of a table
+func (db *SQLDB) getJSONColumns(tableName string) ([]string, error) {
+	table, err := db.getTableDef(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	columnNames := make([]string, 0)
+	for _, column := range sortColumns(table.Columns) {
+		if column.Type.IsJSON() {
+			columnNames = append(columnNames, column.Name)
+		}
+	}
+
+	return columnNames, nil
+}
+
+// addJSONPaths adds the columns generated from the JSON paths of a table if the adapter supports them,
+// existing columns are kept, JSON paths are optional so databases unable to generate columns are only warned about
+// (other errors are returned)
+func (db *SQLDB) addJSONPaths(table types.SQLTable) error {
+	jsonPathAdapter, ok := db.DBAdapter.(adapters.JSONPathAdapter)
+	if !ok {
+		return nil
+	}
+
+	for _, jsonPath := range table.JSONPaths {
+		query := clean(jsonPathAdapter.AddJSONPathQuery(table.Name, jsonPath))
+		db.Log.Info("msg", "ADD JSON PATH", "query", query)
+		if _, err := db.DB.Exec(query); err != nil {
+			if db.DBAdapter.ErrorEquals(err, types.SQLErrorTypeDuplicatedColumn) {
+				continue
+			}
+			if db.DBAdapter.ErrorEquals(err, types.SQLErrorTypeUnsupported) {
+				db.Log.Warn("msg", "JSON path columns not supported by database", "err", err, "value", table.Name)
+				return nil
+			}
+			db.Log.Info("msg", "Error adding JSON path column", "err", err, "value", jsonPath.Name)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package sqldb
+
+import (
+	"fmt"
+
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
+)
+
+// Checkpoint copies the write-ahead log to the database & truncates it if the adapter keeps one,
+// a checkpoint blocked by readers or writers is only warned about as the next one catches up
+func (db *SQLDB) Checkpoint() error {
+	checkpointAdapter, ok := db.DBAdapter.(adapters.CheckpointAdapter)
+	if !ok {
+		return nil
+	}
+
+	query := clean(checkpointAdapter.CheckpointQuery())
+	db.Log.Info("msg", "CHECKPOINT", "query", query)
+
+	var busy, logPages, checkpointed int
+	if err := db.DB.QueryRow(query).Scan(&busy, &logPages, &checkpointed); err != nil {
+		db.Log.Info("msg", "Error checkpointing database", "err", err)
+		return err
+	}
+
+	if busy != 0 {
+		db.Log.Warn("msg", "Checkpoint blocked by other connections", "value", checkpointed)
+	}
+
+	return nil
+}
+
+// Backup copies the database to a destination while it keeps being written
+func (db *SQLDB) Backup(destination string) error {
+	backupAdapter, ok := db.DBAdapter.(adapters.BackupAdapter)
+	if !ok {
+		return fmt.Errorf("error online backup not supported by adapter")
+	}
+
+	db.Log.Info("msg", "BACKUP", "value", destination)
+	if err := backupAdapter.Backup(destination); err != nil {
+		db.Log.Info("msg", "Error backing up database", "err", err, "value", destination)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package sqldb
+
+import (
+	"database/sql"
+	"strconv"
+
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// notifyBlock sends the block height & the number of rows of each table to the notification channel
+// within the block transaction, does nothing if no channel is set or the adapter can't notify
+func (db *SQLDB) notifyBlock(tx *sql.Tx, eventTables types.EventTables, eventData types.EventData) error {
+	notifyAdapter, ok := db.DBAdapter.(adapters.NotifyAdapter)
+	if db.NotifyChannel == "" || !ok {
+		return nil
+	}
+
+	height, err := strconv.ParseUint(eventData.Block, 10, 64)
+	if err != nil {
+		db.Log.Info("msg", "Error parsing block height", "err", err, "value", eventData.Block)
+		return err
+	}
+
+	notification := types.BlockNotification{Height: height, Tables: make(map[string]int)}
+	for _, table := range eventTables {
+		if rows := len(eventData.Tables[table.Name]); rows > 0 {
+			notification.Tables[table.Name] = rows
+		}
+	}
+
+	payload, err := db.getJSON(notification)
+	if err != nil {
+		db.Log.Info("msg", "Error marshaling notification", "err", err)
+		return err
+	}
+
+	query := notifyAdapter.NotifyQuery()
+	db.Log.Info("msg", "NOTIFY", "query", query, "value", string(payload))
+	if _, err = tx.Exec(query, db.NotifyChannel, string(payload)); err != nil {
+		db.Log.Info("msg", "Error sending notification", "err", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package sqldb
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// checkPartitioning returns an error if the partitioning of an existing table has been changed,
+// partitions key rows by height as well so existing tables can't be partitioned (or stop being partitioned)
+func (db *SQLDB) checkPartitioning(table types.SQLTable) error {
+	current, err := db.getTableDef(table.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, column := range table.Columns {
+		if column.Name != types.SQLColumnLabelHeight {
+			continue
+		}
+		if height, ok := current.Columns[column.Name]; ok && height.Primary != column.Primary {
+			return fmt.Errorf("error partitioning of table %s can't be changed once the table is created", table.Name)
+		}
+	}
+
+	return nil
+}
+
+// rollPartitions makes sure partitioned tables have partitions for a given height & the following height range,
+// tables are not partitioned if the adapter does not support it
+func (db *SQLDB) rollPartitions(eventTables types.EventTables, height string) error {
+	partitionAdapter, ok := db.DBAdapter.(adapters.PartitionAdapter)
+	if !ok {
+		return nil
+	}
+
+	for eventName, table := range eventTables {
+		if table.PartitionRange == 0 {
+			continue
+		}
+
+		blockHeight, err := strconv.ParseUint(height, 10, 64)
+		if err != nil {
+			db.Log.Info("msg", "Error parsing block height", "err", err, "value", height)
+			return err
+		}
+
+		if err = db.ensurePartitions(partitionAdapter, table, eventName, blockHeight); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensurePartitions creates the missing partitions of a table up to the height range following a given height,
+// so that tables roll over to a new partition before rows need it, partitions ensured are cached
+func (db *SQLDB) ensurePartitions(partitionAdapter adapters.PartitionAdapter, table types.SQLTable, eventName string, height uint64) error {
+	last := height/table.PartitionRange + 1
+	if ensured, ok := db.partitions[table.Name]; ok && ensured >= last {
+		return nil
+	}
+
+	partitions, err := db.getPartitions(partitionAdapter, table.Name)
+	if err != nil {
+		return err
+	}
+
+	for index := height / table.PartitionRange; index <= last; index++ {
+		from := index * table.PartitionRange
+		if _, ok := partitions[from]; ok {
+			continue
+		}
+
+		if err = db.createPartition(partitionAdapter, table, eventName, from, from+table.PartitionRange); err != nil {
+			return err
+		}
+	}
+
+	if db.partitions == nil {
+		db.partitions = make(map[string]uint64)
+	}
+	db.partitions[table.Name] = last
+
+	return nil
+}
+
+// createPartition creates the partition of a table holding a height range & logs it
+func (db *SQLDB) createPartition(partitionAdapter adapters.PartitionAdapter, table types.SQLTable, eventName string, from, to uint64) error {
+	logQuery := clean(db.DBAdapter.InsertLogQuery())
+	query := clean(partitionAdapter.CreatePartitionQuery(table.Name, from, to))
+
+	db.Log.Info("msg", "CREATE PARTITION", "query", query)
+	if _, err := db.DB.Exec(query); err != nil {
+		db.Log.Info("msg", "Error creating partition", "err", err, "value", table.Name)
+		return fmt.Errorf("error creating partition of table %s (only new tables can be partitioned): %v", table.Name, err)
+	}
+
+	jsonData, err := db.getJSON(map[string]interface{}{
+		"Name": partitionAdapter.PartitionName(table.Name, from),
+		"From": from,
+		"To":   to,
+	})
+	if err != nil {
+		db.Log.Info("msg", "error marshaling partition", "err", err, "value", table.Name)
+		return err
+	}
+	sqlValues, _ := db.getJSON(nil)
+
+	if _, err = db.DB.Exec(logQuery, table.Name, eventName, table.Filter, nil, nil, types.ActionPartition, jsonData, query, sqlValues, nil); err != nil {
+		db.Log.Info("msg", "Error inserting log", "err", err)
+		return err
+	}
+
+	return nil
+}
+
+// getPartitions returns the names of the partitions of a table mapped by their lower height bound
+func (db *SQLDB) getPartitions(partitionAdapter adapters.PartitionAdapter, tableName string) (map[uint64]string, error) {
+	queryVal := partitionAdapter.SelectPartitionsQuery(tableName)
+	query := clean(queryVal.Query)
+
+	db.Log.Info("msg", "SELECT PARTITIONS", "query", query, "value", queryVal.Values)
+	rows, err := db.DB.Query(query, queryVal.Pointers...)
+	if err != nil {
+		db.Log.Info("msg", "Error querying partitions", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	partitions := make(map[uint64]string)
+	for rows.Next() {
+		var name string
+		var from uint64
+
+		if err = rows.Scan(&name, &from); err != nil {
+			db.Log.Info("msg", "Error scanning partitions", "err", err)
+			return nil, err
+		}
+		partitions[from] = name
+	}
+
+	if err = rows.Err(); err != nil {
+		db.Log.Info("msg", "Error during rows iteration", "err", err)
+		return nil, err
+	}
+
+	return partitions, nil
+}
+
+// renamePartitions renames the partitions of a table after renaming the table,
+// so that partitions can be created again for a new table with the previous name
+func (db *SQLDB) renamePartitions(tx *sql.Tx, newTableName string, partitions map[uint64]string) error {
+	partitionAdapter, ok := db.DBAdapter.(adapters.PartitionAdapter)
+	if !ok {
+		return nil
+	}
+
+	for from, name := range partitions {
+		query := clean(db.DBAdapter.RenameTableQuery(name, partitionAdapter.PartitionName(newTableName, from)))
+		db.Log.Info("msg", "RENAME PARTITION", "query", query)
+		if _, err := tx.Exec(query); err != nil {
+			db.Log.Info("msg", "Error renaming partition", "err", err, "value", name)
+			return err
+		}
+	}
+
+	return nil
+}
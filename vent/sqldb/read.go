@@ -0,0 +1,95 @@
+package sqldb
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// timeStampLayouts are the layouts of timestamps returned as text (SQLite views, MySQL without parseTime)
+var timeStampLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	time.RFC3339Nano,
+}
+
+// readValue converts a value read from a column to the Go type of the column type stored in the dictionary:
+// bool, int64 (integers), *big.Int (numerics), string (text), []byte, json.RawMessage & time.Time, nil for NULL,
+// drivers return the same value in different ways (e.g. booleans as integers or numerics as text)
+func readValue(sqlColumnType types.SQLColumnType, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch sqlColumnType {
+	case types.SQLColumnTypeBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case int64:
+			return v != 0, nil
+		}
+		return strconv.ParseBool(readText(value))
+
+	case types.SQLColumnTypeInt, types.SQLColumnTypeSerial, types.SQLColumnTypeBigInt:
+		if v, ok := value.(int64); ok {
+			return v, nil
+		}
+		return strconv.ParseInt(readText(value), 10, 64)
+
+	case types.SQLColumnTypeNumeric:
+		if v, ok := value.(int64); ok {
+			return big.NewInt(v), nil
+		}
+		number, ok := new(big.Int).SetString(readText(value), 10)
+		if !ok {
+			return nil, fmt.Errorf("error reading numeric value %v", value)
+		}
+		return number, nil
+
+	case types.SQLColumnTypeByteA:
+		if v, ok := value.([]byte); ok {
+			return v, nil
+		}
+		return []byte(readText(value)), nil
+
+	case types.SQLColumnTypeJSON, types.SQLColumnTypeJSONB:
+		if v, ok := value.([]byte); ok {
+			return json.RawMessage(v), nil
+		}
+		return json.RawMessage(readText(value)), nil
+
+	case types.SQLColumnTypeTimeStamp:
+		if v, ok := value.(time.Time); ok {
+			return v, nil
+		}
+		text := readText(value)
+		for _, layout := range timeStampLayouts {
+			if t, err := time.Parse(layout, text); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("error reading timestamp value %s", text)
+
+	default:
+		return readText(value), nil
+	}
+}
+
+// readText returns the text form of a value
+func readText(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,194 @@
+package sqldb
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// restoreProgressRows is the number of restored rows between progress reports
+const restoreProgressRows = 1000
+
+// RestoreOptions defines up to which point logged changes are restored and where
+type RestoreOptions struct {
+	// Height restores changes logged up to a block height, if zero Time is used
+	Height uint64
+	// Time restores changes logged up to a point in time (database time)
+	Time time.Time
+	// Prefix names restored tables <prefix>_<table>
+	Prefix string
+	// Target is the database (schema) where tables are restored, defaults to the source database
+	Target *SQLDB
+	// Progress is called with the number of restored rows as the restore goes on
+	Progress func(rows int)
+}
+
+// Restore recreates event tables from the dictionary in a target schema or with a prefix,
+// then replays upserts & deletes stored in the log table up to a given height or time
+func (db *SQLDB) Restore(options RestoreOptions) error {
+	target := options.Target
+	if target == nil {
+		target = db
+	}
+
+	if options.Prefix == "" && target.Schema == db.Schema {
+		return fmt.Errorf("error prefix must not be empty when restoring into the same schema")
+	}
+
+	db.Log.Info("msg", "RESTORING DB..................................")
+
+	// restore replays the log from the first block
+	compacted, err := db.compactedHeight()
+	if err != nil {
+		return err
+	}
+	if compacted > 0 {
+		return fmt.Errorf("error log has been compacted up to height %d, restore needs full detail from the first block", compacted)
+	}
+
+	// Create tables
+	tables, err := db.createRestoreTables(target, options.Prefix)
+	if err != nil {
+		return err
+	}
+
+	// Open log rows
+	var rows *sql.Rows
+	if options.Height > 0 {
+		query := clean(db.DBAdapter.RestoreHeightQuery())
+		db.Log.Info("msg", "open log", "query", query, "value", options.Height)
+		rows, err = db.DB.Query(query, int64(options.Height))
+	} else {
+		query := clean(db.DBAdapter.RestoreDBQuery())
+		db.Log.Info("msg", "open log", "query", query, "value", options.Time)
+		rows, err = db.DB.Query(query, options.Time.Format("2006-01-02 15:04:05"))
+	}
+	if err != nil {
+		db.Log.Info("msg", "error querying log", "err", err)
+		return err
+	}
+	defer rows.Close()
+
+	// Begin tx
+	tx, err := target.DB.Begin()
+	if err != nil {
+		db.Log.Info("msg", "Error beginning transaction", "err", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	restored := 0
+
+	for rows.Next() {
+		var tableName string
+		var action types.DBAction
+		var dataRow []byte
+
+		if err = rows.Scan(&tableName, &action, &dataRow); err != nil {
+			db.Log.Info("msg", "error scanning log", "err", err)
+			return err
+		}
+
+		table, ok := tables[tableName]
+		if !ok {
+			return fmt.Errorf("error table %s not found in dictionary", tableName)
+		}
+
+		row := types.EventDataRow{Action: action}
+		if err = decodeJSON(dataRow, &row.RowData); err != nil {
+			db.Log.Info("msg", "error unmarshaling json", "err", err, "value", string(dataRow))
+			return err
+		}
+		if err = decodeLogValues(table, row.RowData); err != nil {
+			db.Log.Info("msg", "error decoding log row", "err", err, "value", string(dataRow))
+			return err
+		}
+
+		var queryVal types.UpsertDeleteQuery
+		switch action {
+		case types.ActionUpsert:
+			queryVal, _, err = target.DBAdapter.UpsertQuery(table, row)
+		case types.ActionDelete:
+			queryVal, err = target.DBAdapter.DeleteQuery(table, row)
+		}
+		if err != nil {
+			db.Log.Info("msg", "Error building upsert/delete query", "err", err, "value", tableName)
+			return err
+		}
+
+		query := clean(queryVal.Query)
+		db.Log.Info("msg", "SQL COMMAND", "sql", query, "value", queryVal.Values)
+		if _, err = tx.Exec(query, queryVal.Pointers...); err != nil {
+			db.Log.Info("msg", "Error executing upsert/delete", "err", err, "value", queryVal.Values)
+			return err
+		}
+
+		restored++
+		if options.Progress != nil && restored%restoreProgressRows == 0 {
+			options.Progress(restored)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		db.Log.Info("msg", "Error during rows iteration", "err", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		db.Log.Info("msg", "Error on commit", "err", err)
+		return err
+	}
+
+	if options.Progress != nil {
+		options.Progress(restored)
+	}
+
+	return nil
+}
+
+// createRestoreTables creates every event table stored in the dictionary in the target database,
+// returns restored table definitions mapped by source table name
+func (db *SQLDB) createRestoreTables(target *SQLDB, prefix string) (map[string]types.SQLTable, error) {
+	tables := make(map[string]types.SQLTable)
+
+	tableNames, err := db.getEventTableNames(db.DBAdapter.CleanDBQueries().SelectDictionaryQry)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tableName := range tableNames {
+		table, err := db.getTableDef(tableName)
+		if err != nil {
+			return nil, err
+		}
+
+		if prefix != "" {
+			table.Name = fmt.Sprintf("%s_%s", prefix, tableName)
+		}
+
+		if err = target.DBAdapter.ValidateTableName(table.Name); err != nil {
+			return nil, err
+		}
+
+		columns := make([]types.SQLTableColumn, 0, len(table.Columns))
+		for _, column := range table.Columns {
+			columns = append(columns, column)
+		}
+		sort.Slice(columns, func(i, j int) bool { return columns[i].Order < columns[j].Order })
+
+		query, _ := target.DBAdapter.CreateTableQuery(table.Name, columns)
+
+		db.Log.Info("msg", "CREATE TABLE", "query", clean(query))
+		if _, err = target.DB.Exec(query); err != nil {
+			db.Log.Info("msg", "Error creating restore table", "err", err, "value", table.Name)
+			return nil, err
+		}
+
+		tables[tableName] = table
+	}
+
+	return tables, nil
+}
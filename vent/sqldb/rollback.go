@@ -0,0 +1,216 @@
+package sqldb
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// logImage is a row change logged after the rollback height
+type logImage struct {
+	tableName   string
+	dataRow     map[string]interface{}
+	beforeImage []byte
+}
+
+// RollbackToHeight reverts event tables in place to their state at a given height using the
+// before-images stored in the log table, then removes later log entries (so consuming restarts
+// from the next height), table structure changes are not reverted
+func (db *SQLDB) RollbackToHeight(height uint64) error {
+	db.Log.Info("msg", "ROLLING BACK DB..................................", "value", height)
+
+	if err := db.requireLogDetail(height); err != nil {
+		return err
+	}
+
+	images, err := db.getImagesAfterHeight(height)
+	if err != nil {
+		return err
+	}
+
+	tables := make(map[string]types.SQLTable)
+	for _, image := range images {
+		if _, ok := tables[image.tableName]; !ok {
+			if tables[image.tableName], err = db.getTableDef(image.tableName); err != nil {
+				return err
+			}
+			if err = requirePrimaryKey(tables[image.tableName], "rollback"); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Begin tx
+	tx, err := db.DB.Begin()
+	if err != nil {
+		db.Log.Info("msg", "Error beginning transaction", "err", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	// only the first change of each row after height has its state at height
+	restored := make(map[string]bool)
+
+	for _, image := range images {
+		table := tables[image.tableName]
+
+		key := image.tableName + "/" + primaryKeyValue(primaryKeys(table), types.EventDataRow{RowData: image.dataRow})
+		if restored[key] {
+			continue
+		}
+		restored[key] = true
+
+		queryVal, err := db.restoreImageQuery(table, image)
+		if err != nil {
+			return err
+		}
+
+		query := clean(queryVal.Query)
+		db.Log.Info("msg", "ROLLBACK ROW", "query", query, "value", queryVal.Values)
+		if _, err = tx.Exec(query, queryVal.Pointers...); err != nil {
+			db.Log.Info("msg", "Error restoring row", "err", err, "value", queryVal.Values)
+			return err
+		}
+	}
+
+	// Reset checkpoint
+	query := clean(db.DBAdapter.DeleteLogAfterHeightQuery())
+	db.Log.Info("msg", "DELETE LOG", "query", query, "value", height)
+	if _, err = tx.Exec(query, int64(height)); err != nil {
+		db.Log.Info("msg", "Error deleting log", "err", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		db.Log.Info("msg", "Error on commit", "err", err)
+		return err
+	}
+
+	for tableName := range tables {
+		db.stmts.invalidate(tableName)
+	}
+
+	return nil
+}
+
+// getImagesAfterHeight returns upserts & deletes logged after a given height in log order
+func (db *SQLDB) getImagesAfterHeight(height uint64) ([]logImage, error) {
+	images := make([]logImage, 0)
+
+	query := clean(db.DBAdapter.SelectLogAfterHeightQuery())
+	db.Log.Info("msg", "SELECT LOG", "query", query, "value", height)
+
+	rows, err := db.DB.Query(query, int64(height))
+	if err != nil {
+		db.Log.Info("msg", "Error querying log", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var image logImage
+		var action types.DBAction
+		var dataRow []byte
+		var beforeImage sql.NullString
+
+		if err = rows.Scan(&id, &image.tableName, &action, &dataRow, &beforeImage); err != nil {
+			db.Log.Info("msg", "Error scanning log", "err", err)
+			return nil, err
+		}
+
+		if !beforeImage.Valid {
+			return nil, fmt.Errorf("error log entry %d has no before image, it was stored by a previous version", id)
+		}
+		image.beforeImage = []byte(beforeImage.String)
+
+		if err = decodeJSON(dataRow, &image.dataRow); err != nil {
+			db.Log.Info("msg", "Error unmarshaling json", "err", err, "value", string(dataRow))
+			return nil, err
+		}
+
+		images = append(images, image)
+	}
+
+	if err = rows.Err(); err != nil {
+		db.Log.Info("msg", "Error during rows iteration", "err", err)
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// restoreImageQuery returns a query that restores a row to its before-image,
+// deleting it if it did not exist
+func (db *SQLDB) restoreImageQuery(table types.SQLTable, image logImage) (types.UpsertDeleteQuery, error) {
+	var rowData map[string]interface{}
+
+	if err := decodeJSON(image.beforeImage, &rowData); err != nil {
+		db.Log.Info("msg", "Error unmarshaling before image", "err", err, "value", string(image.beforeImage))
+		return types.UpsertDeleteQuery{}, err
+	}
+
+	if rowData == nil {
+		return db.DBAdapter.DeleteQuery(table, types.EventDataRow{Action: types.ActionDelete, RowData: image.dataRow})
+	}
+
+	if err := decodeLogValues(table, rowData); err != nil {
+		db.Log.Info("msg", "Error decoding before image", "err", err, "value", string(image.beforeImage))
+		return types.UpsertDeleteQuery{}, err
+	}
+
+	// set every column so values stored after height are overwritten
+	row := types.EventDataRow{Action: types.ActionUpsert, RowData: make(map[string]interface{}, len(table.Columns))}
+	for _, column := range table.Columns {
+		row.RowData[column.Name] = rowData[column.Name]
+	}
+
+	queryVal, _, err := db.DBAdapter.UpsertQuery(table, row)
+	return queryVal, err
+}
+
+// requirePrimaryKey returns an error if a table has no primary key, rows of such tables
+// cannot be identified so their before-images are not stored
+func requirePrimaryKey(table types.SQLTable, operation string) error {
+	if len(primaryKeys(table)) == 0 {
+		return fmt.Errorf("error table %s has no primary key, %s needs primary keys to identify rows", table.Name, operation)
+	}
+	return nil
+}
+
+// decodeLogValues converts row values decoded from json stored in the log table back to query values:
+// numbers are kept as strings and byte columns (base64 in json) as []byte
+func decodeLogValues(table types.SQLTable, rowData map[string]interface{}) error {
+	columnTypes := make(map[string]types.SQLColumnType, len(table.Columns))
+	for _, column := range table.Columns {
+		columnTypes[column.Name] = column.Type
+	}
+
+	for column, value := range rowData {
+		switch v := value.(type) {
+		case json.Number:
+			rowData[column] = v.String()
+		case string:
+			if columnTypes[column] == types.SQLColumnTypeByteA {
+				b, err := base64.StdEncoding.DecodeString(v)
+				if err != nil {
+					return fmt.Errorf("error decoding column %s: %v", column, err)
+				}
+				rowData[column] = b
+			}
+		}
+	}
+
+	return nil
+}
+
+// decodeJSON unmarshals json keeping numbers as strings to avoid precision loss
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,173 @@
+package sqldb
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/monax/bosmarmot/vent/types"
+)
+
+// columnChangeType identifies the kind of change needed to synchronize a column
+type columnChangeType int
+
+const (
+	columnAdd columnChangeType = iota
+	columnRename
+	columnAlterType
+	columnDrop
+)
+
+// String returns a readable description of the change
+func (change columnChangeType) String() string {
+	switch change {
+	case columnAdd:
+		return "add"
+	case columnRename:
+		return "rename"
+	case columnAlterType:
+		return "alter type of"
+	case columnDrop:
+		return "drop"
+	default:
+		return "unknown change on"
+	}
+}
+
+// columnChange describes a single difference between the dictionary and a table specification,
+// column holds the new definition (the dropped one for drops) and current the stored one (if any)
+type columnChange struct {
+	change      columnChangeType
+	column      types.SQLTableColumn
+	current     types.SQLTableColumn
+	destructive bool
+}
+
+// diffTable computes column changes needed to move the current table structure to the new one,
+// renames declared with PreviousName are resolved before deciding if a column has to be added
+func diffTable(currentTable, newTable types.SQLTable) []columnChange {
+	changes := make([]columnChange, 0)
+	matched := make(map[string]bool)
+
+	for _, newColumn := range sortColumns(newTable.Columns) {
+		currentColumn, found := currentTable.Columns[newColumn.Name]
+
+		if !found && newColumn.PreviousName != "" && !matched[newColumn.PreviousName] {
+			if previousColumn, ok := currentTable.Columns[newColumn.PreviousName]; ok {
+				matched[previousColumn.Name] = true
+				changes = append(changes, columnChange{change: columnRename, column: newColumn, current: previousColumn})
+
+				currentColumn = previousColumn
+				currentColumn.Name = newColumn.Name
+				found = true
+			}
+		}
+
+		if !found {
+			changes = append(changes, columnChange{change: columnAdd, column: newColumn})
+			continue
+		}
+
+		matched[currentColumn.Name] = true
+
+		if currentColumn.Type != newColumn.Type || currentColumn.Length != newColumn.Length {
+			changes = append(changes, columnChange{
+				change:      columnAlterType,
+				column:      newColumn,
+				current:     currentColumn,
+				destructive: !isSafeWidening(currentColumn, newColumn),
+			})
+		}
+	}
+
+	// columns no longer in the specification
+	for _, currentColumn := range sortColumns(currentTable.Columns) {
+		if !matched[currentColumn.Name] {
+			changes = append(changes, columnChange{change: columnDrop, column: currentColumn, current: currentColumn, destructive: true})
+		}
+	}
+
+	return changes
+}
+
+// isSafeWidening determines if changing a column type can be done without losing data
+func isSafeWidening(from, to types.SQLTableColumn) bool {
+	switch from.Type {
+	case to.Type:
+		// zero length means unbounded
+		return to.Length == 0 || (from.Length != 0 && to.Length >= from.Length)
+	case types.SQLColumnTypeInt:
+		return to.Type == types.SQLColumnTypeBigInt || to.Type == types.SQLColumnTypeNumeric
+	case types.SQLColumnTypeBigInt:
+		return to.Type == types.SQLColumnTypeNumeric
+	case types.SQLColumnTypeVarchar:
+		return to.Type == types.SQLColumnTypeText || isHeightMigration(from, to)
+	case types.SQLColumnTypeJSON:
+		return to.Type == types.SQLColumnTypeJSONB
+	default:
+		return false
+	}
+}
+
+// isHeightMigration determines if a column is a height stored as text by previous versions,
+// heights are always decimal numbers so they can be converted to BIGINT in place
+func isHeightMigration(from, to types.SQLTableColumn) bool {
+	return from.Name == types.SQLColumnLabelHeight && from.Type == types.SQLColumnTypeVarchar && to.Type == types.SQLColumnTypeBigInt
+}
+
+// numericStorageChanges returns the changes needed to convert numeric columns whose catalog type is
+// not the one used by the adapter (numeric storage changed over versions), their dictionary type is kept
+func (db *SQLDB) numericStorageChanges(currentTable types.SQLTable, changes []columnChange) ([]columnChange, error) {
+	changed := make(map[string]bool)
+	for _, change := range changes {
+		changed[change.current.Name] = true
+	}
+
+	numericColumns := make([]types.SQLTableColumn, 0)
+	for _, column := range sortColumns(currentTable.Columns) {
+		if column.Type == types.SQLColumnTypeNumeric && !changed[column.Name] {
+			numericColumns = append(numericColumns, column)
+		}
+	}
+	if len(numericColumns) == 0 {
+		return nil, nil
+	}
+
+	catalogColumns, err := db.getCatalogColumns(currentTable.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	catalogTypes := make(map[string]string, len(catalogColumns))
+	for _, column := range catalogColumns {
+		catalogTypes[column.name] = column.typeName
+	}
+
+	storageChanges := make([]columnChange, 0)
+	expected := db.DBAdapter.CatalogColumnType(types.SQLColumnTypeNumeric)
+
+	for _, column := range numericColumns {
+		if catalogType, ok := catalogTypes[column.Name]; ok && !strings.EqualFold(catalogType, expected) {
+			db.Log.Info("msg", "Converting numeric column storage", "value", column.Name, "type", catalogType)
+			storageChanges = append(storageChanges, columnChange{change: columnAlterType, column: column, current: column})
+		}
+	}
+
+	return storageChanges, nil
+}
+
+// sortColumns returns table columns sorted by their order
+func sortColumns(columns map[string]types.SQLTableColumn) []types.SQLTableColumn {
+	sorted := make([]types.SQLTableColumn, 0, len(columns))
+	for _, column := range columns {
+		sorted = append(sorted, column)
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Order == sorted[j].Order {
+			return sorted[i].Name < sorted[j].Name
+		}
+		return sorted[i].Order < sorted[j].Order
+	})
+
+	return sorted
+}
@@ -2,7 +2,6 @@ package sqldb
 
 import (
 	"database/sql"
-	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -14,37 +13,55 @@ import (
 
 // SQLDB implements the access to a sql database
 type SQLDB struct {
-	DB        *sql.DB
-	DBAdapter adapters.DBAdapter
-	Schema    string
-	Log       *logger.Logger
+	DB               *sql.DB
+	DBAdapter        adapters.DBAdapter
+	Schema           string
+	Log              *logger.Logger
+	AllowDestructive bool
+	ChainIDPolicy    string
+	NotifyChannel    string
+	stmts            stmtCache
+	partitions       map[string]uint64
 }
 
 // NewSQLDB delegates work to a specific database adapter implementation,
 // opens database connection and create log tables
 func NewSQLDB(connection types.SQLConnection) (*SQLDB, error) {
 	db := &SQLDB{
-		Schema: connection.DBSchema,
-		Log:    connection.Log,
+		Schema:           connection.DBSchema,
+		Log:              connection.Log,
+		AllowDestructive: connection.AllowDestructive,
+		ChainIDPolicy:    connection.ChainIDPolicy,
+		NotifyChannel:    connection.NotifyChannel,
 	}
 
-	var url string
+	switch db.ChainIDPolicy {
+	case "", types.ChainIDPolicyRefuse, types.ChainIDPolicyArchive, types.ChainIDPolicyNamespace, types.ChainIDPolicyDrop:
+	default:
+		return nil, fmt.Errorf("invalid chain ID policy %s, valid policies are: %s", db.ChainIDPolicy, strings.Join(types.ChainIDPolicies, ", "))
+	}
 
-	switch connection.DBAdapter {
-	case types.PostgresDB:
-		db.DBAdapter = adapters.NewPostgresAdapter(safe(connection.DBSchema), connection.Log)
-		url = connection.DBURL
+	// keep a separate schema for each chain
+	if db.ChainIDPolicy == types.ChainIDPolicyNamespace {
+		db.Schema = NamespacedSchema(db.Schema, connection.ChainID)
+	}
 
-	case types.SQLiteDB:
-		db.DBAdapter = adapters.NewSQLiteAdapter(connection.Log)
-		// "?_journal_mode=WAL" parameter is necessary to prevent database locking
-		url = connection.DBURL + "?_journal_mode=WAL"
+	dbAdapter, options, err := adapters.New(connection.DBAdapter, db.Schema, connection.Log)
+	if err != nil {
+		return nil, err
+	}
+	db.DBAdapter = dbAdapter
 
-	default:
-		return nil, errors.New("invalid database adapter")
+	if sqliteAdapter, ok := dbAdapter.(*adapters.SQLiteAdapter); ok {
+		sqliteAdapter.BusyTimeout = connection.SQLiteBusyTimeout
+		sqliteAdapter.Synchronous = connection.SQLiteSynchronous
+	}
+
+	if db.ChainIDPolicy == types.ChainIDPolicyNamespace && options.NoSchemas {
+		return nil, fmt.Errorf("error chain ID policy %s is not supported by %s adapter", db.ChainIDPolicy, connection.DBAdapter)
 	}
 
-	dbc, err := db.DBAdapter.Open(url)
+	dbc, err := db.DBAdapter.Open(options.ConnectionURL(connection.DBURL))
 	if err != nil {
 		db.Log.Info("msg", "Error opening database connection", "err", err)
 		return nil, err
@@ -85,6 +102,27 @@ func NewSQLDB(connection types.SQLConnection) (*SQLDB, error) {
 		}
 	}
 
+	// IMPORTANT: DO NOT CHANGE TABLE CREATION ORDER (4)
+	if err = db.createTable(sysTables[types.SQLAbiTableName], string(types.ActionInitialize)); err != nil {
+		if !db.DBAdapter.ErrorEquals(err, types.SQLErrorTypeDuplicatedTable) {
+			db.Log.Info("msg", "Error creating Abi table", "err", err)
+			return nil, err
+		}
+	}
+
+	// Migrate system tables created by previous versions (e.g. text heights in log table)
+	for _, tableName := range sysTableNames {
+		if err = db.alterTable(sysTables[tableName], string(types.ActionInitialize)); err != nil {
+			db.Log.Info("msg", "Error migrating system table", "err", err, "value", tableName)
+			return nil, err
+		}
+	}
+
+	// maintenance commands (e.g. rollback) do not connect to a chain
+	if connection.ChainID == "" {
+		return db, nil
+	}
+
 	if err = db.CleanTables(connection.ChainID, connection.BurrowVersion); err != nil {
 		db.Log.Info("msg", "Error cleaning tables", "err", err)
 		return nil, err
@@ -92,7 +130,8 @@ func NewSQLDB(connection types.SQLConnection) (*SQLDB, error) {
 	return db, nil
 }
 
-// CleanTables, drop tables if stored chainID is different from the given one & store new chainID
+// CleanTables, if stored chainID is different from the given one applies the chain ID policy
+// (refuse by default, archive or drop tables) & stores new chainID
 // if the chainID is the same, do nothing
 func (db *SQLDB) CleanTables(chainID, burrowVersion string) error {
 
@@ -131,6 +170,16 @@ func (db *SQLDB) CleanTables(chainID, burrowVersion string) error {
 	case savedChainID == chainID:
 		return nil
 
+	// keep previous chain tables with a chain ID suffix
+	case db.ChainIDPolicy == types.ChainIDPolicyArchive:
+		return db.archiveTables(savedChainID, chainID, burrowVersion)
+
+	// tables are only dropped if explicitly allowed
+	case db.ChainIDPolicy != types.ChainIDPolicyDrop:
+		db.Log.Info("msg", "CHAIN ID changed", "value", fmt.Sprintf("%s -> %s", savedChainID, chainID))
+		return fmt.Errorf("error stored CHAIN ID %s differs from %s, set chain ID policy to %s, %s or %s to index a different chain",
+			savedChainID, chainID, types.ChainIDPolicyArchive, types.ChainIDPolicyNamespace, types.ChainIDPolicyDrop)
+
 	// clean database
 	default:
 		var tx *sql.Tx
@@ -196,6 +245,13 @@ func (db *SQLDB) CleanTables(chainID, burrowVersion string) error {
 			return err
 		}
 
+		// Delete Abi registry (contract addresses belong to the previous chain)
+		query = clean(cleanQueries.DeleteAbiQry)
+		if _, err = tx.Exec(query); err != nil {
+			db.Log.Info("msg", "Error deleting abi registry", "err", err, "query", query)
+			return err
+		}
+
 		// Commit
 		if err = tx.Commit(); err != nil {
 			db.Log.Info("msg", "Error commiting transaction", "err", err)
@@ -203,6 +259,17 @@ func (db *SQLDB) CleanTables(chainID, burrowVersion string) error {
 		}
 
 		// Drop database tables
+		db.stmts.invalidateAll()
+		// views may depend on several tables (e.g. block & tx tables)
+		for _, tableName = range tables {
+			if err = db.dropHistoryView(tableName); err != nil {
+				return err
+			}
+			if err = db.dropEnrichedView(tableName); err != nil {
+				return err
+			}
+		}
+
 		for _, tableName = range tables {
 			query = clean(db.DBAdapter.DropTableQuery(tableName))
 			if _, err = db.DB.Exec(query); err != nil {
@@ -219,6 +286,7 @@ func (db *SQLDB) CleanTables(chainID, burrowVersion string) error {
 
 // Close database connection
 func (db *SQLDB) Close() {
+	db.stmts.invalidateAll()
 	if err := db.DB.Close(); err != nil {
 		db.Log.Error("msg", "Error closing database", "err", err)
 	}
@@ -249,10 +317,82 @@ func (db *SQLDB) GetLastBlockID() (string, error) {
 	return id, nil
 }
 
+// GetAbis returns all abi specifications stored in the abi registry table mapped by contract address
+func (db *SQLDB) GetAbis() (map[string]string, error) {
+	abis := make(map[string]string)
+	query := clean(db.DBAdapter.SelectAbiQuery())
+
+	db.Log.Info("msg", "QUERY ABI", "query", query)
+	rows, err := db.DB.Query(query)
+	if err != nil {
+		db.Log.Info("msg", "Error querying abi registry", "err", err)
+		return abis, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var address, abiJSON string
+
+		if err = rows.Scan(&address, &abiJSON); err != nil {
+			db.Log.Info("msg", "Error scanning abi registry", "err", err)
+			return abis, err
+		}
+		abis[address] = abiJSON
+	}
+
+	if err = rows.Err(); err != nil {
+		db.Log.Info("msg", "Error during rows iteration", "err", err)
+		return abis, err
+	}
+
+	return abis, nil
+}
+
+// SetAbi upserts the abi specification of a given contract address in the abi registry table
+func (db *SQLDB) SetAbi(address, abiJSON string) error {
+	table := db.getSysTablesDefinition()[types.SQLAbiTableName]
+	row := types.EventDataRow{
+		Action: types.ActionUpsert,
+		RowData: map[string]interface{}{
+			types.SQLColumnLabelAddress: address,
+			types.SQLColumnLabelAbi:     abiJSON,
+		},
+	}
+
+	queryVal, _, err := db.DBAdapter.UpsertQuery(table, row)
+	if err != nil {
+		db.Log.Info("msg", "Error building abi upsert query", "err", err)
+		return err
+	}
+
+	query := clean(queryVal.Query)
+	db.Log.Info("msg", "UPSERT ABI", "query", query, "value", address)
+	if _, err = db.DB.Exec(query, queryVal.Pointers...); err != nil {
+		db.Log.Info("msg", "Error upserting abi", "err", err)
+		return err
+	}
+
+	return nil
+}
+
 // SynchronizeDB synchronize db tables structures from given tables specifications
 func (db *SQLDB) SynchronizeDB(eventTables types.EventTables) error {
 	db.Log.Info("msg", "Synchronizing DB")
 
+	// check every name before touching the database
+	for _, table := range eventTables {
+		if err := db.validateTable(table); err != nil {
+			return err
+		}
+	}
+
+	// enriched views depend on event, block & tx tables
+	for _, table := range eventTables {
+		if err := db.dropEnrichedView(table.Name); err != nil {
+			return err
+		}
+	}
+
 	for eventName, table := range eventTables {
 		found, err := db.findTable(table.Name)
 		if err != nil {
@@ -260,25 +400,72 @@ func (db *SQLDB) SynchronizeDB(eventTables types.EventTables) error {
 		}
 
 		if found {
+			if err = db.checkPartitioning(table); err != nil {
+				return err
+			}
+			// views depend on the table structure
+			if err = db.dropHistoryView(table.Name); err != nil {
+				return err
+			}
 			err = db.alterTable(table, eventName)
 		} else {
 			err = db.createTable(table, eventName)
 		}
+
+		// cached statements & partitions may not match the table anymore
+		db.stmts.invalidate(table.Name)
+		delete(db.partitions, table.Name)
+
 		if err != nil {
 			return err
 		}
+
+		if err = db.createHistoryView(table.Name); err != nil {
+			return err
+		}
+
+		if err = db.createIndexes(table); err != nil {
+			return err
+		}
+
+		if err = db.addJSONPaths(table); err != nil {
+			return err
+		}
+
+		if err = db.createFullText(table); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	for _, table := range eventTables {
+		if err := db.createEnrichedView(table.Name); err != nil {
+			return err
+		}
+	}
+
+	// partitioned tables need partitions for the heights to come
+	height, err := db.GetLastBlockID()
+	if err != nil {
+		return err
+	}
+
+	return db.rollPartitions(eventTables, height)
 }
 
 // SetBlock inserts or updates multiple rows and stores log info in SQL tables
 func (db *SQLDB) SetBlock(eventTables types.EventTables, eventData types.EventData) error {
+	return db.setBlock(eventTables, eventData, true)
+}
+
+// setBlock inserts or updates rows, if a table or column is not found the DB is synchronized
+// and the block retried once (if allowed), schema drift is reported if the retry fails too
+func (db *SQLDB) setBlock(eventTables types.EventTables, eventData types.EventData, retry bool) error {
 
 	db.Log.Info("msg", "Synchronize Block..........")
 
 	//Declarations
 	var logStmt *sql.Stmt
+	var stmt *sql.Stmt
 	var tx *sql.Tx
 	var safeTable string
 	var query string
@@ -288,6 +475,12 @@ func (db *SQLDB) SetBlock(eventTables types.EventTables, eventData types.EventDa
 	var txHash interface{}
 	var jsonData []byte
 	var sqlValues []byte
+	var beforeImages [][]byte
+
+	// roll partitioned tables over before writing rows
+	if err = db.rollPartitions(eventTables, eventData.Block); err != nil {
+		return err
+	}
 
 	// Begin tx
 	if tx, err = db.DB.Begin(); err != nil {
@@ -298,18 +491,27 @@ func (db *SQLDB) SetBlock(eventTables types.EventTables, eventData types.EventDa
 
 	// Prepare log statement
 	logQuery := clean(db.DBAdapter.InsertLogQuery())
-	if logStmt, err = tx.Prepare(logQuery); err != nil {
+	if logStmt, err = db.stmts.prepare(db.DB, types.SQLLogTableName, logQuery); err != nil {
 		db.Log.Info("msg", "Error preparing log stmt", "err", err)
 		return err
 	}
+	logStmt = tx.Stmt(logStmt)
 
 loop:
 	// for each table in the block
 	for eventName, table := range eventTables {
 
-		safeTable = safe(table.Name)
+		safeTable = table.Name
 		dataRows := eventData.Tables[table.Name]
 
+		// write rows in bulk if supported by the adapter
+		if bulkAdapter, ok := db.DBAdapter.(adapters.BulkAdapter); ok {
+			if err = db.setBulkRows(tx, bulkAdapter, eventName, table, dataRows, eventData.Block); err != nil {
+				break loop // exits from all loops -> continue in close log stmt
+			}
+			continue
+		}
+
 		// for Each Row
 		for _, row := range dataRows {
 
@@ -337,9 +539,19 @@ loop:
 
 			query = clean(queryVal.Query)
 
+			// Read row contents before the action
+			if beforeImages, err = db.beforeImages(tx, table, []types.EventDataRow{row}); err != nil {
+				db.Log.Info("msg", "error reading before image", "err", err, "value", queryVal.Values)
+				break loop // exits from all loops -> continue in close log stmt
+			}
+
 			// Perform row action
 			db.Log.Info("msg", row.Action, "query", query, "value", queryVal.Values)
-			if _, err = tx.Exec(query, queryVal.Pointers...); err != nil {
+			if stmt, err = db.stmts.prepare(db.DB, safeTable, query); err != nil {
+				db.Log.Info("msg", fmt.Sprintf("error preparing %s on row", row.Action), "err", err, "value", queryVal.Values)
+				break loop // exits from all loops -> continue in close log stmt
+			}
+			if _, err = tx.Stmt(stmt).Exec(queryVal.Pointers...); err != nil {
 				db.Log.Info("msg", fmt.Sprintf("error performing %s on row", row.Action), "err", err, "value", queryVal.Values)
 				break loop // exits from all loops -> continue in close log stmt
 			}
@@ -358,7 +570,7 @@ loop:
 
 			// Insert in log
 			db.Log.Info("msg", "INSERT LOG", "query", logQuery, "value", fmt.Sprintf("tableName = %s eventName = %s filter = %s block = %s", safeTable, eventName, table.Filter, eventData.Block))
-			if _, err = logStmt.Exec(safeTable, eventName, table.Filter, eventData.Block, txHash, row.Action, jsonData, query, sqlValues); err != nil {
+			if _, err = logStmt.Exec(safeTable, eventName, table.Filter, eventData.Block, txHash, row.Action, jsonData, query, sqlValues, beforeImages[0]); err != nil {
 				db.Log.Info("msg", "Error inserting into log", "err", err)
 				break loop // exits from all loops -> continue in close log stmt
 			}
@@ -372,6 +584,11 @@ loop:
 		}
 	}
 
+	// Notify listeners (delivered on commit)
+	if err == nil {
+		err = db.notifyBlock(tx, eventTables, eventData)
+	}
+
 	// Error handling
 	if err != nil {
 		// Rollback error
@@ -386,23 +603,29 @@ loop:
 			// Table does not exists
 			if db.DBAdapter.ErrorEquals(err, types.SQLErrorTypeUndefinedTable) {
 				db.Log.Warn("msg", "Table not found", "value", safeTable)
+				if !retry {
+					return db.driftError(err)
+				}
 				//Synchronize DB
 				if err = db.SynchronizeDB(eventTables); err != nil {
 					return err
 				}
 				//Retry
-				return db.SetBlock(eventTables, eventData)
+				return db.setBlock(eventTables, eventData, false)
 			}
 
 			// Columns do not match
 			if db.DBAdapter.ErrorEquals(err, types.SQLErrorTypeUndefinedColumn) {
 				db.Log.Warn("msg", "Column not found", "value", safeTable)
+				if !retry {
+					return db.driftError(err)
+				}
 				//Synchronize DB
 				if err = db.SynchronizeDB(eventTables); err != nil {
 					return err
 				}
 				//Retry
-				return db.SetBlock(eventTables, eventData)
+				return db.setBlock(eventTables, eventData, false)
 			}
 			return err
 		}
@@ -443,53 +666,9 @@ func (db *SQLDB) GetBlock(block string) (types.EventData, error) {
 		}
 		query = clean(query)
 		db.Log.Info("msg", "Query table data", "query", query)
-		rows, err := db.DB.Query(query)
-		if err != nil {
-			db.Log.Info("msg", "Error querying table data", "err", err)
-			return data, err
-		}
-		defer rows.Close()
 
-		cols, err := rows.Columns()
+		dataRows, err := db.selectRows(table, query)
 		if err != nil {
-			db.Log.Info("msg", "Error getting row columns", "err", err)
-			return data, err
-		}
-
-		// builds pointers
-		length := len(cols)
-		pointers := make([]interface{}, length)
-		containers := make([]sql.NullString, length)
-
-		for i := range pointers {
-			pointers[i] = &containers[i]
-		}
-
-		// for each row in table
-		var dataRows []types.EventDataRow
-
-		for rows.Next() {
-
-			row := make(map[string]interface{})
-
-			if err = rows.Scan(pointers...); err != nil {
-				db.Log.Info("msg", "Error scanning data", "err", err)
-				return data, err
-			}
-			db.Log.Info("msg", "Query resultset", "value", fmt.Sprintf("%+v", containers))
-
-			// for each column in row
-			for i, col := range cols {
-				// add value if not null
-				if containers[i].Valid {
-					row[col] = containers[i].String
-				}
-			}
-			dataRows = append(dataRows, types.EventDataRow{Action: types.ActionRead, RowData: row})
-		}
-
-		if err = rows.Err(); err != nil {
-			db.Log.Info("msg", "Error during rows iteration", "err", err)
 			return data, err
 		}
 		data.Tables[table.Name] = dataRows
@@ -497,80 +676,7 @@ func (db *SQLDB) GetBlock(block string) (types.EventData, error) {
 	return data, nil
 }
 
-// RestoreDB restores the DB to a given moment in time
+// RestoreDB restores the DB to a given moment in time in tables named <prefix>_<table>
 func (db *SQLDB) RestoreDB(time time.Time, prefix string) error {
-
-	const yymmddhhmmss = "2006-01-02 15:04:05"
-
-	var pointers []interface{}
-
-	if prefix == "" {
-		return fmt.Errorf("error prefix mus not be empty")
-	}
-
-	// Get Restore DB query
-	query := db.DBAdapter.RestoreDBQuery()
-	strTime := time.Format(yymmddhhmmss)
-
-	db.Log.Info("msg", "RESTORING DB..................................")
-
-	// Open rows
-	db.Log.Info("msg", "open log", "query", query)
-	rows, err := db.DB.Query(query, strTime)
-	if err != nil {
-		db.Log.Info("msg", "error querying log", "err", err)
-		return err
-	}
-	defer rows.Close()
-
-	// For each row returned
-	for rows.Next() {
-		var tableName, sqlSmt, sqlValues string
-		var action types.DBAction
-
-		if err = rows.Scan(&tableName, &action, &sqlSmt, &sqlValues); err != nil {
-			db.Log.Info("msg", "error scanning table structure", "err", err)
-			return err
-		}
-
-		if err = rows.Err(); err != nil {
-			db.Log.Info("msg", "error scanning table structure", "err", err)
-			return err
-		}
-
-		restoreTable := fmt.Sprintf("%s_%s", prefix, tableName)
-
-		switch action {
-		case types.ActionUpsert, types.ActionDelete:
-			// get row values
-			if pointers, err = db.getValuesFromJSON(sqlValues); err != nil {
-				db.Log.Info("msg", "error unmarshaling json", "err", err, "value", sqlValues)
-				return err
-			}
-
-			// Prepare Upsert/delete
-			query = strings.Replace(sqlSmt, tableName, restoreTable, -1)
-
-			db.Log.Info("msg", "SQL COMMAND", "sql", query)
-			if _, err = db.DB.Exec(query, pointers...); err != nil {
-				db.Log.Info("msg", "Error executing upsert/delete ", "err", err, "value", sqlSmt, "data", sqlValues)
-				return err
-			}
-
-		case types.ActionAlterTable, types.ActionCreateTable:
-			// Prepare Alter/Create Table
-			query = strings.Replace(sqlSmt, tableName, restoreTable, -1)
-
-			db.Log.Info("msg", "SQL COMMAND", "sql", query)
-			if _, err = db.DB.Exec(query); err != nil {
-				db.Log.Info("msg", "Error executing alter/create table command ", "err", err, "value", sqlSmt)
-				return err
-			}
-		default:
-			// Invalid Action
-			db.Log.Info("msg", "invalid action", "value", action)
-			return fmt.Errorf("invalid row action %s", action)
-		}
-	}
-	return nil
+	return db.Restore(RestoreOptions{Time: time, Prefix: prefix})
 }
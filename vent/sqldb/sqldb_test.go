@@ -3,10 +3,24 @@
 package sqldb_test
 
 import (
+	"database/sql"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/lib/pq"
+	"github.com/monax/bosmarmot/vent/config"
+	"github.com/monax/bosmarmot/vent/logger"
+	"github.com/monax/bosmarmot/vent/sqldb"
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
 	"github.com/monax/bosmarmot/vent/sqlsol"
 	"github.com/monax/bosmarmot/vent/test"
 	"github.com/monax/bosmarmot/vent/types"
@@ -45,10 +59,87 @@ func TestSynchronizeDB(t *testing.T) {
 		err = db.SynchronizeDB(tableStructure.GetTables())
 		require.NoError(t, err)
 	})
+
+	t.Run("MYSQL: successfully creates database tables and synchronizes db", func(t *testing.T) {
+		goodJSON := test.GoodJSONConfFile(t)
+
+		byteValue := []byte(goodJSON)
+		tableStructure, _ := sqlsol.NewParserFromBytes(byteValue)
+
+		db, cleanUpDB := test.NewTestDB(t, types.MySQLDB)
+		defer cleanUpDB()
+
+		err := db.Ping()
+		require.NoError(t, err)
+
+		err = db.SynchronizeDB(tableStructure.GetTables())
+		require.NoError(t, err)
+	})
+
+	t.Run("POSTGRES: successfully migrates tables only if destructive changes are allowed", func(t *testing.T) {
+		db, cleanUpDB := test.NewTestDB(t, types.PostgresDB)
+		defer cleanUpDB()
+
+		requireMigration(t, db)
+	})
+
+	t.Run("SQLITE: successfully migrates tables only if destructive changes are allowed", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requireMigration(t, db)
+	})
+
+	t.Run("MYSQL: successfully migrates tables only if destructive changes are allowed", func(t *testing.T) {
+		db, cleanUpDB := test.NewTestDB(t, types.MySQLDB)
+		defer cleanUpDB()
+
+		requireMigration(t, db)
+	})
+}
+
+func TestMigrateHeights(t *testing.T) {
+	t.Run("POSTGRES: successfully migrates text heights and big integers in place", func(t *testing.T) {
+		db, cleanUpDB := test.NewTestDB(t, types.PostgresDB)
+		defer cleanUpDB()
+
+		requireHeightMigration(t, db)
+	})
+
+	t.Run("SQLITE: successfully migrates text heights and big integers in place", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requireHeightMigration(t, db)
+	})
+
+	t.Run("MYSQL: successfully migrates text heights and big integers in place", func(t *testing.T) {
+		db, cleanUpDB := test.NewTestDB(t, types.MySQLDB)
+		defer cleanUpDB()
+
+		requireHeightMigration(t, db)
+	})
+
+	t.Run("SQLITE: successfully converts numeric columns with NUMERIC affinity to text", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requireNumericStorageMigration(t, db, `ALTER TABLE test_numeric RENAME TO test_numeric_old;
+			CREATE TABLE test_numeric (test_id INTEGER NOT NULL, amount NUMERIC, _height BIGINT, PRIMARY KEY (test_id));
+			INSERT INTO test_numeric SELECT test_id, amount, _height FROM test_numeric_old;
+			DROP TABLE test_numeric_old;`)
+	})
+
+	t.Run("MYSQL: successfully converts DECIMAL numeric columns to text", func(t *testing.T) {
+		db, cleanUpDB := test.NewTestDB(t, types.MySQLDB)
+		defer cleanUpDB()
+
+		requireNumericStorageMigration(t, db, fmt.Sprintf("ALTER TABLE %s.test_numeric MODIFY COLUMN amount DECIMAL(65,0);", db.Schema))
+	})
 }
 
 func TestCleanDB(t *testing.T) {
-	t.Run("POSTGRES: successfully creates tables, updates chainID and drops all tables", func(t *testing.T) {
+	t.Run("POSTGRES: successfully creates tables, refuses to change chainID unless tables can be dropped", func(t *testing.T) {
 		goodJSON := test.GoodJSONConfFile(t)
 
 		byteValue := []byte(goodJSON)
@@ -63,11 +154,15 @@ func TestCleanDB(t *testing.T) {
 		err = db.SynchronizeDB(tableStructure.GetTables())
 		require.NoError(t, err)
 
+		err = db.CleanTables("NEW_ID", "Version 1.0")
+		require.Error(t, err)
+
+		db.ChainIDPolicy = types.ChainIDPolicyDrop
 		err = db.CleanTables("NEW_ID", "Version 1.0")
 		require.NoError(t, err)
 	})
 
-	t.Run("SQLITE: successfully creates tables, updates chainID and drops all tables", func(t *testing.T) {
+	t.Run("SQLITE: successfully creates tables, refuses to change chainID unless tables can be dropped", func(t *testing.T) {
 		goodJSON := test.GoodJSONConfFile(t)
 
 		byteValue := []byte(goodJSON)
@@ -82,9 +177,71 @@ func TestCleanDB(t *testing.T) {
 		err = db.SynchronizeDB(tableStructure.GetTables())
 		require.NoError(t, err)
 
+		err = db.CleanTables("NEW_ID", "Version 1.0")
+		require.Error(t, err)
+
+		db.ChainIDPolicy = types.ChainIDPolicyDrop
+		err = db.CleanTables("NEW_ID", "Version 1.0")
+		require.NoError(t, err)
+
+	})
+
+	t.Run("MYSQL: successfully creates tables, refuses to change chainID unless tables can be dropped", func(t *testing.T) {
+		goodJSON := test.GoodJSONConfFile(t)
+
+		byteValue := []byte(goodJSON)
+		tableStructure, _ := sqlsol.NewParserFromBytes(byteValue)
+
+		db, cleanUpDB := test.NewTestDB(t, types.MySQLDB)
+		defer cleanUpDB()
+
+		err := db.Ping()
+		require.NoError(t, err)
+
+		err = db.SynchronizeDB(tableStructure.GetTables())
+		require.NoError(t, err)
+
+		err = db.CleanTables("NEW_ID", "Version 1.0")
+		require.Error(t, err)
+
+		db.ChainIDPolicy = types.ChainIDPolicyDrop
 		err = db.CleanTables("NEW_ID", "Version 1.0")
 		require.NoError(t, err)
+	})
+
+	t.Run("POSTGRES: successfully archives tables when chainID changes", func(t *testing.T) {
+		db, cleanUpDB := test.NewTestDB(t, types.PostgresDB)
+		defer cleanUpDB()
+
+		requireArchivedTables(t, db)
+	})
+
+	t.Run("SQLITE: successfully archives tables when chainID changes", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requireArchivedTables(t, db)
+	})
+
+	t.Run("MYSQL: successfully archives tables when chainID changes", func(t *testing.T) {
+		db, cleanUpDB := test.NewTestDB(t, types.MySQLDB)
+		defer cleanUpDB()
+
+		requireArchivedTables(t, db)
+	})
+
+	t.Run("POSTGRES: refuses to archive tables whose archived names are too long", func(t *testing.T) {
+		db, cleanUpDB := test.NewTestDB(t, types.PostgresDB)
+		defer cleanUpDB()
+
+		requireArchivedNameLimit(t, db)
+	})
 
+	t.Run("MYSQL: refuses to archive tables whose archived names are too long", func(t *testing.T) {
+		db, cleanUpDB := test.NewTestDB(t, types.MySQLDB)
+		defer cleanUpDB()
+
+		requireArchivedNameLimit(t, db)
 	})
 }
 
@@ -150,6 +307,37 @@ func TestSetBlock(t *testing.T) {
 
 	})
 
+	t.Run("MYSQL: successfully inserts a block", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.MySQLDB)
+		defer closeDB()
+
+		errp := db.Ping()
+		require.NoError(t, errp)
+
+		// new
+		str, dat := getBlock()
+		err := db.SetBlock(str, dat)
+		require.NoError(t, err)
+
+		// read
+		_, err = db.GetLastBlockID()
+		require.NoError(t, err)
+
+		_, err = db.GetBlock(dat.Block)
+		require.NoError(t, err)
+
+		// alter
+		str, dat = getAlterBlock()
+		err = db.SetBlock(str, dat)
+		require.NoError(t, err)
+
+		//restore
+		ti := time.Now().Local().AddDate(10, 0, 0)
+		err = db.RestoreDB(ti, "RESTORED")
+		require.NoError(t, err)
+
+	})
+
 	t.Run("POSTGRES: successfully creates an empty table", func(t *testing.T) {
 		db, closeDB := test.NewTestDB(t, types.PostgresDB)
 		defer closeDB()
@@ -197,6 +385,1672 @@ func TestSetBlock(t *testing.T) {
 		err := db.SynchronizeDB(tables)
 		require.NoError(t, err)
 	})
+
+	t.Run("MYSQL: successfully creates an empty table", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.MySQLDB)
+		defer closeDB()
+
+		errp := db.Ping()
+		require.NoError(t, errp)
+
+		//table 1
+		cols1 := make(map[string]types.SQLTableColumn)
+		cols1["ID"] = types.SQLTableColumn{Name: "test_id", Type: types.SQLColumnTypeSerial, Primary: true, Order: 1}
+		cols1["Column1"] = types.SQLTableColumn{Name: "col1", Type: types.SQLColumnTypeBool, Primary: false, Order: 2}
+		cols1["Column2"] = types.SQLTableColumn{Name: "col2", Type: types.SQLColumnTypeByteA, Primary: false, Order: 3}
+		cols1["Column3"] = types.SQLTableColumn{Name: "col3", Type: types.SQLColumnTypeInt, Primary: false, Order: 4}
+		cols1["Column4"] = types.SQLTableColumn{Name: "col4", Type: types.SQLColumnTypeText, Primary: false, Order: 5}
+		cols1["Column5"] = types.SQLTableColumn{Name: "col5", Type: types.SQLColumnTypeTimeStamp, Primary: false, Order: 6}
+		cols1["Column6"] = types.SQLTableColumn{Name: "col6", Type: types.SQLColumnTypeVarchar, Length: 100, Primary: false, Order: 7}
+		table1 := types.SQLTable{Name: "AllDataTypesTable", Columns: cols1}
+		tables := make(map[string]types.SQLTable)
+		tables["AllDataTypesTable"] = table1
+
+		err := db.SynchronizeDB(tables)
+		require.NoError(t, err)
+	})
+
+	t.Run("POSTGRES: successfully keeps order of interleaved upserts and deletes", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.PostgresDB)
+		defer closeDB()
+
+		str, dat := getInterleavedBlock()
+		err := db.SetBlock(str, dat)
+		require.NoError(t, err)
+
+		requireInterleavedBlock(t, db.GetBlock)
+	})
+
+	t.Run("SQLITE: successfully keeps order of interleaved upserts and deletes", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		str, dat := getInterleavedBlock()
+		err := db.SetBlock(str, dat)
+		require.NoError(t, err)
+
+		requireInterleavedBlock(t, db.GetBlock)
+	})
+
+	t.Run("MYSQL: successfully keeps order of interleaved upserts and deletes", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.MySQLDB)
+		defer closeDB()
+
+		str, dat := getInterleavedBlock()
+		err := db.SetBlock(str, dat)
+		require.NoError(t, err)
+
+		requireInterleavedBlock(t, db.GetBlock)
+	})
+
+	t.Run("POSTGRES: successfully writes tables without primary key", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.PostgresDB)
+		defer closeDB()
+
+		requireNoPrimaryKey(t, db)
+	})
+
+	t.Run("SQLITE: successfully writes tables without primary key", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requireNoPrimaryKey(t, db)
+	})
+
+	t.Run("MYSQL: successfully writes tables without primary key", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.MySQLDB)
+		defer closeDB()
+
+		requireNoPrimaryKey(t, db)
+	})
+
+	t.Run("POSTGRES: successfully writes blocks larger than a query", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.PostgresDB)
+		defer closeDB()
+
+		requireLargeBlocks(t, db)
+	})
+
+	t.Run("SQLITE: successfully writes blocks larger than a query", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requireLargeBlocks(t, db)
+	})
+
+	t.Run("MYSQL: successfully writes blocks larger than a query", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.MySQLDB)
+		defer closeDB()
+
+		requireLargeBlocks(t, db)
+	})
+
+	t.Run("POSTGRES: successfully reuses prepared statements across blocks", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.PostgresDB)
+		defer closeDB()
+
+		requireStmtReuse(t, db)
+	})
+
+	t.Run("SQLITE: successfully reuses prepared statements across blocks", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requireStmtReuse(t, db)
+	})
+
+	t.Run("MYSQL: successfully reuses prepared statements across blocks", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.MySQLDB)
+		defer closeDB()
+
+		requireStmtReuse(t, db)
+	})
+}
+
+func TestRollbackToHeight(t *testing.T) {
+	t.Run("POSTGRES: successfully rolls back tables to a given height", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.PostgresDB)
+		defer closeDB()
+
+		requireRollback(t, db)
+	})
+
+	t.Run("SQLITE: successfully rolls back tables to a given height", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requireRollback(t, db)
+	})
+
+	t.Run("MYSQL: successfully rolls back tables to a given height", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.MySQLDB)
+		defer closeDB()
+
+		requireRollback(t, db)
+	})
+}
+
+func TestRestore(t *testing.T) {
+	t.Run("POSTGRES: successfully restores tables up to a given height", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.PostgresDB)
+		defer closeDB()
+
+		requireRestore(t, db)
+	})
+
+	t.Run("SQLITE: successfully restores tables up to a given height", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requireRestore(t, db)
+	})
+
+	t.Run("MYSQL: successfully restores tables up to a given height", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.MySQLDB)
+		defer closeDB()
+
+		requireRestore(t, db)
+	})
+}
+
+func TestGetTableAsOf(t *testing.T) {
+	t.Run("POSTGRES: successfully reads tables as of a given height", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.PostgresDB)
+		defer closeDB()
+
+		requireTableAsOf(t, db)
+	})
+
+	t.Run("SQLITE: successfully reads tables as of a given height", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requireTableAsOf(t, db)
+	})
+
+	t.Run("MYSQL: successfully reads tables as of a given height", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.MySQLDB)
+		defer closeDB()
+
+		requireTableAsOf(t, db)
+	})
+}
+
+func TestCompactLog(t *testing.T) {
+	t.Run("POSTGRES: successfully compacts and archives log entries", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.PostgresDB)
+		defer closeDB()
+
+		requireCompactLog(t, db)
+	})
+
+	t.Run("SQLITE: successfully compacts and archives log entries", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requireCompactLog(t, db)
+	})
+
+	t.Run("MYSQL: successfully compacts and archives log entries", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.MySQLDB)
+		defer closeDB()
+
+		requireCompactLog(t, db)
+	})
+}
+
+func TestIdentifiers(t *testing.T) {
+	t.Run("POSTGRES: successfully quotes & validates identifiers", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.PostgresDB)
+		defer closeDB()
+
+		requireIdentifiers(t, db)
+	})
+
+	t.Run("SQLITE: successfully quotes & validates identifiers", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requireIdentifiers(t, db)
+	})
+
+	t.Run("MYSQL: successfully quotes & validates identifiers", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.MySQLDB)
+		defer closeDB()
+
+		requireIdentifiers(t, db)
+	})
+}
+
+func TestCheckSchema(t *testing.T) {
+	t.Run("POSTGRES: successfully detects & repairs schema drift", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.PostgresDB)
+		defer closeDB()
+
+		requireSchemaDrift(t, db)
+	})
+
+	t.Run("SQLITE: successfully detects & repairs schema drift", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requireSchemaDrift(t, db)
+	})
+
+	t.Run("MYSQL: successfully detects & repairs schema drift", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.MySQLDB)
+		defer closeDB()
+
+		requireSchemaDrift(t, db)
+	})
+}
+
+func TestNotify(t *testing.T) {
+	t.Run("POSTGRES: successfully notifies committed blocks", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.PostgresDB)
+		defer closeDB()
+
+		listener := pq.NewListener(config.DefaultFlags().DBURL, time.Second, time.Second, nil)
+		defer listener.Close()
+
+		db.NotifyChannel = db.Schema + "_blocks"
+		err := listener.Listen(db.NotifyChannel)
+		require.NoError(t, err)
+
+		str, dat := getBlock()
+		err = db.SetBlock(str, dat)
+		require.NoError(t, err)
+
+		select {
+		case n := <-listener.Notify:
+			var notification types.BlockNotification
+			err = json.Unmarshal([]byte(n.Extra), &notification)
+			require.NoError(t, err)
+			require.Equal(t, uint64(123456789012340), notification.Height)
+			require.Equal(t, map[string]int{"test_table1": 5, "test_table2": 5, "test_table3": 4, "test_table4": 5}, notification.Tables)
+		case <-time.After(5 * time.Second):
+			t.Fatal("notification not received")
+		}
+	})
+
+	t.Run("SQLITE: successfully ignores notification channel", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		db.NotifyChannel = "blocks"
+
+		str, dat := getBlock()
+		err := db.SetBlock(str, dat)
+		require.NoError(t, err)
+	})
+
+	t.Run("MYSQL: successfully ignores notification channel", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.MySQLDB)
+		defer closeDB()
+
+		db.NotifyChannel = "blocks"
+
+		str, dat := getBlock()
+		err := db.SetBlock(str, dat)
+		require.NoError(t, err)
+	})
+}
+
+func TestEnrichedViews(t *testing.T) {
+	t.Run("POSTGRES: successfully joins event rows with block & tx data", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.PostgresDB)
+		defer closeDB()
+
+		requireEnrichedViews(t, db)
+	})
+
+	t.Run("SQLITE: successfully joins event rows with block & tx data", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requireEnrichedViews(t, db)
+	})
+
+	t.Run("MYSQL: successfully joins event rows with block & tx data", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.MySQLDB)
+		defer closeDB()
+
+		requireEnrichedViews(t, db)
+	})
+}
+
+func TestJSONColumns(t *testing.T) {
+	t.Run("POSTGRES: successfully migrates json columns to indexed jsonb", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.PostgresDB)
+		defer closeDB()
+
+		str := requireJSONColumns(t, db)
+
+		countIndexes := func() int {
+			count := 0
+			err := db.DB.QueryRow("SELECT COUNT(*) FROM pg_indexes WHERE schemaname = $1 AND indexdef LIKE '%USING gin%'", db.Schema).Scan(&count)
+			require.NoError(t, err)
+			return count
+		}
+		require.Equal(t, 1, countIndexes())
+
+		// archived tables keep their indexes, new tables get their own
+		db.ChainIDPolicy = types.ChainIDPolicyArchive
+		err := db.CleanTables("NEW_ID", "Version 1.0")
+		require.NoError(t, err)
+		err = db.SynchronizeDB(str)
+		require.NoError(t, err)
+		require.Equal(t, 2, countIndexes())
+	})
+
+	t.Run("SQLITE: successfully generates columns from json paths", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requireJSONColumns(t, db)
+	})
+
+	t.Run("MYSQL: successfully migrates json columns", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.MySQLDB)
+		defer closeDB()
+
+		requireJSONColumns(t, db)
+	})
+}
+
+func TestPartitioning(t *testing.T) {
+	t.Run("POSTGRES: successfully rolls over height range partitions", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.PostgresDB)
+		defer closeDB()
+
+		str := requirePartitioning(t, db)
+
+		countPartitions := func() int {
+			count := 0
+			err := db.DB.QueryRow(`SELECT COUNT(*) FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+				JOIN pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = $1`, db.Schema).Scan(&count)
+			require.NoError(t, err)
+			return count
+		}
+		// heights 0 to 39 in ranges of 10
+		require.Equal(t, 4, countPartitions())
+
+		count := 0
+		err := db.DB.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE _action = $1", db.DBAdapter.SecureTableName(types.SQLLogTableName)), types.ActionPartition).Scan(&count)
+		require.NoError(t, err)
+		require.Equal(t, 4, count)
+
+		// archived tables keep their partitions, new tables get their own
+		db.ChainIDPolicy = types.ChainIDPolicyArchive
+		err = db.CleanTables("NEW_ID", "Version 1.0")
+		require.NoError(t, err)
+		err = db.SynchronizeDB(str)
+		require.NoError(t, err)
+		require.Equal(t, 6, countPartitions())
+	})
+
+	t.Run("SQLITE: successfully keys partitioned tables by height", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requirePartitioning(t, db)
+	})
+
+	t.Run("MYSQL: successfully keys partitioned tables by height", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.MySQLDB)
+		defer closeDB()
+
+		requirePartitioning(t, db)
+	})
+}
+
+func TestFullTextSearch(t *testing.T) {
+	t.Run("POSTGRES: successfully searches tsvector columns", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.PostgresDB)
+		defer closeDB()
+
+		requireFullTextSearch(t, db)
+	})
+
+	t.Run("SQLITE: successfully searches fts5 tables", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requireFullTextSearch(t, db)
+	})
+
+	t.Run("SQLITE: successfully rebuilds fts5 tables only when needed", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requireFullTextRebuilds(t, db)
+	})
+
+	t.Run("MYSQL: ignores full-text columns", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.MySQLDB)
+		defer closeDB()
+
+		requireFullTextSearch(t, db)
+	})
+}
+
+func TestSQLiteModes(t *testing.T) {
+	t.Run("SQLITE: successfully writes, checkpoints & backs up an in-memory database", func(t *testing.T) {
+		connection := types.SQLConnection{
+			DBAdapter:         types.SQLiteDB,
+			DBURL:             adapters.SQLiteMemory,
+			Log:               logger.NewLogger("debug"),
+			ChainID:           "ID 0123",
+			BurrowVersion:     "Version 0.0",
+			SQLiteBusyTimeout: 5 * time.Second,
+			SQLiteSynchronous: "normal",
+		}
+
+		db, err := sqldb.NewSQLDB(connection)
+		require.NoError(t, err)
+		defer db.Close()
+
+		requireSQLiteModes(t, db, connection)
+	})
+
+	t.Run("SQLITE: successfully checkpoints a database file", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		str, dat := getInterleavedBlock()
+		err := db.SynchronizeDB(str)
+		require.NoError(t, err)
+		err = db.SetBlock(str, dat)
+		require.NoError(t, err)
+
+		err = db.Checkpoint()
+		require.NoError(t, err)
+		requireInterleavedBlock(t, db.GetBlock)
+	})
+
+	t.Run("SQLITE: successfully backs up a database file while blocks are written", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		str, dat := getInterleavedBlock()
+		err := db.SynchronizeDB(str)
+		require.NoError(t, err)
+		err = db.SetBlock(str, dat)
+		require.NoError(t, err)
+
+		// keep writing blocks until the backup is done
+		stop := make(chan struct{})
+		written := make(chan error, 1)
+		go func() {
+			for height := int64(123456789012341); ; height++ {
+				select {
+				case <-stop:
+					written <- nil
+					return
+				default:
+				}
+				dat.Block = strconv.FormatInt(height, 10)
+				if err := db.SetBlock(str, dat); err != nil {
+					written <- err
+					return
+				}
+			}
+		}()
+
+		dir, err := ioutil.TempDir("", "vent")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		backupFile := filepath.Join(dir, "backup.sqlite")
+		err = db.Backup(backupFile)
+		close(stop)
+		require.NoError(t, err)
+		require.NoError(t, <-written)
+
+		backup, err := sqldb.NewSQLDB(types.SQLConnection{
+			DBAdapter:     types.SQLiteDB,
+			DBURL:         backupFile,
+			Log:           logger.NewLogger("debug"),
+			ChainID:       "ID 0123",
+			BurrowVersion: "Version 0.0",
+		})
+		require.NoError(t, err)
+		defer backup.Close()
+
+		requireInterleavedBlock(t, backup.GetBlock)
+		lastBlock, err := backup.GetLastBlockID()
+		require.NoError(t, err)
+		require.NotEqual(t, "0", lastBlock)
+	})
+
+	t.Run("SQLITE: returns an error if the synchronous level is not valid", func(t *testing.T) {
+		_, err := sqldb.NewSQLDB(types.SQLConnection{
+			DBAdapter:         types.SQLiteDB,
+			DBURL:             adapters.SQLiteMemory,
+			Log:               logger.NewLogger("debug"),
+			SQLiteSynchronous: "sometimes",
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestTypedReads(t *testing.T) {
+	t.Run("POSTGRES: successfully reads typed values", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.PostgresDB)
+		defer closeDB()
+
+		requireTypedReads(t, db)
+	})
+
+	t.Run("SQLITE: successfully reads typed values", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.SQLiteDB)
+		defer closeDB()
+
+		requireTypedReads(t, db)
+	})
+
+	t.Run("MYSQL: successfully reads typed values", func(t *testing.T) {
+		db, closeDB := test.NewTestDB(t, types.MySQLDB)
+		defer closeDB()
+
+		requireTypedReads(t, db)
+	})
+}
+
+func getInterleavedBlock() (types.EventTables, types.EventData) {
+	cols := make(map[string]types.SQLTableColumn)
+	cols["ID"] = types.SQLTableColumn{Name: "test_id", Type: types.SQLColumnTypeInt, Primary: true, Order: 1}
+	cols["Value"] = types.SQLTableColumn{Name: "val", Type: types.SQLColumnTypeVarchar, Length: 100, Primary: false, Order: 2}
+	cols["Note"] = types.SQLTableColumn{Name: "note", Type: types.SQLColumnTypeVarchar, Length: 100, Primary: false, Order: 3}
+	cols["Height"] = types.SQLTableColumn{Name: "_height", Type: types.SQLColumnTypeVarchar, Length: 100, Primary: false, Order: 4}
+	table := types.SQLTable{Name: "test_interleaved", Filter: "TEST", Columns: cols}
+
+	str := make(types.EventTables)
+	str["1"] = table
+
+	var dat types.EventData
+	dat.Block = "123456789012340"
+	dat.Tables = make(map[string]types.EventDataTable)
+
+	var rows []types.EventDataRow
+	rows = append(rows, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "1", "val": "a", "note": "n1", "_height": dat.Block}})
+	rows = append(rows, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "2", "val": "b", "_height": dat.Block}})
+	rows = append(rows, types.EventDataRow{Action: types.ActionDelete, RowData: map[string]interface{}{"test_id": "1", "_height": dat.Block}})
+	rows = append(rows, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "1", "val": "c", "_height": dat.Block}})
+	rows = append(rows, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "2", "note": "n2", "_height": dat.Block}})
+	rows = append(rows, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "3", "val": "d", "_height": dat.Block}})
+	rows = append(rows, types.EventDataRow{Action: types.ActionDelete, RowData: map[string]interface{}{"test_id": "3", "_height": dat.Block}})
+	dat.Tables["test_interleaved"] = rows
+
+	return str, dat
+}
+
+func requireInterleavedBlock(t *testing.T, getBlock func(string) (types.EventData, error)) {
+	t.Helper()
+
+	dat, err := getBlock("123456789012340")
+	require.NoError(t, err)
+
+	rows := make(map[string]map[string]interface{})
+	for _, row := range dat.Tables["test_interleaved"] {
+		rows[fmt.Sprint(row.RowData["test_id"])] = row.RowData
+	}
+
+	require.Len(t, rows, 2)
+	require.Equal(t, int64(1), rows["1"]["test_id"])
+	require.Equal(t, "c", rows["1"]["val"])
+	require.Contains(t, rows["1"], "note")
+	require.Nil(t, rows["1"]["note"])
+	require.Equal(t, "b", rows["2"]["val"])
+	require.Equal(t, "n2", rows["2"]["note"])
+}
+
+func getNoPrimaryKeyBlocks() (types.EventTables, []types.EventData) {
+	cols := make(map[string]types.SQLTableColumn)
+	cols["Value"] = types.SQLTableColumn{Name: "val", Type: types.SQLColumnTypeVarchar, Length: 100, Primary: false, Order: 1}
+	cols["Height"] = types.SQLTableColumn{Name: "_height", Type: types.SQLColumnTypeBigInt, Primary: false, Order: 2}
+	str := types.EventTables{"1": types.SQLTable{Name: "test_nopk", Filter: "TEST", Columns: cols}}
+
+	blocks := []types.EventData{
+		{Block: "100", Tables: map[string]types.EventDataTable{"test_nopk": {
+			{Action: types.ActionUpsert, RowData: map[string]interface{}{"val": "a", "_height": "100"}},
+		}}},
+		{Block: "101", Tables: map[string]types.EventDataTable{"test_nopk": {
+			{Action: types.ActionUpsert, RowData: map[string]interface{}{"val": "b", "_height": "101"}},
+			{Action: types.ActionUpsert, RowData: map[string]interface{}{"val": "c", "_height": "101"}},
+		}}},
+	}
+
+	return str, blocks
+}
+
+func requireNoPrimaryKey(t *testing.T, db *sqldb.SQLDB) {
+	t.Helper()
+
+	str, blocks := getNoPrimaryKeyBlocks()
+	for _, dat := range blocks {
+		err := db.SetBlock(str, dat)
+		require.NoError(t, err)
+	}
+
+	for _, dat := range blocks {
+		read, err := db.GetBlock(dat.Block)
+		require.NoError(t, err)
+		require.Len(t, read.Tables["test_nopk"], len(dat.Tables["test_nopk"]))
+	}
+
+	// rows cannot be identified so their history is not available
+	_, err := db.GetTableAsOf("test_nopk", 100)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no primary key")
+
+	err = db.RollbackToHeight(100)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no primary key")
+}
+
+// getLargeBlocks returns blocks whose rows are written in several multi-row queries of varying size
+func getLargeBlocks() (types.EventTables, []types.EventData) {
+	cols := make(map[string]types.SQLTableColumn)
+	cols["ID"] = types.SQLTableColumn{Name: "test_id", Type: types.SQLColumnTypeInt, Primary: true, Order: 1}
+	cols["Height"] = types.SQLTableColumn{Name: "_height", Type: types.SQLColumnTypeBigInt, Primary: false, Order: 2}
+	str := types.EventTables{"1": types.SQLTable{Name: "test_large", Filter: "TEST", Columns: cols}}
+
+	var first, second types.EventDataTable
+	for id := 0; id < 1500; id++ {
+		first = append(first, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": id, "_height": "100"}})
+	}
+	for id := 0; id < 1200; id++ {
+		second = append(second, types.EventDataRow{Action: types.ActionDelete, RowData: map[string]interface{}{"test_id": id, "_height": "101"}})
+	}
+	for id := 1200; id < 1300; id++ {
+		second = append(second, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": id, "_height": "101"}})
+	}
+
+	blocks := []types.EventData{
+		{Block: "100", Tables: map[string]types.EventDataTable{"test_large": first}},
+		{Block: "101", Tables: map[string]types.EventDataTable{"test_large": second}},
+	}
+
+	return str, blocks
+}
+
+func requireLargeBlocks(t *testing.T, db *sqldb.SQLDB) {
+	t.Helper()
+
+	str, blocks := getLargeBlocks()
+	for _, dat := range blocks {
+		err := db.SetBlock(str, dat)
+		require.NoError(t, err)
+	}
+
+	read, err := db.GetBlock("100")
+	require.NoError(t, err)
+	require.Len(t, read.Tables["test_large"], 200)
+
+	read, err = db.GetBlock("101")
+	require.NoError(t, err)
+	require.Len(t, read.Tables["test_large"], 100)
+
+	// every row change is logged, so the first block can be rolled back to
+	err = db.RollbackToHeight(100)
+	require.NoError(t, err)
+
+	read, err = db.GetBlock("100")
+	require.NoError(t, err)
+	require.Len(t, read.Tables["test_large"], 1500)
+}
+
+func requireStmtReuse(t *testing.T, db *sqldb.SQLDB) {
+	t.Helper()
+
+	cols := make(map[string]types.SQLTableColumn)
+	cols["ID"] = types.SQLTableColumn{Name: "test_id", Type: types.SQLColumnTypeInt, Primary: true, Order: 1}
+	cols["Height"] = types.SQLTableColumn{Name: "_height", Type: types.SQLColumnTypeBigInt, Primary: false, Order: 2}
+	str := types.EventTables{"1": types.SQLTable{Name: "test_stmts", Filter: "TEST", Columns: cols}}
+
+	setBlocks := func(height int) {
+		// blocks of 7, 5, 3 & 6 rows are written with chunks of 4, 2 & 1 rows
+		for _, size := range []int{7, 5, 3, 6} {
+			block := strconv.Itoa(height)
+			height++
+
+			var rows types.EventDataTable
+			for id := 0; id < size; id++ {
+				rows = append(rows, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": id, "_height": block}})
+			}
+			rows = append(rows, types.EventDataRow{Action: types.ActionDelete, RowData: map[string]interface{}{"test_id": 0, "_height": block}})
+
+			err := db.SetBlock(str, types.EventData{Block: block, Tables: map[string]types.EventDataTable{"test_stmts": rows}})
+			require.NoError(t, err)
+
+			read, err := db.GetBlock(block)
+			require.NoError(t, err)
+			require.Len(t, read.Tables["test_stmts"], size-1)
+		}
+	}
+
+	setBlocks(100)
+	stmts, logStmts := db.CachedStmts("test_stmts"), db.CachedStmts(types.SQLLogTableName)
+	require.NotZero(t, stmts)
+	require.NotZero(t, logStmts)
+
+	// the same chunk sizes are written with the statements prepared for previous blocks
+	setBlocks(200)
+	require.Equal(t, stmts, db.CachedStmts("test_stmts"))
+	require.Equal(t, logStmts, db.CachedStmts(types.SQLLogTableName))
+}
+
+func getRollbackBlocks() (types.EventTables, []types.EventData) {
+	cols := make(map[string]types.SQLTableColumn)
+	cols["ID"] = types.SQLTableColumn{Name: "test_id", Type: types.SQLColumnTypeInt, Primary: true, Order: 1}
+	cols["Value"] = types.SQLTableColumn{Name: "val", Type: types.SQLColumnTypeVarchar, Length: 100, Primary: false, Order: 2}
+	cols["Note"] = types.SQLTableColumn{Name: "note", Type: types.SQLColumnTypeVarchar, Length: 100, Primary: false, Order: 3}
+	cols["Height"] = types.SQLTableColumn{Name: "_height", Type: types.SQLColumnTypeBigInt, Primary: false, Order: 4}
+	cols["Data"] = types.SQLTableColumn{Name: "bin", Type: types.SQLColumnTypeByteA, Primary: false, Order: 5}
+	str := types.EventTables{"1": types.SQLTable{Name: "test_rollback", Filter: "TEST", Columns: cols}}
+
+	blocks := []types.EventData{
+		{Block: "100", Tables: map[string]types.EventDataTable{"test_rollback": {
+			{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "1", "val": "a", "note": "n1", "_height": "100", "bin": []byte{1, 2, 3}}},
+			{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "2", "val": "b", "_height": "100"}},
+		}}},
+		{Block: "101", Tables: map[string]types.EventDataTable{"test_rollback": {
+			{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "1", "val": "a2", "_height": "101", "bin": []byte{4, 5}}},
+			{Action: types.ActionDelete, RowData: map[string]interface{}{"test_id": "2", "_height": "101"}},
+			{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "3", "val": "c", "_height": "101"}},
+		}}},
+		{Block: "102", Tables: map[string]types.EventDataTable{"test_rollback": {
+			{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "2", "val": "b2", "note": "n2", "_height": "102"}},
+			{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "1", "note": "n3", "_height": "102"}},
+			{Action: types.ActionDelete, RowData: map[string]interface{}{"test_id": "3", "_height": "102"}},
+		}}},
+	}
+
+	return str, blocks
+}
+
+func requireRollback(t *testing.T, db *sqldb.SQLDB) {
+	t.Helper()
+
+	str, blocks := getRollbackBlocks()
+	for _, dat := range blocks {
+		err := db.SetBlock(str, dat)
+		require.NoError(t, err)
+	}
+
+	err := db.RollbackToHeight(100)
+	require.NoError(t, err)
+
+	// tables are back to their state at height 100
+	dat, err := db.GetBlock("100")
+	require.NoError(t, err)
+
+	rows := make(map[string]map[string]interface{})
+	for _, row := range dat.Tables["test_rollback"] {
+		rows[fmt.Sprint(row.RowData["test_id"])] = row.RowData
+	}
+
+	require.Len(t, rows, 2)
+	require.Equal(t, "a", rows["1"]["val"])
+	require.Equal(t, "n1", rows["1"]["note"])
+	require.Equal(t, []byte{1, 2, 3}, rows["1"]["bin"])
+	require.Equal(t, "b", rows["2"]["val"])
+	require.Nil(t, rows["2"]["note"])
+	require.Equal(t, int64(100), rows["2"]["_height"])
+
+	dat, err = db.GetBlock("101")
+	require.NoError(t, err)
+	require.Empty(t, dat.Tables)
+
+	// checkpoint is reset & later blocks can be consumed again
+	id, err := db.GetLastBlockID()
+	require.NoError(t, err)
+	require.Equal(t, "100", id)
+
+	err = db.SetBlock(str, blocks[1])
+	require.NoError(t, err)
+
+	dat, err = db.GetBlock("101")
+	require.NoError(t, err)
+	require.Len(t, dat.Tables["test_rollback"], 2)
+}
+
+func requireRestore(t *testing.T, db *sqldb.SQLDB) {
+	t.Helper()
+
+	str, blocks := getRollbackBlocks()
+	for _, dat := range blocks {
+		err := db.SetBlock(str, dat)
+		require.NoError(t, err)
+	}
+
+	err := db.Restore(sqldb.RestoreOptions{Height: 101})
+	require.Error(t, err)
+
+	restoredRows := 0
+	err = db.Restore(sqldb.RestoreOptions{Height: 101, Prefix: "restored", Progress: func(rows int) { restoredRows = rows }})
+	require.NoError(t, err)
+	require.Equal(t, 5, restoredRows)
+
+	tableName := "restored_test_rollback"
+	if db.Schema != "" {
+		tableName = db.Schema + "." + tableName
+	}
+
+	rows, err := db.DB.Query(fmt.Sprintf("SELECT test_id, val, note, bin FROM %s ORDER BY test_id", tableName))
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var restored []string
+	for rows.Next() {
+		var id int
+		var val string
+		var note sql.NullString
+		var bin []byte
+		err = rows.Scan(&id, &val, &note, &bin)
+		require.NoError(t, err)
+		restored = append(restored, fmt.Sprintf("%d %s %s %x", id, val, note.String, bin))
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, []string{"1 a2 n1 0405", "3 c  "}, restored)
+
+	// source tables are untouched
+	dat, err := db.GetBlock("102")
+	require.NoError(t, err)
+	require.Len(t, dat.Tables["test_rollback"], 2)
+}
+
+func requireTableAsOf(t *testing.T, db *sqldb.SQLDB) {
+	t.Helper()
+
+	str, blocks := getRollbackBlocks()
+	for _, dat := range blocks {
+		err := db.SetBlock(str, dat)
+		require.NoError(t, err)
+	}
+
+	asOf := func(height uint64) []string {
+		rows, err := db.GetTableAsOf("test_rollback", height)
+		require.NoError(t, err)
+
+		values := make([]string, 0, len(rows))
+		for _, row := range rows {
+			values = append(values, fmt.Sprintf("%v %v %v %v %v", row.RowData["test_id"], row.RowData["val"], row.RowData["note"], row.RowData["_height"], row.RowData["bin"]))
+		}
+		sort.Strings(values)
+		return values
+	}
+
+	require.Empty(t, asOf(99))
+	require.Equal(t, []string{"1 a n1 100 [1 2 3]", "2 b <nil> 100 <nil>"}, asOf(100))
+	require.Equal(t, []string{"1 a2 n1 101 [4 5]", "3 c <nil> 101 <nil>"}, asOf(101))
+	require.Equal(t, []string{"1 a2 n3 102 [4 5]", "2 b2 n2 102 <nil>"}, asOf(102))
+	require.Equal(t, asOf(102), asOf(1000))
+}
+
+func requireCompactLog(t *testing.T, db *sqldb.SQLDB) {
+	t.Helper()
+
+	str, blocks := getRollbackBlocks()
+	for _, dat := range blocks {
+		err := db.SetBlock(str, dat)
+		require.NoError(t, err)
+	}
+
+	dir, err := ioutil.TempDir("", "vent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = db.CompactLog(101, dir)
+	require.NoError(t, err)
+
+	// compacted entries are archived
+	archive, err := ioutil.ReadFile(filepath.Join(dir, "_vent_log_1_101.jsonl"))
+	require.NoError(t, err)
+	require.Len(t, strings.Split(strings.TrimSpace(string(archive)), "\n"), 5)
+
+	id, err := db.GetLastBlockID()
+	require.NoError(t, err)
+	require.Equal(t, "102", id)
+
+	// features relying on compacted entries report it
+	err = db.RollbackToHeight(100)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "compacted")
+
+	_, err = db.GetTableAsOf("test_rollback", 100)
+	require.Error(t, err)
+
+	err = db.Restore(sqldb.RestoreOptions{Height: 101, Prefix: "restored"})
+	require.Error(t, err)
+
+	rows, err := db.GetTableAsOf("test_rollback", 101)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	// checkpoints keep track of compacted blocks
+	err = db.RollbackToHeight(101)
+	require.NoError(t, err)
+
+	id, err = db.GetLastBlockID()
+	require.NoError(t, err)
+	require.Equal(t, "101", id)
+}
+
+func requireIdentifiers(t *testing.T, db *sqldb.SQLDB) {
+	t.Helper()
+
+	// keywords, spaces & symbols are valid names
+	cols := make(map[string]types.SQLTableColumn)
+	cols["From"] = types.SQLTableColumn{Name: "from", Type: types.SQLColumnTypeInt, Primary: true, Order: 1}
+	cols["Select"] = types.SQLTableColumn{Name: "select", Type: types.SQLColumnTypeVarchar, Length: 100, Order: 2}
+	cols["GroupBy"] = types.SQLTableColumn{Name: "group by-1;", Type: types.SQLColumnTypeVarchar, Length: 100, Order: 3}
+	cols["Height"] = types.SQLTableColumn{Name: "_height", Type: types.SQLColumnTypeBigInt, Order: 4}
+	str := types.EventTables{"1": types.SQLTable{Name: "order table", Filter: "TEST", Columns: cols}}
+
+	err := db.SynchronizeDB(str)
+	require.NoError(t, err)
+
+	dat := types.EventData{Block: "1", Tables: map[string]types.EventDataTable{"order table": {
+		{Action: types.ActionUpsert, RowData: map[string]interface{}{"from": "1", "select": "a", "group by-1;": "b", "_height": "1"}},
+		{Action: types.ActionUpsert, RowData: map[string]interface{}{"from": "2", "select": "c", "_height": "1"}},
+	}}}
+	err = db.SetBlock(str, dat)
+	require.NoError(t, err)
+
+	read, err := db.GetBlock("1")
+	require.NoError(t, err)
+	require.Len(t, read.Tables["order table"], 2)
+
+	rows, err := db.GetTableAsOf("order table", 1)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	// invalid names are rejected before touching the database
+	cols["Quote"] = types.SQLTableColumn{Name: "it's", Type: types.SQLColumnTypeVarchar, Length: 100, Order: 5}
+	err = db.SynchronizeDB(str)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid character")
+
+	found, err := db.GetBlock("1")
+	require.NoError(t, err)
+	require.NotContains(t, found.Tables["order table"][0].RowData, "it's")
+}
+
+func requireSchemaDrift(t *testing.T, db *sqldb.SQLDB) {
+	t.Helper()
+
+	str, dat := getBlock()
+	err := db.SetBlock(str, dat)
+	require.NoError(t, err)
+
+	drifts, err := db.CheckSchema()
+	require.NoError(t, err)
+	require.Empty(t, drifts)
+
+	// alter the table by hand, behind the dictionary's back
+	_, err = db.DB.Exec(db.DBAdapter.DropViewQuery(adapters.HistoryViewName("test_table2")))
+	require.NoError(t, err)
+	_, err = db.DB.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", db.DBAdapter.SecureTableName("test_table2"), db.DBAdapter.SecureColumnName("field_2")))
+	require.NoError(t, err)
+	_, err = db.DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s INTEGER", db.DBAdapter.SecureTableName("test_table2"), db.DBAdapter.SecureColumnName("extra")))
+	require.NoError(t, err)
+
+	drifts, err = db.CheckSchema()
+	require.NoError(t, err)
+	require.Equal(t, []sqldb.SchemaDrift{
+		{Kind: sqldb.SchemaDriftMissingColumn, Table: "test_table2", Column: "field_2"},
+		{Kind: sqldb.SchemaDriftUnexpectedColumn, Table: "test_table2", Column: "extra"},
+	}, drifts)
+
+	// synchronizing cannot fix the table, the block is retried only once
+	dat.Block = "123456789012350"
+	err = db.SetBlock(str, dat)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "schema drift found: missing column test_table2.field_2")
+
+	// unexpected columns are only dropped if destructive changes are allowed
+	drifts, err = db.RepairSchema()
+	require.NoError(t, err)
+	require.Len(t, drifts, 1)
+	require.Equal(t, sqldb.SchemaDriftUnexpectedColumn, drifts[0].Kind)
+
+	db.AllowDestructive = true
+	drifts, err = db.RepairSchema()
+	require.NoError(t, err)
+	require.Empty(t, drifts)
+
+	err = db.SetBlock(str, dat)
+	require.NoError(t, err)
+
+	rows, err := db.GetTableAsOf("test_table2", 123456789012350)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+}
+
+func requireEnrichedViews(t *testing.T, db *sqldb.SQLDB) {
+	t.Helper()
+
+	blockCols := make(map[string]types.SQLTableColumn)
+	blockCols["height"] = types.SQLTableColumn{Name: "_height", Type: types.SQLColumnTypeBigInt, Primary: true, Order: 1}
+	blockCols["blockTime"] = types.SQLTableColumn{Name: "_blocktime", Type: types.SQLColumnTypeTimeStamp, Order: 2}
+
+	txCols := make(map[string]types.SQLTableColumn)
+	txCols["height"] = types.SQLTableColumn{Name: "_height", Type: types.SQLColumnTypeBigInt, Primary: true, Order: 1}
+	txCols["txHash"] = types.SQLTableColumn{Name: "_txhash", Type: types.SQLColumnTypeVarchar, Length: 40, Primary: true, Order: 2}
+	txCols["txType"] = types.SQLTableColumn{Name: "_txtype", Type: types.SQLColumnTypeVarchar, Length: 100, Order: 3}
+	txCols["caller"] = types.SQLTableColumn{Name: "_caller", Type: types.SQLColumnTypeVarchar, Length: 40, Order: 4}
+
+	cols := make(map[string]types.SQLTableColumn)
+	cols["height"] = types.SQLTableColumn{Name: "_height", Type: types.SQLColumnTypeBigInt, Order: 1}
+	cols["txHash"] = types.SQLTableColumn{Name: "_txhash", Type: types.SQLColumnTypeVarchar, Length: 40, Order: 2}
+	cols["id"] = types.SQLTableColumn{Name: "id", Type: types.SQLColumnTypeInt, Primary: true, Order: 3}
+
+	str := types.EventTables{
+		types.SQLBlockTableName: types.SQLTable{Name: types.SQLBlockTableName, Columns: blockCols},
+		types.SQLTxTableName:    types.SQLTable{Name: types.SQLTxTableName, Columns: txCols},
+		"1":                     types.SQLTable{Name: "transfers", Filter: "TEST", Columns: cols},
+	}
+
+	dat := types.EventData{Block: "10", Tables: map[string]types.EventDataTable{
+		types.SQLBlockTableName: {{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "10", "_blocktime": "2019-01-02 15:04:05"}}},
+		types.SQLTxTableName:    {{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "10", "_txhash": "AB01", "_txtype": "CallTx", "_caller": "CA11E5"}}},
+		"transfers": {
+			{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "10", "_txhash": "AB01", "id": "1"}},
+			{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "10", "_txhash": "FF00", "id": "2"}},
+		},
+	}}
+
+	err := db.SynchronizeDB(str)
+	require.NoError(t, err)
+	err = db.SetBlock(str, dat)
+	require.NoError(t, err)
+
+	selectEnriched := func() []sql.NullString {
+		rows, err := db.DB.Query(fmt.Sprintf("SELECT _blocktime, _txtype, _caller FROM %s ORDER BY id", db.DBAdapter.SecureTableName(adapters.EnrichedViewName("transfers"))))
+		require.NoError(t, err)
+		defer rows.Close()
+
+		values := make([]sql.NullString, 0)
+		for rows.Next() {
+			var blockTime, txType, caller sql.NullString
+			require.NoError(t, rows.Scan(&blockTime, &txType, &caller))
+			require.True(t, blockTime.Valid)
+			values = append(values, txType, caller)
+		}
+		require.NoError(t, rows.Err())
+		return values
+	}
+
+	// rows without a matching tx keep null tx data
+	require.Equal(t, []sql.NullString{{String: "CallTx", Valid: true}, {String: "CA11E5", Valid: true}, {}, {}}, selectEnriched())
+
+	// views follow table structure changes
+	cols["amount"] = types.SQLTableColumn{Name: "amount", Type: types.SQLColumnTypeInt, Order: 4}
+	err = db.SynchronizeDB(str)
+	require.NoError(t, err)
+
+	rows, err := db.DB.Query(fmt.Sprintf("SELECT amount FROM %s", db.DBAdapter.SecureTableName(adapters.EnrichedViewName("transfers"))))
+	require.NoError(t, err)
+	rows.Close()
+}
+
+func requireJSONColumns(t *testing.T, db *sqldb.SQLDB) types.EventTables {
+	t.Helper()
+
+	txCols := make(map[string]types.SQLTableColumn)
+	txCols["height"] = types.SQLTableColumn{Name: "_height", Type: types.SQLColumnTypeBigInt, Primary: true, Order: 1}
+	txCols["txHash"] = types.SQLTableColumn{Name: "_txhash", Type: types.SQLColumnTypeVarchar, Length: 40, Primary: true, Order: 2}
+	txCols["receipt"] = types.SQLTableColumn{Name: "_receipt", Type: types.SQLColumnTypeJSON, Order: 3}
+
+	str := types.EventTables{types.SQLTxTableName: types.SQLTable{Name: types.SQLTxTableName, Columns: txCols}}
+
+	setTx := func(height, txHash, receipt string) {
+		dat := types.EventData{Block: height, Tables: map[string]types.EventDataTable{
+			types.SQLTxTableName: {{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": height, "_txhash": txHash, "_receipt": receipt}}},
+		}}
+		err := db.SetBlock(str, dat)
+		require.NoError(t, err)
+	}
+
+	err := db.SynchronizeDB(str)
+	require.NoError(t, err)
+	setTx("10", "AB01", `{"CreatesContract": true, "ContractAddress": "C0FFEE"}`)
+
+	// json columns are migrated in place, indexed & their paths extracted (if the adapter supports it)
+	txCols["receipt"] = types.SQLTableColumn{Name: "_receipt", Type: types.SQLColumnTypeJSONB, Order: 3, Index: types.SQLIndexGIN}
+	str[types.SQLTxTableName] = types.SQLTable{Name: types.SQLTxTableName, Columns: txCols, JSONPaths: []types.SQLJSONPath{
+		{Name: "_contractaddress", Column: "_receipt", Path: "$.ContractAddress"},
+	}}
+
+	for i := 0; i < 2; i++ {
+		err = db.SynchronizeDB(str)
+		require.NoError(t, err)
+	}
+	setTx("11", "AB02", `{"CreatesContract": false}`)
+
+	drifts, err := db.CheckSchema()
+	require.NoError(t, err)
+	require.Empty(t, drifts)
+
+	var receipt string
+	err = db.DB.QueryRow(fmt.Sprintf("SELECT _receipt FROM %s WHERE _txhash = 'AB01'", db.DBAdapter.SecureTableName(types.SQLTxTableName))).Scan(&receipt)
+	require.NoError(t, err)
+	require.Contains(t, receipt, "C0FFEE")
+
+	if _, ok := db.DBAdapter.(adapters.JSONPathAdapter); ok {
+		var address sql.NullString
+		rows, err := db.DB.Query(fmt.Sprintf("SELECT _contractaddress FROM %s ORDER BY _height", db.DBAdapter.SecureTableName(types.SQLTxTableName)))
+		require.NoError(t, err)
+		defer rows.Close()
+
+		addresses := make([]sql.NullString, 0)
+		for rows.Next() {
+			require.NoError(t, rows.Scan(&address))
+			addresses = append(addresses, address)
+		}
+		require.NoError(t, rows.Err())
+		require.Equal(t, []sql.NullString{{String: "C0FFEE", Valid: true}, {}}, addresses)
+	}
+
+	return str
+}
+
+func requirePartitioning(t *testing.T, db *sqldb.SQLDB) types.EventTables {
+	t.Helper()
+
+	cols := make(map[string]types.SQLTableColumn)
+	cols["index"] = types.SQLTableColumn{Name: "index", Type: types.SQLColumnTypeInt, Primary: true, Order: 1}
+	cols["amount"] = types.SQLTableColumn{Name: "amount", Type: types.SQLColumnTypeInt, Order: 2}
+	cols["height"] = types.SQLTableColumn{Name: "_height", Type: types.SQLColumnTypeBigInt, Primary: true, Order: 3}
+
+	str := types.EventTables{"transfers": types.SQLTable{Name: "transfers", Filter: "TEST", Columns: cols, PartitionRange: 10}}
+
+	setTransfer := func(height, index, amount string) {
+		dat := types.EventData{Block: height, Tables: map[string]types.EventDataTable{
+			"transfers": {{Action: types.ActionUpsert, RowData: map[string]interface{}{"index": index, "amount": amount, "_height": height}}},
+		}}
+		err := db.SetBlock(str, dat)
+		require.NoError(t, err)
+	}
+
+	err := db.SynchronizeDB(str)
+	require.NoError(t, err)
+
+	// rows are kept for each height, whichever partition holds them
+	setTransfer("5", "1", "100")
+	setTransfer("15", "1", "200")
+	setTransfer("25", "2", "300")
+
+	count := 0
+	err = db.DB.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = 1", db.DBAdapter.SecureTableName("transfers"), db.DBAdapter.SecureColumnName("index"))).Scan(&count)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	eventData, err := db.GetBlock("15")
+	require.NoError(t, err)
+	require.Len(t, eventData.Tables["transfers"], 1)
+
+	drifts, err := db.CheckSchema()
+	require.NoError(t, err)
+	require.Empty(t, drifts)
+
+	// partitioning can't be changed once the table is created
+	notPartitioned := str["transfers"]
+	notPartitionedCols := make(map[string]types.SQLTableColumn)
+	for name, column := range cols {
+		notPartitionedCols[name] = column
+	}
+	height := notPartitionedCols["height"]
+	height.Primary = false
+	notPartitionedCols["height"] = height
+	notPartitioned.Columns = notPartitionedCols
+	notPartitioned.PartitionRange = 0
+
+	err = db.SynchronizeDB(types.EventTables{"transfers": notPartitioned})
+	require.Error(t, err)
+
+	return str
+}
+
+func requireFullTextSearch(t *testing.T, db *sqldb.SQLDB) {
+	t.Helper()
+
+	cols := make(map[string]types.SQLTableColumn)
+	cols["key"] = types.SQLTableColumn{Name: "key", Type: types.SQLColumnTypeInt, Primary: true, Order: 1}
+	cols["memo"] = types.SQLTableColumn{Name: "memo", Type: types.SQLColumnTypeText, Order: 2, FullText: true}
+	cols["height"] = types.SQLTableColumn{Name: "_height", Type: types.SQLColumnTypeBigInt, Order: 3}
+
+	str := types.EventTables{"payments": types.SQLTable{Name: "payments", Filter: "TEST", Columns: cols}}
+
+	setPayment := func(height string, action types.DBAction, key, memo string) {
+		dat := types.EventData{Block: height, Tables: map[string]types.EventDataTable{
+			"payments": {{Action: action, RowData: map[string]interface{}{"key": key, "memo": memo, "_height": height}}},
+		}}
+		err := db.SetBlock(str, dat)
+		require.NoError(t, err)
+	}
+
+	search := func(text string) []string {
+		rows, err := db.Search("payments", "memo", text, 10)
+		require.NoError(t, err)
+
+		keys := make([]string, len(rows))
+		for i, row := range rows {
+			keys[i] = fmt.Sprint(row.RowData["key"])
+		}
+		sort.Strings(keys)
+		return keys
+	}
+
+	err := db.SynchronizeDB(str)
+	require.NoError(t, err)
+
+	setPayment("1", types.ActionUpsert, "1", "Invoice for March rent")
+	setPayment("2", types.ActionUpsert, "2", "march groceries")
+	setPayment("3", types.ActionUpsert, "3", "Refund \"invoice\" - April")
+
+	if _, ok := db.DBAdapter.(adapters.FullTextAdapter); !ok {
+		_, err = db.Search("payments", "memo", "march", 10)
+		require.Error(t, err)
+		return
+	}
+
+	require.Equal(t, []string{"1", "2"}, search("March"))
+	require.Equal(t, []string{"1"}, search("  invoice   march "))
+	require.Equal(t, []string{"1", "3"}, search(`"invoice"`))
+	require.Empty(t, search("may"))
+
+	// search structures follow updates & deletes and survive synchronization
+	setPayment("4", types.ActionUpsert, "2", "may groceries")
+	setPayment("5", types.ActionDelete, "3", "")
+
+	err = db.SynchronizeDB(str)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"1"}, search("march"))
+	require.Equal(t, []string{"2"}, search("may"))
+	require.Empty(t, search("refund"))
+
+	drifts, err := db.CheckSchema()
+	require.NoError(t, err)
+	require.Empty(t, drifts)
+}
+
+func requireFullTextRebuilds(t *testing.T, db *sqldb.SQLDB) {
+	t.Helper()
+
+	cols := make(map[string]types.SQLTableColumn)
+	cols["key"] = types.SQLTableColumn{Name: "key", Type: types.SQLColumnTypeInt, Primary: true, Order: 1}
+	cols["memo"] = types.SQLTableColumn{Name: "memo", Type: types.SQLColumnTypeText, Order: 2, FullText: true}
+	cols["title"] = types.SQLTableColumn{Name: "title", Type: types.SQLColumnTypeText, Order: 3}
+	cols["ref"] = types.SQLTableColumn{Name: "ref", Type: types.SQLColumnTypeVarchar, Length: 10, Order: 4}
+	cols["height"] = types.SQLTableColumn{Name: "_height", Type: types.SQLColumnTypeBigInt, Order: 5}
+
+	str := types.EventTables{"notes": types.SQLTable{Name: "notes", Filter: "TEST", Columns: cols}}
+
+	setNote := func(height, key, memo, title string) {
+		dat := types.EventData{Block: height, Tables: map[string]types.EventDataTable{
+			"notes": {{Action: types.ActionUpsert, RowData: map[string]interface{}{"key": key, "memo": memo, "title": title, "ref": "r" + key, "_height": height}}},
+		}}
+		err := db.SetBlock(str, dat)
+		require.NoError(t, err)
+	}
+
+	search := func(columnName, text string) []string {
+		rows, err := db.Search("notes", columnName, text, 10)
+		require.NoError(t, err)
+
+		keys := make([]string, len(rows))
+		for i, row := range rows {
+			keys[i] = fmt.Sprint(row.RowData["key"])
+		}
+		sort.Strings(keys)
+		return keys
+	}
+
+	// removes a row from the index only, so that a rebuild is noticed
+	unindex := func(key string) {
+		_, err := db.DB.Exec(`INSERT INTO notes_fts (notes_fts, rowid, memo) SELECT 'delete', rowid, memo FROM notes WHERE key = $1;`, key)
+		require.NoError(t, err)
+		require.Empty(t, search("memo", "march"))
+	}
+
+	err := db.SynchronizeDB(str)
+	require.NoError(t, err)
+
+	setNote("1", "1", "march rent", "alpha")
+	setNote("2", "2", "may groceries", "beta")
+	require.Equal(t, []string{"1"}, search("memo", "march"))
+
+	// synchronizing an unchanged table keeps the index as is
+	unindex("1")
+	err = db.SynchronizeDB(str)
+	require.NoError(t, err)
+	require.Empty(t, search("memo", "march"))
+
+	// the index is recreated with the full-text columns
+	title := cols["title"]
+	title.FullText = true
+	cols["title"] = title
+
+	err = db.SynchronizeDB(str)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1"}, search("memo", "march"))
+	require.Equal(t, []string{"2"}, search("title", "beta"))
+
+	// the index is rebuilt with the table, whose triggers are recreated
+	unindex("1")
+	ref := cols["ref"]
+	ref.Length = 20
+	cols["ref"] = ref
+
+	err = db.SynchronizeDB(str)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1"}, search("memo", "march"))
+
+	setNote("3", "3", "march fees", "gamma")
+	require.Equal(t, []string{"1", "3"}, search("memo", "march"))
+	require.Equal(t, []string{"3"}, search("title", "gamma"))
+}
+
+func requireSQLiteModes(t *testing.T, db *sqldb.SQLDB, connection types.SQLConnection) {
+	t.Helper()
+
+	str, dat := getInterleavedBlock()
+	err := db.SynchronizeDB(str)
+	require.NoError(t, err)
+	err = db.SetBlock(str, dat)
+	require.NoError(t, err)
+	requireInterleavedBlock(t, db.GetBlock)
+
+	// in-memory databases are not shared by connections
+	other, err := sqldb.NewSQLDB(connection)
+	require.NoError(t, err)
+	lastBlock, err := other.GetLastBlockID()
+	require.NoError(t, err)
+	require.Equal(t, "0", lastBlock)
+	other.Close()
+
+	// checkpoints are a no-op without write-ahead log
+	err = db.Checkpoint()
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "vent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	backupFile := filepath.Join(dir, "backup.sqlite")
+	err = db.Backup(backupFile)
+	require.NoError(t, err)
+
+	connection.DBURL = backupFile
+	backup, err := sqldb.NewSQLDB(connection)
+	require.NoError(t, err)
+	defer backup.Close()
+
+	requireInterleavedBlock(t, backup.GetBlock)
+}
+
+func requireTypedReads(t *testing.T, db *sqldb.SQLDB) {
+	t.Helper()
+
+	cols := make(map[string]types.SQLTableColumn)
+	cols["ID"] = types.SQLTableColumn{Name: "test_id", Type: types.SQLColumnTypeInt, Primary: true, Order: 1}
+	cols["Flag"] = types.SQLTableColumn{Name: "flag", Type: types.SQLColumnTypeBool, Order: 2}
+	cols["Amount"] = types.SQLTableColumn{Name: "amount", Type: types.SQLColumnTypeNumeric, Order: 3}
+	cols["Data"] = types.SQLTableColumn{Name: "data", Type: types.SQLColumnTypeByteA, Order: 4}
+	cols["Doc"] = types.SQLTableColumn{Name: "doc", Type: types.SQLColumnTypeJSON, Order: 5}
+	cols["Memo"] = types.SQLTableColumn{Name: "memo", Type: types.SQLColumnTypeText, Order: 6}
+	cols["Note"] = types.SQLTableColumn{Name: "note", Type: types.SQLColumnTypeVarchar, Length: 100, Order: 7}
+	cols["Time"] = types.SQLTableColumn{Name: "created", Type: types.SQLColumnTypeTimeStamp, Order: 8}
+	cols["Height"] = types.SQLTableColumn{Name: "_height", Type: types.SQLColumnTypeBigInt, Order: 9}
+	str := types.EventTables{"1": types.SQLTable{Name: "test_typed", Filter: "TEST", Columns: cols}}
+
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	dat := types.EventData{Block: "100", Tables: map[string]types.EventDataTable{"test_typed": {
+		{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "1", "flag": true, "amount": "12345678901234567890123",
+			"data": []byte{0, 1, 255}, "doc": `{"a": 1}`, "memo": "", "created": created, "_height": "100"}},
+	}}}
+
+	err := db.SynchronizeDB(str)
+	require.NoError(t, err)
+	err = db.SetBlock(str, dat)
+	require.NoError(t, err)
+
+	block, err := db.GetBlock("100")
+	require.NoError(t, err)
+	require.Len(t, block.Tables["test_typed"], 1)
+
+	row := block.Tables["test_typed"][0].RowData
+	require.Equal(t, types.ActionRead, block.Tables["test_typed"][0].Action)
+	require.Equal(t, int64(1), row["test_id"])
+	require.Equal(t, true, row["flag"])
+	require.Equal(t, "12345678901234567890123", row["amount"].(*big.Int).String())
+	require.Equal(t, []byte{0, 1, 255}, row["data"])
+	require.JSONEq(t, `{"a": 1}`, string(row["doc"].(json.RawMessage)))
+	require.Equal(t, int64(100), row["_height"])
+	require.True(t, created.Equal(row["created"].(time.Time)), "%v", row["created"])
+
+	// empty strings & NULL values are told apart
+	require.Equal(t, "", row["memo"])
+	require.Contains(t, row, "note")
+	require.Nil(t, row["note"])
+}
+
+func requireMigration(t *testing.T, db *sqldb.SQLDB) {
+	t.Helper()
+
+	tableStructure, err := sqlsol.NewParserFromBytes([]byte(test.PreMigrationJSONConfFile(t)))
+	require.NoError(t, err)
+	migrationStructure, err := sqlsol.NewParserFromBytes([]byte(test.MigrationJSONConfFile(t)))
+	require.NoError(t, err)
+
+	err = db.SynchronizeDB(tableStructure.GetTables())
+	require.NoError(t, err)
+
+	_, err = db.DB.Exec(fmt.Sprintf("INSERT INTO %s (%s, %s, %s) VALUES ('A1', 'alice', 1);",
+		db.DBAdapter.SecureTableName("useraccounts"), db.DBAdapter.SecureColumnName("address"),
+		db.DBAdapter.SecureColumnName("username"), db.DBAdapter.SecureColumnName(types.SQLColumnLabelHeight)))
+	require.NoError(t, err)
+
+	// dropping a column is destructive
+	err = db.SynchronizeDB(migrationStructure.GetTables())
+	require.Error(t, err)
+
+	db.AllowDestructive = true
+	err = db.SynchronizeDB(migrationStructure.GetTables())
+	require.NoError(t, err)
+
+	// renamed column keeps its data
+	var name string
+	err = db.DB.QueryRow(fmt.Sprintf("SELECT %s FROM %s WHERE %s = 'A1';",
+		db.DBAdapter.SecureColumnName("name"), db.DBAdapter.SecureTableName("useraccounts"),
+		db.DBAdapter.SecureColumnName("address"))).Scan(&name)
+	require.NoError(t, err)
+	require.Equal(t, "alice", name)
+
+	// widened type is recorded in the dictionary
+	var columnType int
+	err = db.DB.QueryRow(fmt.Sprintf("SELECT %s FROM %s WHERE %s = 'test_table' AND %s = 'instance';",
+		types.SQLColumnLabelColumnType, db.DBAdapter.SecureTableName(types.SQLDictionaryTableName),
+		types.SQLColumnLabelTableName, types.SQLColumnLabelColumnName)).Scan(&columnType)
+	require.NoError(t, err)
+	require.Equal(t, int(types.SQLColumnTypeNumeric), columnType)
+
+	// dropped & renamed columns are gone from the catalog
+	queryVal := db.DBAdapter.TableColumnsQuery("useraccounts")
+	rows, err := db.DB.Query(queryVal.Query, queryVal.Pointers...)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	columns := make([]string, 0)
+	for rows.Next() {
+		var columnName, typeName string
+		var length sql.NullInt64
+		require.NoError(t, rows.Scan(&columnName, &typeName, &length))
+		columns = append(columns, columnName)
+	}
+	require.NoError(t, rows.Err())
+	require.Contains(t, columns, "name")
+	require.NotContains(t, columns, "username")
+	require.NotContains(t, columns, "userbool")
+}
+
+func requireArchivedTables(t *testing.T, db *sqldb.SQLDB) {
+	t.Helper()
+
+	str, dat := getBlock()
+	err := db.SetBlock(str, dat)
+	require.NoError(t, err)
+
+	db.ChainIDPolicy = types.ChainIDPolicyArchive
+	err = db.CleanTables("NEW_ID", "Version 1.0")
+	require.NoError(t, err)
+
+	// previous chain data is kept in suffixed tables
+	tableName := "test_table1_id_0123"
+	if db.Schema != "" {
+		tableName = db.Schema + "." + tableName
+	}
+
+	count := 0
+	err = db.DB.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", tableName)).Scan(&count)
+	require.NoError(t, err)
+	require.Equal(t, 4, count)
+
+	// new chain starts from scratch
+	id, err := db.GetLastBlockID()
+	require.NoError(t, err)
+	require.Equal(t, "0", id)
+
+	err = db.SetBlock(str, dat)
+	require.NoError(t, err)
+
+	data, err := db.GetBlock(dat.Block)
+	require.NoError(t, err)
+	require.Len(t, data.Tables["test_table1"], 4)
+}
+
+func requireArchivedNameLimit(t *testing.T, db *sqldb.SQLDB) {
+	t.Helper()
+
+	// store a chain ID too long to suffix table names with
+	db.ChainIDPolicy = types.ChainIDPolicyDrop
+	err := db.CleanTables(strings.Repeat("long_chain_id_", 5), "Version 1.0")
+	require.NoError(t, err)
+
+	str, dat := getBlock()
+	err = db.SetBlock(str, dat)
+	require.NoError(t, err)
+
+	db.ChainIDPolicy = types.ChainIDPolicyArchive
+	err = db.CleanTables("NEW_ID", "Version 1.0")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "too long")
+
+	// no table has been renamed
+	id, err := db.GetLastBlockID()
+	require.NoError(t, err)
+	require.Equal(t, dat.Block, id)
+
+	data, err := db.GetBlock(dat.Block)
+	require.NoError(t, err)
+	require.Len(t, data.Tables["test_table1"], 4)
+}
+
+func requireHeightMigration(t *testing.T, db *sqldb.SQLDB) {
+	t.Helper()
+
+	// table structure built by previous versions
+	cols := make(map[string]types.SQLTableColumn)
+	cols["ID"] = types.SQLTableColumn{Name: "test_id", Type: types.SQLColumnTypeInt, Primary: true, Order: 1}
+	cols["Amount"] = types.SQLTableColumn{Name: "amount", Type: types.SQLColumnTypeBigInt, Primary: false, Order: 2}
+	cols["Height"] = types.SQLTableColumn{Name: "_height", Type: types.SQLColumnTypeVarchar, Length: 100, Primary: false, Order: 3}
+	str := types.EventTables{"1": types.SQLTable{Name: "test_migration", Filter: "TEST", Columns: cols}}
+
+	var dat types.EventData
+	dat.Block = "100"
+	dat.Tables = map[string]types.EventDataTable{"test_migration": {
+		{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "1", "amount": "9223372036854775807", "_height": dat.Block}},
+	}}
+
+	err := db.SetBlock(str, dat)
+	require.NoError(t, err)
+
+	// heights as BIGINT & 256 bit integers as NUMERIC
+	cols["Amount"] = types.SQLTableColumn{Name: "amount", Type: types.SQLColumnTypeNumeric, Primary: false, Order: 2}
+	cols["Height"] = types.SQLTableColumn{Name: "_height", Type: types.SQLColumnTypeBigInt, Primary: false, Order: 3}
+
+	err = db.SynchronizeDB(str)
+	require.NoError(t, err)
+
+	maxUint256 := "115792089237316195423570985008687907853269984665640564039457584007913129639935"
+
+	dat.Block = "101"
+	dat.Tables = map[string]types.EventDataTable{"test_migration": {
+		{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "2", "amount": maxUint256, "_height": dat.Block}},
+	}}
+
+	err = db.SetBlock(str, dat)
+	require.NoError(t, err)
+
+	block, err := db.GetBlock("100")
+	require.NoError(t, err)
+	require.Len(t, block.Tables["test_migration"], 1)
+	require.Equal(t, "9223372036854775807", fmt.Sprint(block.Tables["test_migration"][0].RowData["amount"]))
+
+	block, err = db.GetBlock("101")
+	require.NoError(t, err)
+	require.Len(t, block.Tables["test_migration"], 1)
+	require.Equal(t, maxUint256, fmt.Sprint(block.Tables["test_migration"][0].RowData["amount"]))
+
+	id, err := db.GetLastBlockID()
+	require.NoError(t, err)
+	require.Equal(t, "101", id)
+}
+
+func requireNumericStorageMigration(t *testing.T, db *sqldb.SQLDB, legacyQuery string) {
+	t.Helper()
+
+	cols := make(map[string]types.SQLTableColumn)
+	cols["ID"] = types.SQLTableColumn{Name: "test_id", Type: types.SQLColumnTypeInt, Primary: true, Order: 1}
+	cols["Amount"] = types.SQLTableColumn{Name: "amount", Type: types.SQLColumnTypeNumeric, Primary: false, Order: 2}
+	cols["Height"] = types.SQLTableColumn{Name: "_height", Type: types.SQLColumnTypeBigInt, Primary: false, Order: 3}
+	str := types.EventTables{"1": types.SQLTable{Name: "test_numeric", Filter: "TEST", Columns: cols}}
+
+	err := db.SynchronizeDB(str)
+	require.NoError(t, err)
+
+	// numeric column as stored by previous versions
+	_, err = db.DB.Exec(legacyQuery)
+	require.NoError(t, err)
+
+	drifts, err := db.CheckSchema()
+	require.NoError(t, err)
+	require.Len(t, drifts, 1)
+	require.Equal(t, sqldb.SchemaDriftColumnType, drifts[0].Kind)
+
+	amount := "1000000000000000000000"
+	dat := types.EventData{Block: "100", Tables: map[string]types.EventDataTable{"test_numeric": {
+		{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "1", "amount": amount, "_height": "100"}},
+	}}}
+	err = db.SetBlock(str, dat)
+	require.NoError(t, err)
+
+	// column is converted in place
+	err = db.SynchronizeDB(str)
+	require.NoError(t, err)
+
+	drifts, err = db.CheckSchema()
+	require.NoError(t, err)
+	require.Empty(t, drifts)
+
+	block, err := db.GetBlock("100")
+	require.NoError(t, err)
+	require.Len(t, block.Tables["test_numeric"], 1)
+	require.Equal(t, amount, fmt.Sprint(block.Tables["test_numeric"][0].RowData["amount"]))
 }
 
 func getBlock() (types.EventTables, types.EventData) {
@@ -244,38 +2098,38 @@ func getBlock() (types.EventTables, types.EventData) {
 
 	//---------------------------------------data-------------------------------------
 	var dat types.EventData
-	dat.Block = "0123456789ABCDEF0"
+	dat.Block = "123456789012340"
 	dat.Tables = make(map[string]types.EventDataTable)
 
 	var rows1 []types.EventDataRow
-	rows1 = append(rows1, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "1", "col1": "text11", "col2": "text12", "_height": "0123456789ABCDEF0", "col4": "14", "colV": longtext, "colT": longtext}})
-	rows1 = append(rows1, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "2", "col1": "text21", "col2": "text22", "_height": "0123456789ABCDEF0", "col4": "24", "colV": longtext, "colT": longtext}})
-	rows1 = append(rows1, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "3", "col1": "text31", "col2": "text32", "_height": "0123456789ABCDEF0", "col4": "34", "colV": longtext, "colT": longtext}})
-	rows1 = append(rows1, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "4", "col1": "text41", "col3": "text43", "_height": "0123456789ABCDEF0", "colV": longtext, "colT": longtext}})
-	rows1 = append(rows1, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "1", "col1": "upd", "col2": "upd", "_height": "0123456789ABCDEF0", "col4": "upd", "colV": longtext, "colT": longtext}})
+	rows1 = append(rows1, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "1", "col1": "text11", "col2": "text12", "_height": "123456789012340", "col4": "14", "colV": longtext, "colT": longtext}})
+	rows1 = append(rows1, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "2", "col1": "text21", "col2": "text22", "_height": "123456789012340", "col4": "24", "colV": longtext, "colT": longtext}})
+	rows1 = append(rows1, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "3", "col1": "text31", "col2": "text32", "_height": "123456789012340", "col4": "34", "colV": longtext, "colT": longtext}})
+	rows1 = append(rows1, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "4", "col1": "text41", "col3": "text43", "_height": "123456789012340", "colV": longtext, "colT": longtext}})
+	rows1 = append(rows1, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"test_id": "1", "col1": "upd", "col2": "upd", "_height": "123456789012340", "col4": "upd", "colV": longtext, "colT": longtext}})
 	dat.Tables["test_table1"] = rows1
 
 	var rows2 []types.EventDataRow
-	rows2 = append(rows2, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "0123456789ABCDEF0", "sid_id": "1", "field_1": "A", "field_2": "B"}})
-	rows2 = append(rows2, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "0123456789ABCDEF0", "sid_id": "2", "field_1": "C", "field_2": ""}})
-	rows2 = append(rows2, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "0123456789ABCDEF0", "sid_id": "3", "field_1": "D", "field_2": "E"}})
-	rows2 = append(rows2, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "0123456789ABCDEF0", "sid_id": "1", "field_1": "F"}})
-	rows2 = append(rows2, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "0123456789ABCDEF0", "sid_id": "1", "field_2": "U"}})
+	rows2 = append(rows2, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "123456789012340", "sid_id": "1", "field_1": "A", "field_2": "B"}})
+	rows2 = append(rows2, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "123456789012340", "sid_id": "2", "field_1": "C", "field_2": ""}})
+	rows2 = append(rows2, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "123456789012340", "sid_id": "3", "field_1": "D", "field_2": "E"}})
+	rows2 = append(rows2, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "123456789012340", "sid_id": "1", "field_1": "F"}})
+	rows2 = append(rows2, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "123456789012340", "sid_id": "1", "field_2": "U"}})
 	dat.Tables["test_table2"] = rows2
 
 	var rows3 []types.EventDataRow
-	rows3 = append(rows3, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "0123456789ABCDEF1", "val": "1"}})
-	rows3 = append(rows3, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "0123456789ABCDEF2", "val": "2"}})
-	rows3 = append(rows3, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "0123456789ABCDEFX", "val": "-1"}})
-	rows3 = append(rows3, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "0123456789ABCDEF0"}})
+	rows3 = append(rows3, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "123456789012341", "val": "1"}})
+	rows3 = append(rows3, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "123456789012342", "val": "2"}})
+	rows3 = append(rows3, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "12345678901234X", "val": "-1"}})
+	rows3 = append(rows3, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "123456789012340"}})
 	dat.Tables["test_table3"] = rows3
 
 	var rows4 []types.EventDataRow
-	rows4 = append(rows4, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "0123456789ABCDEF0", "time": "2006-01-01 15:04:05", "index": "1"}})
-	rows4 = append(rows4, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "0123456789ABCDEF0", "time": "2006-01-02 15:04:05", "index": "2"}})
-	rows4 = append(rows4, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "0123456789ABCDEF0", "time": "2006-01-03 15:04:05", "index": "3"}})
-	rows4 = append(rows4, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "0123456789ABCDEF0", "time": "2006-01-03 15:04:05", "index": "4"}})
-	rows4 = append(rows4, types.EventDataRow{Action: types.ActionDelete, RowData: map[string]interface{}{"_height": "0123456789ABCDEF0", "time": "2006-01-03 15:04:05", "index": "3"}})
+	rows4 = append(rows4, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "123456789012340", "time": "2006-01-01 15:04:05", "index": "1"}})
+	rows4 = append(rows4, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "123456789012340", "time": "2006-01-02 15:04:05", "index": "2"}})
+	rows4 = append(rows4, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "123456789012340", "time": "2006-01-03 15:04:05", "index": "3"}})
+	rows4 = append(rows4, types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{"_height": "123456789012340", "time": "2006-01-03 15:04:05", "index": "4"}})
+	rows4 = append(rows4, types.EventDataRow{Action: types.ActionDelete, RowData: map[string]interface{}{"_height": "123456789012340", "time": "2006-01-03 15:04:05", "index": "3"}})
 	dat.Tables["test_table4"] = rows4
 
 	return str, dat
@@ -0,0 +1,77 @@
+package sqldb
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// maxTableStmts is the maximum number of prepared statements cached for each table
+const maxTableStmts = 64
+
+// stmtCache stores prepared statements by table name and query text,
+// statements of a table must be invalidated when the table structure changes
+type stmtCache struct {
+	sync.Mutex
+	tables map[string]map[string]*sql.Stmt
+}
+
+// prepare returns a cached prepared statement for a table query, preparing it if not found
+func (cache *stmtCache) prepare(db *sql.DB, tableName, query string) (*sql.Stmt, error) {
+	cache.Lock()
+	defer cache.Unlock()
+
+	if cache.tables == nil {
+		cache.tables = make(map[string]map[string]*sql.Stmt)
+	}
+
+	stmts, ok := cache.tables[tableName]
+	if !ok {
+		stmts = make(map[string]*sql.Stmt)
+		cache.tables[tableName] = stmts
+	}
+
+	if stmt, ok := stmts[query]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// keep the cache bounded (e.g. single row upserts vary with the columns set by each event)
+	if len(stmts) >= maxTableStmts {
+		closeStmts(stmts)
+		stmts = make(map[string]*sql.Stmt)
+		cache.tables[tableName] = stmts
+	}
+
+	stmts[query] = stmt
+	return stmt, nil
+}
+
+// invalidate closes and removes cached statements of a table
+func (cache *stmtCache) invalidate(tableName string) {
+	cache.Lock()
+	defer cache.Unlock()
+
+	closeStmts(cache.tables[tableName])
+	delete(cache.tables, tableName)
+}
+
+// invalidateAll closes and removes all cached statements
+func (cache *stmtCache) invalidateAll() {
+	cache.Lock()
+	defer cache.Unlock()
+
+	for _, stmts := range cache.tables {
+		closeStmts(stmts)
+	}
+	cache.tables = nil
+}
+
+func closeStmts(stmts map[string]*sql.Stmt) {
+	for _, stmt := range stmts {
+		stmt.Close()
+	}
+}
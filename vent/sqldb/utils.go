@@ -7,6 +7,7 @@ import (
 
 	"encoding/json"
 
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
 	"github.com/monax/bosmarmot/vent/types"
 )
 
@@ -14,7 +15,7 @@ import (
 func (db *SQLDB) findTable(tableName string) (bool, error) {
 
 	found := 0
-	safeTable := safe(tableName)
+	safeTable := tableName
 	query := clean(db.DBAdapter.FindTableQuery())
 
 	db.Log.Info("msg", "FIND TABLE", "query", query, "value", safeTable)
@@ -31,13 +32,17 @@ func (db *SQLDB) findTable(tableName string) (bool, error) {
 	return true, nil
 }
 
-// getSysTablesDefinition returns log, chain info & dictionary structures
+// sysTableNames lists system tables in creation order
+var sysTableNames = []string{types.SQLDictionaryTableName, types.SQLLogTableName, types.SQLChainInfoTableName, types.SQLAbiTableName}
+
+// getSysTablesDefinition returns log, chain info, abi registry & dictionary structures
 func (db *SQLDB) getSysTablesDefinition() types.EventTables {
 
 	tables := make(types.EventTables)
 	dicCol := make(map[string]types.SQLTableColumn)
 	logCol := make(map[string]types.SQLTableColumn)
 	chainCol := make(map[string]types.SQLTableColumn)
+	abiCol := make(map[string]types.SQLTableColumn)
 
 	// log table
 	logCol[types.SQLColumnLabelId] = types.SQLTableColumn{
@@ -80,8 +85,7 @@ func (db *SQLDB) getSysTablesDefinition() types.EventTables {
 
 	logCol[types.SQLColumnLabelHeight] = types.SQLTableColumn{
 		Name:    types.SQLColumnLabelHeight,
-		Type:    types.SQLColumnTypeVarchar,
-		Length:  100,
+		Type:    types.SQLColumnTypeBigInt,
 		Primary: false,
 		Order:   6,
 	}
@@ -126,6 +130,15 @@ func (db *SQLDB) getSysTablesDefinition() types.EventTables {
 		Order:   11,
 	}
 
+	// previous row contents (json null if the row did not exist), used to rollback
+	logCol[types.SQLColumnLabelBeforeImage] = types.SQLTableColumn{
+		Name:    types.SQLColumnLabelBeforeImage,
+		Type:    types.SQLColumnTypeJSON,
+		Length:  0,
+		Primary: false,
+		Order:   12,
+	}
+
 	// dictionary table
 	dicCol[types.SQLColumnLabelTableName] = types.SQLTableColumn{
 		Name:    types.SQLColumnLabelTableName,
@@ -192,6 +205,22 @@ func (db *SQLDB) getSysTablesDefinition() types.EventTables {
 		Order:   2,
 	}
 
+	// abi registry table
+	abiCol[types.SQLColumnLabelAddress] = types.SQLTableColumn{
+		Name:    types.SQLColumnLabelAddress,
+		Type:    types.SQLColumnTypeVarchar,
+		Length:  40,
+		Primary: true,
+		Order:   1,
+	}
+
+	abiCol[types.SQLColumnLabelAbi] = types.SQLTableColumn{
+		Name:    types.SQLColumnLabelAbi,
+		Type:    types.SQLColumnTypeText,
+		Primary: false,
+		Order:   2,
+	}
+
 	// add tables
 	//log
 	tables[types.SQLLogTableName] = types.SQLTable{
@@ -211,15 +240,38 @@ func (db *SQLDB) getSysTablesDefinition() types.EventTables {
 		Columns: chainCol,
 	}
 
+	//abi registry
+	tables[types.SQLAbiTableName] = types.SQLTable{
+		Name:    types.SQLAbiTableName,
+		Columns: abiCol,
+	}
+
 	return tables
 }
 
+// validateTable returns an error if the name of a table or of any of its columns breaks the database naming rules
+func (db *SQLDB) validateTable(table types.SQLTable) error {
+	if err := db.DBAdapter.ValidateTableName(table.Name); err != nil {
+		db.Log.Info("msg", "Invalid table name", "err", err, "value", table.Name)
+		return err
+	}
+
+	for _, column := range table.Columns {
+		if err := db.DBAdapter.ValidateColumnName(column.Name); err != nil {
+			db.Log.Info("msg", "Invalid column name", "err", err, "value", table.Name)
+			return fmt.Errorf("%v in table %s", err, table.Name)
+		}
+	}
+
+	return nil
+}
+
 // getTableDef returns the structure of a given SQL table
 func (db *SQLDB) getTableDef(tableName string) (types.SQLTable, error) {
 
 	var table types.SQLTable
 
-	safeTable := safe(tableName)
+	safeTable := tableName
 
 	found, err := db.findTable(safeTable)
 	if err != nil {
@@ -280,77 +332,115 @@ func (db *SQLDB) getTableDef(tableName string) (types.SQLTable, error) {
 	return table, nil
 }
 
-// alterTable alters the structure of a SQL table & add info to the dictionary
+// alterTable alters the structure of a SQL table & updates info in the dictionary,
+// destructive changes (drops & narrowing type changes) are only applied if explicitly allowed
 func (db *SQLDB) alterTable(newTable types.SQLTable, eventName string) error {
 
 	db.Log.Info("msg", "Altering table", "value", newTable.Name)
 
-	// prepare log query
-	logQuery := clean(db.DBAdapter.InsertLogQuery())
-
 	// current table structure
-	safeTable := safe(newTable.Name)
+	safeTable := newTable.Name
 	currentTable, err := db.getTableDef(safeTable)
 	if err != nil {
 		return err
 	}
 
-	sqlValues, _ := db.getJSON(nil)
+	changes := diffTable(currentTable, newTable)
+
+	// numeric columns stored by previous versions (e.g. lossy NUMERIC affinity in SQLite)
+	storageChanges, err := db.numericStorageChanges(currentTable, changes)
+	if err != nil {
+		return err
+	}
+	changes = append(changes, storageChanges...)
+
+	// check every change before touching the table
+	for _, change := range changes {
+		if change.destructive && !db.AllowDestructive {
+			db.Log.Info("msg", "Destructive change not allowed", "value", change.column.Name)
+			return fmt.Errorf("error cannot %s column %s in table %s, destructive changes must be explicitly allowed", change.change, change.column.Name, safeTable)
+		}
+	}
 
-	// for each column in the new table structure
-	for _, newColumn := range newTable.Columns {
-		found := false
+	// resulting table structure, needed by adapters that rebuild tables
+	columns := make(map[string]types.SQLTableColumn)
+	for name, column := range currentTable.Columns {
+		columns[name] = column
+	}
+
+	for _, change := range changes {
+		var query, dictionary string
+		safeCol := change.column.Name
+
+		switch change.change {
+		case columnAdd:
+			columns[safeCol] = change.column
+			query, dictionary = db.DBAdapter.AlterColumnQuery(safeTable, safeCol, change.column.Type, change.column.Length, change.column.Order)
+
+		case columnRename:
+			column := columns[change.current.Name]
+			column.Name = safeCol
+			delete(columns, change.current.Name)
+			columns[safeCol] = column
+			query, dictionary = db.DBAdapter.RenameColumnQuery(safeTable, change.current.Name, safeCol)
+
+		case columnAlterType:
+			column := columns[safeCol]
+			column.Type = change.column.Type
+			column.Length = change.column.Length
+			columns[safeCol] = column
+			query, dictionary = db.DBAdapter.AlterColumnTypeQuery(safeTable, sortColumns(columns), column)
+
+		case columnDrop:
+			delete(columns, safeCol)
+			query, dictionary = db.DBAdapter.DropColumnQuery(safeTable, sortColumns(columns), safeCol)
+		}
 
-		// check if exists in the current table structure
-		for _, currentColumn := range currentTable.Columns {
-			// if column exists
-			if currentColumn.Name == newColumn.Name {
-				found = true
-				break
+		if err = db.applyColumnChange(newTable, eventName, change.column, clean(query), dictionary); err != nil {
+			if change.change == columnAdd && db.DBAdapter.ErrorEquals(err, types.SQLErrorTypeDuplicatedColumn) {
+				db.Log.Warn("msg", "Duplicate column", "value", safeCol)
+				continue
 			}
+			return err
 		}
+	}
+	return nil
+}
 
-		if !found {
-			safeCol := safe(newColumn.Name)
-			query, dictionary := db.DBAdapter.AlterColumnQuery(safeTable, safeCol, newColumn.Type, newColumn.Length, newColumn.Order)
+// applyColumnChange executes a column DDL query, stores the dictionary & inserts into the log
+func (db *SQLDB) applyColumnChange(table types.SQLTable, eventName string, column types.SQLTableColumn, query, dictionary string) error {
 
-			//alter column
-			db.Log.Info("msg", "ALTER TABLE", "query", safe(query))
-			_, err = db.DB.Exec(safe(query))
+	// prepare log query
+	logQuery := clean(db.DBAdapter.InsertLogQuery())
 
-			if err != nil {
-				if db.DBAdapter.ErrorEquals(err, types.SQLErrorTypeDuplicatedColumn) {
-					db.Log.Warn("msg", "Duplicate column", "value", safeCol)
-				} else {
-					db.Log.Info("msg", "Error altering table", "err", err)
-					return err
-				}
-			} else {
-				//store dictionary
-				db.Log.Info("msg", "STORE DICTIONARY", "query", clean(dictionary))
-				_, err = db.DB.Exec(dictionary)
-				if err != nil {
-					db.Log.Info("msg", "Error storing  dictionary", "err", err)
-					return err
-				}
+	//alter column
+	db.Log.Info("msg", "ALTER TABLE", "query", query)
+	if _, err := db.DB.Exec(query); err != nil {
+		db.Log.Info("msg", "Error altering table", "err", err)
+		return err
+	}
 
-				//insert log (if action is not database initialization)
-				if eventName != string(types.ActionInitialize) {
-					// Marshal the table into a JSON string.
-					var jsonData []byte
-					jsonData, err = db.getJSON(newColumn)
-					if err != nil {
-						db.Log.Info("msg", "error marshaling column", "err", err, "value", fmt.Sprintf("%v", newColumn))
-						return err
-					}
-					//insert log
-					_, err = db.DB.Exec(logQuery, newTable.Name, eventName, newTable.Filter, nil, nil, types.ActionAlterTable, jsonData, query, sqlValues)
-					if err != nil {
-						db.Log.Info("msg", "Error inserting log", "err", err)
-						return err
-					}
-				}
-			}
+	//store dictionary
+	db.Log.Info("msg", "STORE DICTIONARY", "query", clean(dictionary))
+	if _, err := db.DB.Exec(dictionary); err != nil {
+		db.Log.Info("msg", "Error storing  dictionary", "err", err)
+		return err
+	}
+
+	//insert log (if action is not database initialization)
+	if eventName != string(types.ActionInitialize) {
+		// Marshal the column into a JSON string.
+		jsonData, err := db.getJSON(column)
+		if err != nil {
+			db.Log.Info("msg", "error marshaling column", "err", err, "value", fmt.Sprintf("%v", column))
+			return err
+		}
+		sqlValues, _ := db.getJSON(nil)
+
+		//insert log
+		if _, err = db.DB.Exec(logQuery, table.Name, eventName, table.Filter, nil, nil, types.ActionAlterTable, jsonData, query, sqlValues, nil); err != nil {
+			db.Log.Info("msg", "Error inserting log", "err", err)
+			return err
 		}
 	}
 	return nil
@@ -403,8 +493,15 @@ func (db *SQLDB) createTable(table types.SQLTable, eventName string) error {
 	}
 
 	//get create table query
-	safeTable := safe(table.Name)
+	safeTable := table.Name
 	query, dictionary := db.DBAdapter.CreateTableQuery(safeTable, sortedColumns)
+	if table.PartitionRange > 0 {
+		if partitionAdapter, ok := db.DBAdapter.(adapters.PartitionAdapter); ok {
+			query, dictionary = partitionAdapter.CreatePartitionedTableQuery(safeTable, sortedColumns)
+		} else {
+			db.Log.Warn("msg", "Partitioning not supported by adapter", "value", safeTable)
+		}
+	}
 	if query == "" {
 		db.Log.Info("msg", "empty CREATE TABLE query")
 		return errors.New("empty CREATE TABLE query")
@@ -448,7 +545,7 @@ func (db *SQLDB) createTable(table types.SQLTable, eventName string) error {
 		sqlValues, _ := db.getJSON(nil)
 
 		//insert log
-		_, err = db.DB.Exec(logQuery, table.Name, eventName, table.Filter, nil, nil, types.ActionCreateTable, jsonData, query, sqlValues)
+		_, err = db.DB.Exec(logQuery, table.Name, eventName, table.Filter, nil, nil, types.ActionCreateTable, jsonData, query, sqlValues, nil)
 		if err != nil {
 			db.Log.Info("msg", "Error inserting log", "err", err)
 			return err
@@ -500,18 +597,78 @@ func (db *SQLDB) getBlockTables(block string) (types.EventTables, error) {
 	return tables, nil
 }
 
+// selectRows returns the rows returned by a query with values typed after the table columns (see readValue),
+// every column is set (nil if NULL), columns the table doesn't declare keep the value returned by the driver
+func (db *SQLDB) selectRows(table types.SQLTable, query string, args ...interface{}) ([]types.EventDataRow, error) {
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		db.Log.Info("msg", "Error querying table data", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		db.Log.Info("msg", "Error getting row columns", "err", err)
+		return nil, err
+	}
+
+	// builds pointers
+	length := len(cols)
+	pointers := make([]interface{}, length)
+	containers := make([]interface{}, length)
+
+	for i := range pointers {
+		pointers[i] = &containers[i]
+	}
+
+	// for each row in table
+	var dataRows []types.EventDataRow
+
+	for rows.Next() {
+
+		row := make(map[string]interface{})
+
+		if err = rows.Scan(pointers...); err != nil {
+			db.Log.Info("msg", "Error scanning data", "err", err)
+			return nil, err
+		}
+		db.Log.Info("msg", "Query resultset", "value", fmt.Sprintf("%+v", containers))
+
+		// for each column in row
+		for i, col := range cols {
+			column, ok := table.Columns[col]
+			if !ok {
+				if value, ok := containers[i].([]byte); ok {
+					row[col] = string(value)
+				} else {
+					row[col] = containers[i]
+				}
+				continue
+			}
+
+			if row[col], err = readValue(column.Type, containers[i]); err != nil {
+				db.Log.Info("msg", "Error reading column value", "err", err, "value", col)
+				return nil, err
+			}
+		}
+		dataRows = append(dataRows, types.EventDataRow{Action: types.ActionRead, RowData: row})
+	}
+
+	if err = rows.Err(); err != nil {
+		db.Log.Info("msg", "Error during rows iteration", "err", err)
+		return nil, err
+	}
+
+	return dataRows, nil
+}
+
 // clean queries from tabs, spaces  and returns
 func clean(parameter string) string {
 	replacer := strings.NewReplacer("\n", " ", "\t", "")
 	return replacer.Replace(parameter)
 }
 
-// safe sanitizes a parameter
-func safe(parameter string) string {
-	replacer := strings.NewReplacer(";", "", ",", "")
-	return replacer.Replace(parameter)
-}
-
 //getJSON returns marshaled json from JSON single column
 func (db *SQLDB) getJSON(JSON interface{}) ([]byte, error) {
 	if JSON != nil {
@@ -1,54 +1,193 @@
 package sqlsol
 
 import (
+	"encoding/json"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/hyperledger/burrow/execution/evm/abi"
 	"github.com/pkg/errors"
 )
 
-// AbiLoader loads abi files and parses them
-func AbiLoader(abiDir, abiFile string) (*abi.AbiSpec, error) {
+// errUnknownAbiFormat is returned when a json file does not contain any known abi format
+var errUnknownAbiFormat = errors.New("Unknown abi format")
+
+// AbiSpec is a merged abi specification that keeps track of the contract declaring each event
+type AbiSpec struct {
+	*abi.AbiSpec
+	EventContracts map[abi.EventID]string
+}
+
+// contractAbi is the abi specification of a single named contract
+type contractAbi struct {
+	name    string
+	abiSpec *abi.AbiSpec
+}
+
+// abiArtifactJSON covers burrow bin files, truffle & hardhat artifacts and solc combined-json output
+// (json field matching is case insensitive so Abi also matches burrow bin files)
+type abiArtifactJSON struct {
+	ContractName string                     `json:"contractName"`
+	Abi          json.RawMessage            `json:"abi"`
+	Contracts    map[string]abiArtifactJSON `json:"contracts"`
+}
 
-	var abiSpec *abi.AbiSpec
-	var err error
+// AbiLoader loads abi files and parses them
+func AbiLoader(abiDir, abiFile string) (*AbiSpec, error) {
 
 	if abiDir == "" && abiFile == "" {
-		return &abi.AbiSpec{}, errors.New("One of AbiDir or AbiFile must be provided")
+		return &AbiSpec{}, errors.New("One of AbiDir or AbiFile must be provided")
 	}
 
 	if abiDir != "" && abiFile != "" {
-		return &abi.AbiSpec{}, errors.New("AbiDir or AbiFile must be provided, but not both")
+		return &AbiSpec{}, errors.New("AbiDir or AbiFile must be provided, but not both")
 	}
 
-	if abiDir != "" {
-		specs := make([]*abi.AbiSpec, 0)
+	contracts := make([]contractAbi, 0)
 
+	if abiDir != "" {
 		err := filepath.Walk(abiDir, func(path string, fi os.FileInfo, err error) error {
 			ext := filepath.Ext(path)
-			if fi.IsDir() || !(ext == ".bin" || ext == ".abi") {
+			if fi.IsDir() || !(ext == ".bin" || ext == ".abi" || ext == ".json") {
 				return nil
 			}
 			if err == nil {
-				abiSpc, err := abi.ReadAbiSpecFile(path)
+				fileContracts, err := readAbiFile(path)
+				// json files in build folders may not be abi artifacts
+				if err == errUnknownAbiFormat && ext == ".json" {
+					return nil
+				}
 				if err != nil {
 					return errors.Wrap(err, "Error parsing abi file "+path)
 				}
-				specs = append(specs, abiSpc)
+				contracts = append(contracts, fileContracts...)
 			}
 			return nil
 		})
 		if err != nil {
-			return &abi.AbiSpec{}, err
+			return &AbiSpec{}, err
 		}
-		abiSpec = abi.MergeAbiSpec(specs)
 	} else {
-		abiSpec, err = abi.ReadAbiSpecFile(abiFile)
+		fileContracts, err := readAbiFile(abiFile)
+		if err != nil {
+			return &AbiSpec{}, errors.Wrap(err, "Error parsing abi file")
+		}
+		contracts = append(contracts, fileContracts...)
+	}
+
+	return mergeContractAbis(contracts), nil
+}
+
+// ReadAbiSpec parses an abi specification given as a plain abi, a burrow bin file,
+// a truffle or hardhat artifact or a solc combined-json output
+func ReadAbiSpec(specBytes []byte) (*AbiSpec, error) {
+	contracts, err := readContractAbis("", specBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeContractAbis(contracts), nil
+}
+
+// readAbiFile reads an abi file, plain abi specifications are named after the file
+func readAbiFile(path string) ([]contractAbi, error) {
+	specBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	return readContractAbis(name, specBytes)
+}
+
+// readContractAbis detects the format of an abi specification and returns the abi of each contract in it
+func readContractAbis(name string, specBytes []byte) ([]contractAbi, error) {
+	// plain abi specification
+	var abiJSON []json.RawMessage
+	if err := json.Unmarshal(specBytes, &abiJSON); err == nil {
+		abiSpec, err := abi.ReadAbiSpec(specBytes)
 		if err != nil {
-			return &abi.AbiSpec{}, errors.Wrap(err, "Error parsing abi file")
+			return nil, err
 		}
+		return []contractAbi{{name: name, abiSpec: abiSpec}}, nil
+	}
+
+	var artifact abiArtifactJSON
+	if err := json.Unmarshal(specBytes, &artifact); err != nil {
+		return nil, err
 	}
 
-	return abiSpec, nil
+	// solc combined-json output, contracts are keyed by <source file>:<contract name>
+	if len(artifact.Contracts) > 0 {
+		contracts := make([]contractAbi, 0, len(artifact.Contracts))
+
+		keys := make([]string, 0, len(artifact.Contracts))
+		for key := range artifact.Contracts {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			contract := artifact.Contracts[key]
+			contractName := key[strings.LastIndex(key, ":")+1:]
+
+			abiSpec, err := readArtifactAbi(contract.Abi)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Error parsing abi of contract %s", key)
+			}
+			contracts = append(contracts, contractAbi{name: contractName, abiSpec: abiSpec})
+		}
+
+		return contracts, nil
+	}
+
+	// burrow bin file, truffle or hardhat artifact
+	if len(artifact.Abi) > 0 {
+		if artifact.ContractName != "" {
+			name = artifact.ContractName
+		}
+
+		abiSpec, err := readArtifactAbi(artifact.Abi)
+		if err != nil {
+			return nil, err
+		}
+		return []contractAbi{{name: name, abiSpec: abiSpec}}, nil
+	}
+
+	return nil, errUnknownAbiFormat
+}
+
+// readArtifactAbi parses an abi given as a json array or as a json encoded string (older solc versions)
+func readArtifactAbi(abiJSON json.RawMessage) (*abi.AbiSpec, error) {
+	var abiString string
+	if err := json.Unmarshal(abiJSON, &abiString); err == nil {
+		return abi.ReadAbiSpec([]byte(abiString))
+	}
+
+	return abi.ReadAbiSpec(abiJSON)
+}
+
+// mergeContractAbis merges contract abi specifications into a single one
+func mergeContractAbis(contracts []contractAbi) *AbiSpec {
+	specs := make([]*abi.AbiSpec, 0, len(contracts))
+	eventContracts := make(map[abi.EventID]string)
+
+	for _, contract := range contracts {
+		specs = append(specs, contract.abiSpec)
+		if contract.name == "" {
+			continue
+		}
+		for eventID := range contract.abiSpec.EventsById {
+			eventContracts[eventID] = contract.name
+		}
+	}
+
+	return &AbiSpec{
+		AbiSpec:        abi.MergeAbiSpec(specs),
+		EventContracts: eventContracts,
+	}
 }
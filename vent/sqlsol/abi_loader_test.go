@@ -0,0 +1,97 @@
+package sqlsol_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/monax/bosmarmot/vent/sqlsol"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// solc --combined-json abi,bin (older solc versions encode the abi as a json string)
+	combinedJSONAbi = `{
+		"contracts": {
+			"contracts/Token.sol:Token": {
+				"abi": "[{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"name\":\"from\",\"type\":\"address\"}],\"name\":\"Transfer\",\"type\":\"event\"}]",
+				"bin": "6080"
+			},
+			"contracts/Registry.sol:Registry": {
+				"abi": [{"anonymous":false,"inputs":[{"indexed":true,"name":"name","type":"string"}],"name":"Registered","type":"event"}],
+				"bin": "6080"
+			}
+		},
+		"version": "0.4.25"
+	}`
+
+	truffleArtifactAbi = `{
+		"contractName": "Crowdsale",
+		"abi": [{"anonymous":false,"inputs":[{"indexed":false,"name":"amount","type":"uint256"}],"name":"Purchased","type":"event"}],
+		"bytecode": "0x6080"
+	}`
+
+	hardhatArtifactAbi = `{
+		"_format": "hh-sol-artifact-1",
+		"contractName": "Vault",
+		"sourceName": "contracts/Vault.sol",
+		"abi": [{"anonymous":false,"inputs":[{"indexed":false,"name":"amount","type":"uint256"}],"name":"Deposited","type":"event"}],
+		"bytecode": "0x6080"
+	}`
+
+	hardhatDebugFile = `{"_format": "hh-sol-dbg-1", "buildInfo": "../build-info/1.json"}`
+)
+
+func TestReadAbiSpec(t *testing.T) {
+	t.Run("successfully reads solc combined-json output", func(t *testing.T) {
+		abiSpec, err := sqlsol.ReadAbiSpec([]byte(combinedJSONAbi))
+		require.NoError(t, err)
+
+		require.Equal(t, "Token", abiSpec.EventContracts[abiSpec.Events["Transfer"].EventID])
+		require.Equal(t, "Registry", abiSpec.EventContracts[abiSpec.Events["Registered"].EventID])
+	})
+
+	t.Run("successfully reads truffle and hardhat artifacts", func(t *testing.T) {
+		abiSpec, err := sqlsol.ReadAbiSpec([]byte(truffleArtifactAbi))
+		require.NoError(t, err)
+		require.Equal(t, "Crowdsale", abiSpec.EventContracts[abiSpec.Events["Purchased"].EventID])
+
+		abiSpec, err = sqlsol.ReadAbiSpec([]byte(hardhatArtifactAbi))
+		require.NoError(t, err)
+		require.Equal(t, "Vault", abiSpec.EventContracts[abiSpec.Events["Deposited"].EventID])
+	})
+
+	t.Run("returns an error if the abi format is unknown", func(t *testing.T) {
+		_, err := sqlsol.ReadAbiSpec([]byte(hardhatDebugFile))
+		require.Error(t, err)
+	})
+}
+
+func TestAbiLoader(t *testing.T) {
+	abiDir, err := ioutil.TempDir("", "vent-abi")
+	require.NoError(t, err)
+	defer os.RemoveAll(abiDir)
+
+	files := map[string]string{
+		"combined.json":  combinedJSONAbi,
+		"Vault.json":     hardhatArtifactAbi,
+		"Vault.dbg.json": hardhatDebugFile,
+	}
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(abiDir, name), []byte(content), 0644))
+	}
+
+	t.Run("successfully loads abi artifacts skipping unknown json files", func(t *testing.T) {
+		abiSpec, err := sqlsol.AbiLoader(abiDir, "")
+		require.NoError(t, err)
+
+		require.Len(t, abiSpec.Events, 3)
+		require.Equal(t, "Vault", abiSpec.EventContracts[abiSpec.Events["Deposited"].EventID])
+	})
+
+	t.Run("returns an error if an abi file has an unknown format", func(t *testing.T) {
+		_, err := sqlsol.AbiLoader("", filepath.Join(abiDir, "Vault.dbg.json"))
+		require.Error(t, err)
+	})
+}
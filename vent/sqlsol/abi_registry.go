@@ -0,0 +1,139 @@
+package sqlsol
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/burrow/execution/evm/abi"
+	"github.com/pkg/errors"
+)
+
+// AbiRegistry maps contract addresses to their abi specifications,
+// events of unregistered contracts (or not found in the contract abi)
+// are decoded with the global merged abi specification
+type AbiRegistry struct {
+	sync.RWMutex
+	global    *AbiSpec
+	contracts map[crypto.Address]*AbiSpec
+}
+
+// NewAbiRegistry returns a registry that falls back to the given global abi specification
+func NewAbiRegistry(global *AbiSpec) *AbiRegistry {
+	return &AbiRegistry{
+		global:    global,
+		contracts: make(map[crypto.Address]*AbiSpec),
+	}
+}
+
+// Register parses an abi json specification (in any format accepted by ReadAbiSpec) and binds it to a contract address
+func (r *AbiRegistry) Register(address crypto.Address, abiJSON []byte) error {
+	abiSpec, err := ReadAbiSpec(abiJSON)
+	if err != nil {
+		return errors.Wrapf(err, "Error parsing abi for contract %s", address)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.contracts[address] = abiSpec
+	return nil
+}
+
+// RegisterDeployed registers abi specifications of deployed contracts,
+// for each deploy job a <job name>.abi, .bin or .json file is searched in abiDir,
+// returns the abi json registered for each contract address
+func (r *AbiRegistry) RegisterDeployed(deployed map[string]crypto.Address, abiDir string) (map[crypto.Address][]byte, error) {
+	registered := make(map[crypto.Address][]byte)
+
+	if abiDir == "" {
+		return registered, nil
+	}
+
+	for jobName, address := range deployed {
+		for _, ext := range []string{".abi", ".bin", ".json"} {
+			path := filepath.Join(abiDir, jobName+ext)
+
+			abiJSON, err := ioutil.ReadFile(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, errors.Wrap(err, "Error reading abi file "+path)
+			}
+
+			if err = r.Register(address, abiJSON); err != nil {
+				return nil, err
+			}
+			registered[address] = abiJSON
+			break
+		}
+	}
+
+	return registered, nil
+}
+
+// GetEventSpec returns the abi specification of an event emitted by a given contract address
+func (r *AbiRegistry) GetEventSpec(address crypto.Address, eventID abi.EventID) (abi.EventSpec, bool) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if abiSpec, ok := r.contracts[address]; ok {
+		if evAbi, ok := abiSpec.EventsById[eventID]; ok {
+			return evAbi, true
+		}
+	}
+
+	evAbi, ok := r.global.EventsById[eventID]
+	return evAbi, ok
+}
+
+// GetEventSpecByName returns the abi specification of an event given its name and the emitting contract address
+func (r *AbiRegistry) GetEventSpecByName(address crypto.Address, eventName string) (abi.EventSpec, bool) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if abiSpec, ok := r.contracts[address]; ok {
+		if evAbi, ok := abiSpec.Events[eventName]; ok {
+			return evAbi, true
+		}
+	}
+
+	evAbi, ok := r.global.Events[eventName]
+	return evAbi, ok
+}
+
+// GetContractName returns the name of the contract declaring an event emitted by a given contract address,
+// it is empty if the event comes from a plain abi specification without a contract name
+func (r *AbiRegistry) GetContractName(address crypto.Address, eventID abi.EventID) string {
+	r.RLock()
+	defer r.RUnlock()
+
+	if abiSpec, ok := r.contracts[address]; ok {
+		if name, ok := abiSpec.EventContracts[eventID]; ok {
+			return name
+		}
+	}
+
+	return r.global.EventContracts[eventID]
+}
+
+// Addresses returns sorted registered contract addresses
+func (r *AbiRegistry) Addresses() []crypto.Address {
+	r.RLock()
+	defer r.RUnlock()
+
+	addresses := make([]crypto.Address, 0, len(r.contracts))
+	for address := range r.contracts {
+		addresses = append(addresses, address)
+	}
+
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].String() < addresses[j].String()
+	})
+
+	return addresses
+}
@@ -0,0 +1,58 @@
+package sqlsol_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/burrow/execution/evm/abi"
+	"github.com/monax/bosmarmot/vent/sqlsol"
+	"github.com/stretchr/testify/require"
+)
+
+// both abi specifications share the same event signature with different indexed inputs
+const (
+	globalTransferAbi   = `[{"anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"address"},{"indexed":false,"name":"value","type":"uint256"}],"name":"Transfer","type":"event"}]`
+	contractTransferAbi = `[{"anonymous":false,"inputs":[{"indexed":false,"name":"from","type":"address"},{"indexed":true,"name":"value","type":"uint256"}],"name":"Transfer","type":"event"}]`
+)
+
+func TestAbiRegistry(t *testing.T) {
+	globalSpec, err := sqlsol.ReadAbiSpec([]byte(globalTransferAbi))
+	require.NoError(t, err)
+
+	eventID := globalSpec.Events["Transfer"].EventID
+	contract := crypto.Address{1, 2, 3}
+
+	t.Run("successfully gets contract event specifications falling back to the global abi", func(t *testing.T) {
+		registry := sqlsol.NewAbiRegistry(globalSpec)
+
+		err := registry.Register(contract, []byte(contractTransferAbi))
+		require.NoError(t, err)
+		require.Equal(t, []crypto.Address{contract}, registry.Addresses())
+
+		evAbi, ok := registry.GetEventSpec(contract, eventID)
+		require.True(t, ok)
+		require.True(t, evAbi.Inputs[1].Indexed)
+
+		evAbi, ok = registry.GetEventSpec(crypto.Address{4, 5, 6}, eventID)
+		require.True(t, ok)
+		require.True(t, evAbi.Inputs[0].Indexed)
+
+		_, ok = registry.GetEventSpec(contract, abi.EventID{})
+		require.False(t, ok)
+
+		evAbi, ok = registry.GetEventSpecByName(contract, "Transfer")
+		require.True(t, ok)
+		require.True(t, evAbi.Inputs[1].Indexed)
+
+		_, ok = registry.GetEventSpecByName(contract, "Unknown")
+		require.False(t, ok)
+	})
+
+	t.Run("returns an error if the abi specification is malformed", func(t *testing.T) {
+		registry := sqlsol.NewAbiRegistry(globalSpec)
+
+		err := registry.Register(contract, []byte(`{"malformed"`))
+		require.Error(t, err)
+		require.Empty(t, registry.Addresses())
+	})
+}
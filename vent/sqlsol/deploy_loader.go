@@ -0,0 +1,39 @@
+package sqlsol
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/pkg/errors"
+)
+
+// DeployLoader loads a Burrow deploy output file and returns contract addresses
+// mapped by deploy job name, an empty map is returned if no file is provided
+func DeployLoader(deployFile string) (map[string]crypto.Address, error) {
+	addresses := make(map[string]crypto.Address)
+
+	if deployFile == "" {
+		return addresses, nil
+	}
+
+	bytes, err := readFile(deployFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading deploy output file")
+	}
+
+	results := make(map[string]interface{})
+	if err := json.Unmarshal(bytes, &results); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshalling deploy output file")
+	}
+
+	// only deploy jobs results are addresses, other jobs results are ignored
+	for jobName, result := range results {
+		if str, ok := result.(string); ok {
+			if address, err := crypto.AddressFromHexString(str); err == nil {
+				addresses[jobName] = address
+			}
+		}
+	}
+
+	return addresses, nil
+}
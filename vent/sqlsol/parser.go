@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/hyperledger/burrow/crypto"
 	"github.com/monax/bosmarmot/vent/types"
 	"github.com/pkg/errors"
 )
@@ -92,11 +93,30 @@ func NewParserFromEventSpec(eventSpec types.EventSpec) (*Parser, error) {
 		columns := make(map[string]types.SQLTableColumn)
 		j := 0
 		for colName, col := range eventDef.Columns {
-			sqlType, sqlTypeLength, err := getSQLType(strings.ToLower(col.Type), false, col.BytesToString)
+			var sqlType types.SQLColumnType
+			var sqlTypeLength int
+			var err error
+
+			// raw log topics & data columns are not decoded so their type is fixed
+			if col.Raw {
+				sqlType, sqlTypeLength, err = getRawLogSQLType(colName)
+			} else if col.JSON {
+				sqlType, sqlTypeLength, err = getJSONSQLType(strings.ToLower(col.Type))
+			} else {
+				sqlType, sqlTypeLength, err = getSQLType(strings.ToLower(col.Type), false, col.BytesToString)
+			}
 			if err != nil {
 				return nil, err
 			}
 
+			if col.Index != "" && !sqlType.IsJSON() {
+				return nil, fmt.Errorf("Invalid column %s in table %s: only json columns can be indexed", colName, eventDef.TableName)
+			}
+
+			if col.FullText && sqlType != types.SQLColumnTypeText {
+				return nil, fmt.Errorf("Invalid column %s in table %s: only string columns can be searched", colName, eventDef.TableName)
+			}
+
 			j++
 
 			columns[colName] = types.SQLTableColumn{
@@ -107,6 +127,10 @@ func NewParserFromEventSpec(eventSpec types.EventSpec) (*Parser, error) {
 				Primary:       col.Primary,
 				BytesToString: col.BytesToString,
 				Order:         j + globalColumnsLength,
+				PreviousName:  strings.ToLower(col.PreviousName),
+				Index:         col.Index,
+				FullText:      col.FullText,
+				Raw:           col.Raw,
 			}
 		}
 
@@ -115,13 +139,26 @@ func NewParserFromEventSpec(eventSpec types.EventSpec) (*Parser, error) {
 			columns[k] = v
 		}
 
+		// partitions hold height ranges so the height must be part of the primary key (if any)
+		if eventDef.Partitioning.HeightRange > 0 && hasPrimaryKey(columns) {
+			height := columns[types.BlockHeightLabel]
+			height.Primary = true
+			columns[types.BlockHeightLabel] = height
+		}
+
 		tables[eventDef.TableName] = types.SQLTable{
-			Name:    strings.ToLower(eventDef.TableName),
-			Filter:  eventDef.Filter,
-			Columns: columns,
+			Name:           strings.ToLower(eventDef.TableName),
+			Filter:         eventDef.Filter,
+			Columns:        columns,
+			PartitionRange: eventDef.Partitioning.HeightRange,
 		}
 	}
 
+	// check if there are table names colliding once normalised
+	if err := checkTableNames(eventSpec); err != nil {
+		return nil, err
+	}
+
 	// check if there are duplicated duplicated column names (for a given table)
 	colName := make(map[string]int)
 
@@ -140,6 +177,38 @@ func NewParserFromEventSpec(eventSpec types.EventSpec) (*Parser, error) {
 	}, nil
 }
 
+// checkTableNames returns an error if spec table names collide once normalised (lowercased),
+// with vent system tables or with history views of other tables
+func checkTableNames(eventSpec types.EventSpec) error {
+	tableNames := make(map[string]string)
+
+	for _, eventDef := range eventSpec {
+		name := strings.ToLower(eventDef.TableName)
+
+		if strings.HasPrefix(name, types.SQLSysTablePrefix) {
+			return fmt.Errorf("Reserved table name: %s, prefix %s is kept for system tables", eventDef.TableName, types.SQLSysTablePrefix)
+		}
+
+		if tableName, ok := tableNames[name]; ok && tableName != eventDef.TableName {
+			return fmt.Errorf("Duplicated table name: %s and %s are both named %s", tableName, eventDef.TableName, name)
+		}
+		tableNames[name] = eventDef.TableName
+	}
+
+	for name, tableName := range tableNames {
+		for suffix, view := range map[string]string{types.SQLHistoryViewSuffix: "history", types.SQLEnrichedViewSuffix: "enriched"} {
+			if !strings.HasSuffix(name, suffix) {
+				continue
+			}
+			if viewed, ok := tableNames[strings.TrimSuffix(name, suffix)]; ok {
+				return fmt.Errorf("Duplicated table name: %s is the name of the %s view of table %s", tableName, view, viewed)
+			}
+		}
+	}
+
+	return nil
+}
+
 // GetEventSpec returns the event specification
 func (p *Parser) GetEventSpec() types.EventSpec {
 	return p.EventSpec
@@ -150,6 +219,31 @@ func (p *Parser) GetTables() types.EventTables {
 	return p.Tables
 }
 
+// BindContracts resolves contracts given in event specifications to addresses,
+// each contract can be a deploy job name (found in deployed) or an hex address
+func (p *Parser) BindContracts(deployed map[string]crypto.Address) error {
+	for i, eventDef := range p.EventSpec {
+		addresses := make([]crypto.Address, 0, len(eventDef.Contracts))
+
+		for _, contract := range eventDef.Contracts {
+			if address, ok := deployed[contract]; ok {
+				addresses = append(addresses, address)
+				continue
+			}
+
+			address, err := crypto.AddressFromHexString(contract)
+			if err != nil {
+				return fmt.Errorf("contract %s in table %s is neither a deploy job name nor a valid address", contract, eventDef.TableName)
+			}
+			addresses = append(addresses, address)
+		}
+
+		p.EventSpec[i].SetAddresses(addresses)
+	}
+
+	return nil
+}
+
 // GetColumn receives a table & column name and returns column info
 func (p *Parser) GetColumn(tableName, columnName string) (types.SQLTableColumn, error) {
 	column := types.SQLTableColumn{}
@@ -164,6 +258,39 @@ func (p *Parser) GetColumn(tableName, columnName string) (types.SQLTableColumn,
 	return column, fmt.Errorf("GetColumn: tableName does not exists as a table in SQL table structure: %s ", tableName)
 }
 
+// IndexColumns sets the index method of JSON columns (found by name in the given tables)
+func (p *Parser) IndexColumns(tableNames, columnNames []string, method string) error {
+	for _, columnName := range columnNames {
+		found := false
+
+		for _, tableName := range tableNames {
+			table, ok := p.Tables[tableName]
+			if !ok {
+				continue
+			}
+
+			for label, column := range table.Columns {
+				if column.Name != columnName {
+					continue
+				}
+				if !column.Type.IsJSON() {
+					return fmt.Errorf("IndexColumns: column %s in table %s is not a json column", columnName, tableName)
+				}
+
+				column.Index = method
+				table.Columns[label] = column
+				found = true
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("IndexColumns: columnName does not exists as a column in tables %s: %s", strings.Join(tableNames, ", "), columnName)
+		}
+	}
+
+	return nil
+}
+
 // readFile opens a given file and reads it contents into a stream of bytes
 func readFile(file string) ([]byte, error) {
 	theFile, err := os.Open(file)
@@ -205,26 +332,18 @@ func getSQLType(evmSignature string, isArray bool, bytesToString bool) (types.SQ
 		// solidity string => sql text
 	case evmSignature == types.EventInputTypeString:
 		return types.SQLColumnTypeText, 0, nil
-		// solidity int or int256 => sql bigint
 		// solidity int <= 32 => sql int
-		// solidity int > 32 => sql numeric
+		// solidity int > 32 (including int & int256) => sql numeric
 	case strings.HasPrefix(evmSignature, types.EventInputTypeInt):
-		if typeSize == 0 || typeSize == 256 {
-			return types.SQLColumnTypeBigInt, 0, nil
-		}
-		if typeSize <= 32 {
+		if typeSize != 0 && typeSize <= 32 {
 			return types.SQLColumnTypeInt, 0, nil
 		} else {
 			return types.SQLColumnTypeNumeric, 0, nil
 		}
-		// solidity uint or uint256 => sql bigint
 		// solidity uint <= 16 => sql int
-		// solidity uint > 16 => sql numeric
+		// solidity uint > 16 (including uint & uint256) => sql numeric
 	case strings.HasPrefix(evmSignature, types.EventInputTypeUInt):
-		if typeSize == 0 || typeSize == 256 {
-			return types.SQLColumnTypeBigInt, 0, nil
-		}
-		if typeSize <= 16 {
+		if typeSize != 0 && typeSize <= 16 {
 			return types.SQLColumnTypeInt, 0, nil
 		} else {
 			return types.SQLColumnTypeNumeric, 0, nil
@@ -234,6 +353,38 @@ func getSQLType(evmSignature string, isArray bool, bytesToString bool) (types.SQ
 	}
 }
 
+// getJSONSQLType maps event input types stored as JSON documents with corresponding SQL column types,
+// only strings can hold JSON documents
+func getJSONSQLType(evmSignature string) (types.SQLColumnType, int, error) {
+	if evmSignature != types.EventInputTypeString {
+		return -1, 0, fmt.Errorf("Don't know how to store evmSignature as json: %s ", evmSignature)
+	}
+	return types.SQLColumnTypeJSONB, 0, nil
+}
+
+// getRawLogSQLType maps raw log labels with corresponding SQL column types,
+// topics are stored as hex encoded words and data as hex encoded text
+func getRawLogSQLType(label string) (types.SQLColumnType, int, error) {
+	switch {
+	case label == types.LogDataLabel:
+		return types.SQLColumnTypeText, 0, nil
+	case types.IsRawLogLabel(label):
+		return types.SQLColumnTypeVarchar, 64, nil
+	default:
+		return -1, 0, fmt.Errorf("Don't know how to map raw log label: %s, raw columns must be keyed topic0 to topic3 or data ", label)
+	}
+}
+
+// hasPrimaryKey checks if any of the given columns is part of the primary key
+func hasPrimaryKey(columns map[string]types.SQLTableColumn) bool {
+	for _, column := range columns {
+		if column.Primary {
+			return true
+		}
+	}
+	return false
+}
+
 // getGlobalColumns returns global columns for event table structures,
 // these columns will be part of every SQL event table to relate data with source events
 func getGlobalColumns() map[string]types.SQLTableColumn {
@@ -241,8 +392,7 @@ func getGlobalColumns() map[string]types.SQLTableColumn {
 
 	globalColumns[types.BlockHeightLabel] = types.SQLTableColumn{
 		Name:    types.SQLColumnLabelHeight,
-		Type:    types.SQLColumnTypeVarchar,
-		Length:  100,
+		Type:    types.SQLColumnTypeBigInt,
 		Primary: false,
 		Order:   1,
 	}
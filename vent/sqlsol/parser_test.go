@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hyperledger/burrow/crypto"
 	"github.com/monax/bosmarmot/vent/sqlsol"
 	"github.com/monax/bosmarmot/vent/test"
 	"github.com/monax/bosmarmot/vent/types"
@@ -62,6 +63,121 @@ func TestNewParser(t *testing.T) {
 		require.Equal(t, 4, col.Order)
 	})
 
+	t.Run("successfully maps the previous name of a renamed column", func(t *testing.T) {
+		migrationJSON := test.MigrationJSONConfFile(t)
+
+		byteValue := []byte(migrationJSON)
+		tableStruct, err := sqlsol.NewParserFromBytes(byteValue)
+		require.NoError(t, err)
+
+		col, err := tableStruct.GetColumn("UserAccounts", "userName")
+		require.NoError(t, err)
+		require.Equal(t, "name", col.Name)
+		require.Equal(t, "username", col.PreviousName)
+
+		col, err = tableStruct.GetColumn("UserAccounts", "userAddress")
+		require.NoError(t, err)
+		require.Equal(t, "", col.PreviousName)
+	})
+
+	t.Run("successfully maps raw log topics & data columns", func(t *testing.T) {
+		rawLogJSON := test.RawLogJSONConfFile(t)
+
+		byteValue := []byte(rawLogJSON)
+		tableStruct, err := sqlsol.NewParserFromBytes(byteValue)
+		require.NoError(t, err)
+
+		col, err := tableStruct.GetColumn("RawLogs", "topic0")
+		require.NoError(t, err)
+		require.Equal(t, types.SQLColumnTypeVarchar, col.Type)
+		require.Equal(t, 64, col.Length)
+
+		col, err = tableStruct.GetColumn("AnonymousTransfers", "data")
+		require.NoError(t, err)
+		require.Equal(t, types.SQLColumnTypeText, col.Type)
+		require.Equal(t, "raw_data", col.Name)
+
+		eventSpec := tableStruct.GetEventSpec()
+		require.True(t, eventSpec[0].IsRaw())
+		require.False(t, eventSpec[1].IsRaw())
+		require.Equal(t, "Transfer", eventSpec[1].Event)
+
+		// columns keyed like raw labels are event inputs unless marked raw
+		tableStruct, err = sqlsol.NewParserFromBytes([]byte(`[{"TableName" : "Inputs", "Filter" : "EventType = 'LogEvent'",
+			"Columns" : {"data" : {"name" : "data", "type" : "uint256", "primary" : true}}}]`))
+		require.NoError(t, err)
+
+		col, err = tableStruct.GetColumn("Inputs", "data")
+		require.NoError(t, err)
+		require.Equal(t, types.SQLColumnTypeNumeric, col.Type)
+		require.False(t, col.Raw)
+		require.False(t, tableStruct.GetEventSpec()[0].IsRaw())
+
+		_, err = sqlsol.NewParserFromBytes([]byte(`[{"TableName" : "Inputs", "Filter" : "EventType = 'LogEvent'",
+			"Columns" : {"value" : {"name" : "value", "raw" : true}}}]`))
+		require.Error(t, err)
+	})
+
+	t.Run("successfully maps json columns & their indexes", func(t *testing.T) {
+		jsonColumnJSON := test.JSONColumnJSONConfFile(t)
+
+		byteValue := []byte(jsonColumnJSON)
+		tableStruct, err := sqlsol.NewParserFromBytes(byteValue)
+		require.NoError(t, err)
+
+		col, err := tableStruct.GetColumn("Documents", "document")
+		require.NoError(t, err)
+		require.Equal(t, types.SQLColumnTypeJSONB, col.Type)
+		require.Equal(t, types.SQLIndexGIN, col.Index)
+
+		_, err = sqlsol.NewParserFromBytes([]byte(strings.Replace(jsonColumnJSON, `"type": "string"`, `"type": "uint256"`, 1)))
+		require.Error(t, err)
+
+		_, err = sqlsol.NewParserFromBytes([]byte(strings.Replace(jsonColumnJSON, `"json" : true, `, "", 1)))
+		require.Error(t, err)
+
+		_, err = sqlsol.NewParserFromBytes([]byte(strings.Replace(jsonColumnJSON, `"gin"`, `"hash"`, 1)))
+		require.Error(t, err)
+	})
+
+	t.Run("successfully maps full-text columns", func(t *testing.T) {
+		fullTextJSON := test.FullTextJSONConfFile(t)
+
+		tableStruct, err := sqlsol.NewParserFromBytes([]byte(fullTextJSON))
+		require.NoError(t, err)
+
+		col, err := tableStruct.GetColumn("Payments", "memo")
+		require.NoError(t, err)
+		require.Equal(t, types.SQLColumnTypeText, col.Type)
+		require.True(t, col.FullText)
+
+		_, err = sqlsol.NewParserFromBytes([]byte(strings.Replace(fullTextJSON, `"type": "string"`, `"type": "uint256"`, 1)))
+		require.Error(t, err)
+
+		_, err = sqlsol.NewParserFromBytes([]byte(strings.Replace(fullTextJSON, `"fullText" : true`, `"fullText" : true, "json" : true`, 1)))
+		require.Error(t, err)
+	})
+
+	t.Run("successfully adds the height to the primary key of partitioned tables", func(t *testing.T) {
+		partitionedJSON := test.PartitionedJSONConfFile(t)
+
+		tableStruct, err := sqlsol.NewParserFromBytes([]byte(partitionedJSON))
+		require.NoError(t, err)
+		require.Equal(t, uint64(1000000), tableStruct.GetTables()["Transfers"].PartitionRange)
+
+		col, err := tableStruct.GetColumn("Transfers", "height")
+		require.NoError(t, err)
+		require.True(t, col.Primary)
+
+		tableStruct, err = sqlsol.NewParserFromBytes([]byte(strings.Replace(partitionedJSON, `"HeightRange" : 1000000`, "", 1)))
+		require.NoError(t, err)
+		require.Zero(t, tableStruct.GetTables()["Transfers"].PartitionRange)
+
+		col, err = tableStruct.GetColumn("Transfers", "height")
+		require.NoError(t, err)
+		require.False(t, col.Primary)
+	})
+
 	t.Run("returns an error if the event type of a given column is unknown", func(t *testing.T) {
 		typeUnknownJSON := test.UnknownTypeJSONConfFile(t)
 
@@ -78,6 +194,34 @@ func TestNewParser(t *testing.T) {
 		require.Error(t, err)
 	})
 
+	t.Run("returns an error if table names collide once normalised", func(t *testing.T) {
+		duplicatedTableNameJSON := test.DuplicatedTableNameJSONConfFile(t)
+
+		byteValue := []byte(duplicatedTableNameJSON)
+		_, err := sqlsol.NewParserFromBytes(byteValue)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Duplicated table name")
+
+		historyTableNameJSON := test.HistoryTableNameJSONConfFile(t)
+
+		byteValue = []byte(historyTableNameJSON)
+		_, err = sqlsol.NewParserFromBytes(byteValue)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "history view")
+
+		byteValue = []byte(strings.Replace(historyTableNameJSON, "UserAccounts_History", "UserAccounts_Enriched", 1))
+		_, err = sqlsol.NewParserFromBytes(byteValue)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "enriched view")
+	})
+
+	t.Run("returns an error if a table name uses the system tables prefix", func(t *testing.T) {
+		byteValue := []byte(`[{"TableName" : "_vent_accounts", "Filter" : "LOG0 = 'UserAccounts'", "Columns" : {"userAddress" : {"name" : "address", "type": "address", "primary" : true}}}]`)
+		_, err := sqlsol.NewParserFromBytes(byteValue)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Reserved table name")
+	})
+
 }
 
 func TestGetColumn(t *testing.T) {
@@ -90,15 +234,18 @@ func TestGetColumn(t *testing.T) {
 		column, err := tableStruct.GetColumn("TEST_TABLE", "blocknum")
 		require.NoError(t, err)
 		require.Equal(t, strings.ToLower("Block"), column.Name)
-		require.Equal(t, types.SQLColumnTypeBigInt, column.Type)
+		require.Equal(t, types.SQLColumnTypeNumeric, column.Type)
 		require.Equal(t, false, column.Primary)
 
 		column, err = tableStruct.GetColumn("TEST_TABLE", "instance")
 		require.NoError(t, err)
 		require.Equal(t, strings.ToLower("Instance"), column.Name)
-		require.Equal(t, types.SQLColumnTypeBigInt, column.Type)
+		require.Equal(t, types.SQLColumnTypeNumeric, column.Type)
 		require.Equal(t, false, column.Primary)
 
+		column, err = tableStruct.GetColumn("TEST_TABLE", types.BlockHeightLabel)
+		require.NoError(t, err)
+		require.Equal(t, types.SQLColumnTypeBigInt, column.Type)
 	})
 
 	t.Run("unsuccessfully gets the mapping column info for a non existent table name", func(t *testing.T) {
@@ -143,3 +290,39 @@ func TestGetEventSpec(t *testing.T) {
 		require.Equal(t, "TEST_TABLE", eventSpec[1].TableName)
 	})
 }
+
+func TestBindContracts(t *testing.T) {
+	deployed := map[string]crypto.Address{
+		"userAccounts": crypto.Address{1, 2, 3},
+	}
+
+	t.Run("successfully binds contract addresses and deploy job names to event specifications", func(t *testing.T) {
+		parser, err := sqlsol.NewParserFromBytes([]byte(test.ContractsJSONConfFile(t)))
+		require.NoError(t, err)
+
+		err = parser.BindContracts(deployed)
+		require.NoError(t, err)
+
+		address, err := crypto.AddressFromHexString("1AEEFD3783050219C3988098E152A11F02C4F4C4")
+		require.NoError(t, err)
+
+		eventSpec := parser.GetEventSpec()
+		require.True(t, eventSpec[0].MatchesContract(address))
+		require.True(t, eventSpec[0].MatchesContract(deployed["userAccounts"]))
+		require.False(t, eventSpec[0].MatchesContract(crypto.Address{4, 5, 6}))
+		require.False(t, eventSpec[0].MatchesHeight(99))
+		require.True(t, eventSpec[0].MatchesHeight(100))
+
+		// tables without contracts match every address and height
+		require.True(t, eventSpec[1].MatchesContract(crypto.Address{4, 5, 6}))
+		require.True(t, eventSpec[1].MatchesHeight(0))
+	})
+
+	t.Run("returns an error if a contract is neither a deploy job name nor an address", func(t *testing.T) {
+		parser, err := sqlsol.NewParserFromBytes([]byte(test.ContractsJSONConfFile(t)))
+		require.NoError(t, err)
+
+		err = parser.BindContracts(map[string]crypto.Address{})
+		require.Error(t, err)
+	})
+}
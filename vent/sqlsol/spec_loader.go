@@ -53,24 +53,29 @@ func getBlockTxTablesDefinition() types.EventTables {
 	// block table
 	blockCol[types.BlockHeightLabel] = types.SQLTableColumn{
 		Name:    types.SQLColumnLabelHeight,
-		Type:    types.SQLColumnTypeVarchar,
-		Length:  100,
+		Type:    types.SQLColumnTypeBigInt,
 		Primary: true,
 		Order:   1,
 	}
 
 	blockCol[types.BlockHeaderLabel] = types.SQLTableColumn{
 		Name:    types.SQLColumnLabelBlockHeader,
-		Type:    types.SQLColumnTypeJSON,
+		Type:    types.SQLColumnTypeJSONB,
 		Primary: false,
 		Order:   2,
 	}
 
+	blockCol[types.BlockTimeLabel] = types.SQLTableColumn{
+		Name:    types.SQLColumnLabelBlockTime,
+		Type:    types.SQLColumnTypeTimeStamp,
+		Primary: false,
+		Order:   3,
+	}
+
 	// transaction table
 	txCol[types.BlockHeightLabel] = types.SQLTableColumn{
 		Name:    types.SQLColumnLabelHeight,
-		Type:    types.SQLColumnTypeVarchar,
-		Length:  100,
+		Type:    types.SQLColumnTypeBigInt,
 		Primary: true,
 		Order:   1,
 	}
@@ -101,48 +106,65 @@ func getBlockTxTablesDefinition() types.EventTables {
 
 	txCol[types.TxEnvelopeLabel] = types.SQLTableColumn{
 		Name:    types.SQLColumnLabelEnvelope,
-		Type:    types.SQLColumnTypeJSON,
+		Type:    types.SQLColumnTypeJSONB,
 		Primary: false,
 		Order:   5,
 	}
 
 	txCol[types.TxEventsLabel] = types.SQLTableColumn{
 		Name:    types.SQLColumnLabelEvents,
-		Type:    types.SQLColumnTypeJSON,
+		Type:    types.SQLColumnTypeJSONB,
 		Primary: false,
 		Order:   6,
 	}
 
 	txCol[types.TxResultLabel] = types.SQLTableColumn{
 		Name:    types.SQLColumnLabelResult,
-		Type:    types.SQLColumnTypeJSON,
+		Type:    types.SQLColumnTypeJSONB,
 		Primary: false,
 		Order:   7,
 	}
 
 	txCol[types.TxReceiptLabel] = types.SQLTableColumn{
 		Name:    types.SQLColumnLabelReceipt,
-		Type:    types.SQLColumnTypeJSON,
+		Type:    types.SQLColumnTypeJSONB,
 		Primary: false,
 		Order:   8,
 	}
 
 	txCol[types.TxExceptionLabel] = types.SQLTableColumn{
 		Name:    types.SQLColumnLabelException,
-		Type:    types.SQLColumnTypeJSON,
+		Type:    types.SQLColumnTypeJSONB,
 		Primary: false,
 		Order:   9,
 	}
 
-	// add tables
+	txCol[types.TxCallerLabel] = types.SQLTableColumn{
+		Name:    types.SQLColumnLabelCaller,
+		Type:    types.SQLColumnTypeVarchar,
+		Length:  40,
+		Primary: false,
+		Order:   10,
+	}
+
+	// add tables, along with commonly extracted json paths
 	tables[types.SQLBlockTableName] = types.SQLTable{
 		Name:    types.SQLBlockTableName,
 		Columns: blockCol,
+		JSONPaths: []types.SQLJSONPath{
+			{Name: types.SQLColumnLabelNumTxs, Column: types.SQLColumnLabelBlockHeader, Path: "$.num_txs"},
+		},
 	}
 
 	tables[types.SQLTxTableName] = types.SQLTable{
 		Name:    types.SQLTxTableName,
 		Columns: txCol,
+		JSONPaths: []types.SQLJSONPath{
+			{Name: types.SQLColumnLabelGasUsed, Column: types.SQLColumnLabelResult, Path: "$.GasUsed"},
+			{Name: types.SQLColumnLabelCreatesContract, Column: types.SQLColumnLabelReceipt, Path: "$.CreatesContract"},
+			{Name: types.SQLColumnLabelContractAddress, Column: types.SQLColumnLabelReceipt, Path: "$.ContractAddress"},
+			{Name: types.SQLColumnLabelExceptionCode, Column: types.SQLColumnLabelException, Path: "$.Code"},
+		},
 	}
 
 	return tables
@@ -26,4 +26,24 @@ func TestSpecLoader(t *testing.T) {
 		require.Equal(t, types.SQLTxTableName, parser.Tables[types.SQLTxTableName].Name)
 		require.Equal(t, strings.ToLower("_txhash"), parser.Tables[types.SQLTxTableName].Columns["txHash"].Name)
 	})
+
+	t.Run("successfully stores block and transaction json as indexable json", func(t *testing.T) {
+
+		parser, err := sqlsol.SpecLoader(specFile, "", dBBlockTx)
+		require.NoError(t, err)
+		require.Equal(t, types.SQLColumnTypeJSONB, parser.Tables[types.SQLTxTableName].Columns["events"].Type)
+		require.NotEmpty(t, parser.Tables[types.SQLTxTableName].JSONPaths)
+
+		err = parser.IndexColumns([]string{types.SQLBlockTableName, types.SQLTxTableName}, []string{"_events", "_blockheader"}, types.SQLIndexGIN)
+		require.NoError(t, err)
+		require.Equal(t, types.SQLIndexGIN, parser.Tables[types.SQLTxTableName].Columns["events"].Index)
+		require.Equal(t, types.SQLIndexGIN, parser.Tables[types.SQLBlockTableName].Columns["blockHeader"].Index)
+		require.Equal(t, "", parser.Tables[types.SQLTxTableName].Columns["receipt"].Index)
+
+		err = parser.IndexColumns([]string{types.SQLBlockTableName, types.SQLTxTableName}, []string{"_txtype"}, types.SQLIndexGIN)
+		require.Error(t, err)
+
+		err = parser.IndexColumns([]string{types.SQLBlockTableName, types.SQLTxTableName}, []string{"_missing"}, types.SQLIndexGIN)
+		require.Error(t, err)
+	})
 }
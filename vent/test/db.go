@@ -11,11 +11,15 @@ import (
 	"github.com/monax/bosmarmot/vent/config"
 	"github.com/monax/bosmarmot/vent/logger"
 	"github.com/monax/bosmarmot/vent/sqldb"
+	"github.com/monax/bosmarmot/vent/sqldb/adapters"
 	"github.com/monax/bosmarmot/vent/types"
 )
 
 var letters = []rune("abcdefghijklmnopqrstuvwxyz")
 
+// mysqlTestURL is the MySQL server used for testing, test schemas are created as databases
+const mysqlTestURL = "root:pass@tcp(localhost:3306)/"
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
@@ -42,6 +46,11 @@ func NewTestDB(t *testing.T, dbAdapter string) (*sqldb.SQLDB, func()) {
 		connection.DBAdapter = dbAdapter
 		connection.DBURL = fmt.Sprintf("./test_%s.sqlite", randString(10))
 
+	case types.MySQLDB:
+		connection.DBAdapter = dbAdapter
+		connection.DBURL = mysqlTestURL
+		connection.DBSchema = fmt.Sprintf("test_%s", randString(10))
+
 	default:
 		t.Fatal("invalid database adapter")
 	}
@@ -58,12 +67,58 @@ func NewTestDB(t *testing.T, dbAdapter string) (*sqldb.SQLDB, func()) {
 			os.Remove(connection.DBURL + "-shm")
 			os.Remove(connection.DBURL + "-wal")
 		} else {
-			destroySchema(db, connection.DBSchema)
+			destroySchema(db, dbAdapter, connection.DBSchema)
 			db.Close()
 		}
 	}
 }
 
+// NewTestDatabase returns the url & schema of an empty database for testing along with a function to destroy it,
+// the database is created by the adapter when opening the first connection
+func NewTestDatabase(t *testing.T, dbAdapter string) (string, string, func()) {
+	t.Helper()
+
+	dbURL := config.DefaultFlags().DBURL
+	dbSchema := fmt.Sprintf("test_%s", randString(10))
+
+	switch dbAdapter {
+	case types.PostgresDB:
+
+	case types.SQLiteDB:
+		dbURL = fmt.Sprintf("./test_%s.sqlite", randString(10))
+		dbSchema = ""
+
+	case types.MySQLDB:
+		dbURL = mysqlTestURL
+
+	default:
+		t.Fatal("invalid database adapter")
+	}
+
+	return dbURL, dbSchema, func() {
+		if dbAdapter == types.SQLiteDB {
+			os.Remove(dbURL)
+			os.Remove(dbURL + "-shm")
+			os.Remove(dbURL + "-wal")
+			return
+		}
+
+		log := logger.NewLogger("debug")
+		adapter, options, err := adapters.New(dbAdapter, dbSchema, log)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		dbc, err := adapter.Open(options.ConnectionURL(dbURL))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer dbc.Close()
+
+		destroySchema(&sqldb.SQLDB{DB: dbc, Log: log}, dbAdapter, dbSchema)
+	}
+}
+
 func randString(n int) string {
 	b := make([]rune, n)
 
@@ -74,10 +129,15 @@ func randString(n int) string {
 	return string(b)
 }
 
-func destroySchema(db *sqldb.SQLDB, dbSchema string) error {
+func destroySchema(db *sqldb.SQLDB, dbAdapter, dbSchema string) error {
 	db.Log.Info("msg", "Dropping schema")
 	query := fmt.Sprintf("DROP SCHEMA %s CASCADE;", dbSchema)
 
+	// mysql schemas are databases, dropping them always drops their tables
+	if dbAdapter == types.MySQLDB {
+		query = fmt.Sprintf("DROP DATABASE %s;", dbSchema)
+	}
+
 	db.Log.Info("msg", "Drop schema", "query", query)
 
 	if _, err := db.DB.Exec(query); err != nil {
@@ -169,3 +169,229 @@ func DuplicatedColNameJSONConfFile(t *testing.T) string {
 
 	return duplicatedColNameJSONConfFile
 }
+
+// DuplicatedTableNameJSONConfFile sets a json file with table names that collide once normalised
+func DuplicatedTableNameJSONConfFile(t *testing.T) string {
+	t.Helper()
+
+	duplicatedTableNameJSONConfFile := `[
+		{
+			"TableName" : "UserAccounts",
+			"Filter" : "LOG0 = 'UserAccounts'",
+			"Columns"  : {
+				"userAddress" : {"name" : "address", "type": "address", "primary" : true}
+			}
+		},
+		{
+			"TableName" : "USERACCOUNTS",
+			"Filter" : "LOG0 = 'UserAccounts'",
+			"Columns"  : {
+				"userAddress" : {"name" : "address", "type": "address", "primary" : true}
+			}
+		}
+	]`
+
+	return duplicatedTableNameJSONConfFile
+}
+
+// HistoryTableNameJSONConfFile sets a json file with a table named as the history view of another one
+func HistoryTableNameJSONConfFile(t *testing.T) string {
+	t.Helper()
+
+	historyTableNameJSONConfFile := `[
+		{
+			"TableName" : "UserAccounts",
+			"Filter" : "LOG0 = 'UserAccounts'",
+			"Columns"  : {
+				"userAddress" : {"name" : "address", "type": "address", "primary" : true}
+			}
+		},
+		{
+			"TableName" : "UserAccounts_History",
+			"Filter" : "LOG0 = 'UserAccounts'",
+			"Columns"  : {
+				"userAddress" : {"name" : "address", "type": "address", "primary" : true}
+			}
+		}
+	]`
+
+	return historyTableNameJSONConfFile
+}
+
+// PreMigrationJSONConfFile sets a json file with the tables of GoodJSONConfFile,
+// storing a small integer column that MigrationJSONConfFile widens
+func PreMigrationJSONConfFile(t *testing.T) string {
+	t.Helper()
+
+	preMigrationJSONConfFile := `[
+		{
+			"TableName" : "UserAccounts",
+			"Filter" : "LOG0 = 'UserAccounts'",
+			"DeleteFilter": "CRUD_ACTION = 'delete'",
+			"Columns"  : {
+				"userAddress" : {"name" : "address", "type": "address", "primary" : true},
+				"userName": {"name" : "username", "type": "string", "primary" : false},
+				"userId": {"name" : "userid", "type": "uint256", "primary" : false},
+				"userBool": {"name" : "userbool", "type": "bool", "primary" : false}
+			}
+		},
+		{
+		"TableName" : "TEST_TABLE",
+		"Filter" : "Log1Text = 'EVENT_TEST'",
+		"DeleteFilter": "CRUD_ACTION = 'delete'",
+		"Columns"  : {
+			"key"		: {"name" : "Index",    "type": "uint256", "primary" : true},
+			"blocknum"  : {"name" : "Block",    "type": "uint256", "primary" : false},
+			"somestr"	: {"name" : "String",   "type": "string", "primary" : false},
+			"instance" 	: {"name" : "Instance", "type": "uint16", "primary" : false}
+		}
+	}
+	]`
+
+	return preMigrationJSONConfFile
+}
+
+// MigrationJSONConfFile sets a json file with a renamed column, a widened column type
+// and a dropped column with respect to PreMigrationJSONConfFile
+func MigrationJSONConfFile(t *testing.T) string {
+	t.Helper()
+
+	migrationJSONConfFile := `[
+		{
+			"TableName" : "UserAccounts",
+			"Filter" : "LOG0 = 'UserAccounts'",
+			"DeleteFilter": "CRUD_ACTION = 'delete'",
+			"Columns"  : {
+				"userAddress" : {"name" : "address", "type": "address", "primary" : true},
+				"userName": {"name" : "name", "type": "string", "primary" : false, "previousName": "username"},
+				"userId": {"name" : "userid", "type": "uint256", "primary" : false}
+			}
+		},
+		{
+		"TableName" : "TEST_TABLE",
+		"Filter" : "Log1Text = 'EVENT_TEST'",
+		"DeleteFilter": "CRUD_ACTION = 'delete'",
+		"Columns"  : {
+			"key"		: {"name" : "Index",    "type": "uint256", "primary" : true},
+			"blocknum"  : {"name" : "Block",    "type": "uint256", "primary" : false},
+			"somestr"	: {"name" : "String",   "type": "string", "primary" : false},
+			"instance" 	: {"name" : "Instance", "type": "uint64", "primary" : false}
+		}
+	}
+	]`
+
+	return migrationJSONConfFile
+}
+
+// ContractsJSONConfFile sets a json file with tables bound to contracts and start heights
+func ContractsJSONConfFile(t *testing.T) string {
+	t.Helper()
+
+	contractsJSONConfFile := `[
+		{
+			"TableName" : "UserAccounts",
+			"Filter" : "LOG0 = 'UserAccounts'",
+			"Contracts" : ["1AEEFD3783050219C3988098E152A11F02C4F4C4", "userAccounts"],
+			"StartHeight" : 100,
+			"Columns"  : {
+				"userAddress" : {"name" : "address", "type": "address", "primary" : true},
+				"userName": {"name" : "username", "type": "string", "primary" : false}
+			}
+		},
+		{
+			"TableName" : "TEST_TABLE",
+			"Filter" : "Log1Text = 'EVENT_TEST'",
+			"Columns"  : {
+				"key"		: {"name" : "Index",    "type": "uint256", "primary" : true},
+				"somestr"	: {"name" : "String",   "type": "string", "primary" : false}
+			}
+		}
+	]`
+
+	return contractsJSONConfFile
+}
+
+// RawLogJSONConfFile sets a raw log table and an anonymous event table
+func RawLogJSONConfFile(t *testing.T) string {
+	t.Helper()
+
+	rawLogJSONConfFile := `[
+		{
+			"TableName" : "RawLogs",
+			"Filter" : "EventType = 'LogEvent'",
+			"Columns"  : {
+				"topic0" : {"name" : "topic0", "primary" : false, "raw" : true},
+				"topic1" : {"name" : "topic1", "primary" : false, "raw" : true},
+				"data"	 : {"name" : "data", "primary" : false, "raw" : true}
+			}
+		},
+		{
+			"TableName" : "AnonymousTransfers",
+			"Filter" : "EventType = 'LogEvent'",
+			"Contracts" : ["1AEEFD3783050219C3988098E152A11F02C4F4C4"],
+			"Event" : "Transfer",
+			"Columns"  : {
+				"from"  : {"name" : "from", "type": "address", "primary" : true},
+				"value" : {"name" : "value", "type": "uint256", "primary" : false},
+				"data"	: {"name" : "raw_data", "primary" : false, "raw" : true}
+			}
+		}
+	]`
+
+	return rawLogJSONConfFile
+}
+
+// JSONColumnJSONConfFile sets a json file with a string column stored as an indexed json document
+func JSONColumnJSONConfFile(t *testing.T) string {
+	t.Helper()
+
+	jsonColumnJSONConfFile := `[
+		{
+			"TableName" : "Documents",
+			"Filter" : "Log1Text = 'DOCUMENT'",
+			"Columns"  : {
+				"key"      : {"name" : "key", "type": "uint256", "primary" : true},
+				"document" : {"name" : "document", "type": "string", "primary" : false, "json" : true, "index" : "gin"}
+			}
+		}
+	]`
+
+	return jsonColumnJSONConfFile
+}
+
+// PartitionedJSONConfFile sets a json file with a table partitioned by height ranges
+func PartitionedJSONConfFile(t *testing.T) string {
+	t.Helper()
+
+	partitionedJSONConfFile := `[
+		{
+			"TableName" : "Transfers",
+			"Filter" : "Log1Text = 'TRANSFER'",
+			"Partitioning" : {"HeightRange" : 1000000},
+			"Columns"  : {
+				"index"  : {"name" : "index", "type": "uint256", "primary" : true},
+				"amount" : {"name" : "amount", "type": "uint256", "primary" : false}
+			}
+		}
+	]`
+
+	return partitionedJSONConfFile
+}
+
+// FullTextJSONConfFile sets a json file with a searchable string column
+func FullTextJSONConfFile(t *testing.T) string {
+	t.Helper()
+
+	fullTextJSONConfFile := `[
+		{
+			"TableName" : "Payments",
+			"Filter" : "Log1Text = 'PAYMENT'",
+			"Columns"  : {
+				"key"  : {"name" : "key", "type": "uint256", "primary" : true},
+				"memo" : {"name" : "memo", "type": "string", "primary" : false, "fullText" : true}
+			}
+		}
+	]`
+
+	return fullTextJSONConfFile
+}
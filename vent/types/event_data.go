@@ -10,6 +10,8 @@ const (
 	ActionCreateTable DBAction = "CREATE"
 	ActionAlterTable  DBAction = "ALTER"
 	ActionInitialize  DBAction = "_INITIALIZE_VENT"
+	ActionCheckpoint  DBAction = "CHECKPOINT"
+	ActionPartition   DBAction = "PARTITION"
 )
 
 // EventData contains data for each block of events
@@ -26,6 +28,8 @@ type EventDataTable []EventDataRow
 // EventDataRow contains each SQL column name and a corresponding value to upsert
 // map key is the column name and map value is the given column value
 // if Action == 'delete' then the row has to be deleted
+// if Action == 'read' then every column is set with a value typed after the column type (nil if NULL):
+// bool, int64 (integers), *big.Int (numerics), string (text), []byte, json.RawMessage or time.Time
 type EventDataRow struct {
 	Action  DBAction
 	RowData map[string]interface{}
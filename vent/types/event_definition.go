@@ -2,6 +2,7 @@ package types
 
 import (
 	"github.com/go-ozzo/ozzo-validation"
+	"github.com/hyperledger/burrow/crypto"
 	"github.com/hyperledger/burrow/event/query"
 )
 
@@ -9,12 +10,22 @@ import (
 type EventSpec []EventDefinition
 
 // EventDefinition struct (table name where to persist filtered events and it structure)
+// Contracts is an optional list of contract addresses (or deploy job names) emitting the events
+// StartHeight is an optional block height below which events are ignored
+// Event is an optional abi event name used to decode logs instead of looking up topic0,
+// it is required to decode anonymous events (which must also be bound to Contracts)
+// Partitioning optionally splits the table in partitions holding given height ranges
 type EventDefinition struct {
 	TableName    string                 `json:"TableName"`
 	Filter       string                 `json:"Filter"`
 	DeleteFilter string                 `json:"DeleteFilter"`
 	Columns      map[string]EventColumn `json:"Columns"`
+	Contracts    []string               `json:"Contracts"`
+	StartHeight  uint64                 `json:"StartHeight"`
+	Event        string                 `json:"Event"`
+	Partitioning Partitioning           `json:"Partitioning"`
 	query        query.Query
+	addresses    map[crypto.Address]bool
 }
 
 // Validate checks the structure of an EventDefinition
@@ -38,17 +49,66 @@ func (evDef EventDefinition) Query() (query.Query, error) {
 	return evDef.query, nil
 }
 
+// SetAddresses binds resolved contract addresses to the EventDefinition
+func (evDef *EventDefinition) SetAddresses(addresses []crypto.Address) {
+	evDef.addresses = make(map[crypto.Address]bool)
+	for _, address := range addresses {
+		evDef.addresses[address] = true
+	}
+}
+
+// MatchesContract checks if an event emitted by the given contract address belongs to the EventDefinition,
+// every address matches if no contracts are given
+func (evDef EventDefinition) MatchesContract(address crypto.Address) bool {
+	if len(evDef.Contracts) == 0 {
+		return true
+	}
+	return evDef.addresses[address]
+}
+
+// MatchesHeight checks if an event at the given block height belongs to the EventDefinition
+func (evDef EventDefinition) MatchesHeight(height uint64) bool {
+	return height >= evDef.StartHeight
+}
+
+// IsRaw checks if the EventDefinition only maps raw log topics & data,
+// in which case logs are stored without being decoded
+func (evDef EventDefinition) IsRaw() bool {
+	for _, col := range evDef.Columns {
+		if !col.Raw {
+			return false
+		}
+	}
+	return true
+}
+
+// Partitioning struct (table partitioning definition)
+// HeightRange is the number of block heights held by each partition, tables are not partitioned if zero
+type Partitioning struct {
+	HeightRange uint64 `json:"HeightRange"`
+}
+
 // EventColumn struct (table column definition)
+// JSON stores string inputs as JSON documents, which can be indexed with a given Index method
+// FullText makes string inputs searchable
+// Raw stores the log topic or data named by the column key (topic0 to topic3 or data) instead of an event input
 type EventColumn struct {
 	Name          string `json:"name"`
 	Type          string `json:"type"`
 	Primary       bool   `json:"primary"`
 	BytesToString bool   `json:"bytesToString"`
+	PreviousName  string `json:"previousName"`
+	JSON          bool   `json:"json"`
+	Index         string `json:"index"`
+	FullText      bool   `json:"fullText"`
+	Raw           bool   `json:"raw"`
 }
 
 // Validate checks the structure of an EventColumn
 func (evColumn EventColumn) Validate() error {
 	return validation.ValidateStruct(&evColumn,
 		validation.Field(&evColumn.Name, validation.Required, validation.Length(1, 60)),
+		validation.Field(&evColumn.PreviousName, validation.Length(1, 60), validation.NotIn(evColumn.Name)),
+		validation.Field(&evColumn.Index, validation.In(SQLIndexGIN)),
 	)
 }
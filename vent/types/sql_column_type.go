@@ -15,8 +15,15 @@ const (
 	SQLColumnTypeNumeric
 	SQLColumnTypeJSON
 	SQLColumnTypeBigInt
+	// JSONB is a binary (indexable) JSON type where the database supports it, JSON otherwise
+	SQLColumnTypeJSONB
 )
 
+// IsJSON determines if an sqlColumnType stores JSON documents
+func (sqlColumnType SQLColumnType) IsJSON() bool {
+	return sqlColumnType == SQLColumnTypeJSON || sqlColumnType == SQLColumnTypeJSONB
+}
+
 // IsNumeric determines if an sqlColumnType is numeric
 func (sqlColumnType SQLColumnType) IsNumeric() bool {
 	return sqlColumnType == SQLColumnTypeInt || sqlColumnType == SQLColumnTypeSerial || sqlColumnType == SQLColumnTypeNumeric || sqlColumnType == SQLColumnTypeBigInt
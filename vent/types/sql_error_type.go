@@ -12,4 +12,6 @@ const (
 	SQLErrorTypeUndefinedTable
 	SQLErrorTypeUndefinedColumn
 	SQLErrorTypeGeneric
+	// SQLErrorTypeUnsupported is a feature missing from the database (an older version or a missing extension)
+	SQLErrorTypeUnsupported
 )
@@ -1,14 +1,21 @@
 package types
 
 // SQLTable contains the structure of a SQL table,
+// JSONPaths are extracted from JSON columns by adapters supporting generated columns,
+// PartitionRange (if any) is the height range held by each partition of the table
 type SQLTable struct {
-	Name    string
-	Filter  string
-	Columns map[string]SQLTableColumn
+	Name           string
+	Filter         string
+	Columns        map[string]SQLTableColumn
+	JSONPaths      []SQLJSONPath
+	PartitionRange uint64
 }
 
 // SQLTableColumn contains the definition of a SQL table column,
-// the Order is given to be able to sort the columns to be created
+// the Order is given to be able to sort the columns to be created,
+// PreviousName (if any) is the name the column had before being renamed,
+// Index (if any) is the method used to index JSON columns,
+// FullText columns can be searched by adapters supporting full-text search
 type SQLTableColumn struct {
 	Name          string
 	Type          SQLColumnType
@@ -17,8 +24,24 @@ type SQLTableColumn struct {
 	Primary       bool
 	BytesToString bool
 	Order         int
+	PreviousName  string
+	Index         string
+	FullText      bool
+	Raw           bool
 }
 
+// SQLJSONPath defines a column generated from a path (e.g. $.Code) of a JSON column
+type SQLJSONPath struct {
+	Name   string
+	Column string
+	Path   string
+}
+
+// SQL index methods for JSON columns
+const (
+	SQLIndexGIN = "gin"
+)
+
 // UpsertDeleteQuery contains query and values to upsert or delete row data
 type UpsertDeleteQuery struct {
 	Query    string
@@ -33,8 +56,18 @@ const (
 	SQLBlockTableName      = "_vent_block"
 	SQLTxTableName         = "_vent_tx"
 	SQLChainInfoTableName  = "_vent_chain"
+	SQLAbiTableName        = "_vent_abi"
 )
 
+// SQLSysTablePrefix starts the names of tables created by vent
+const SQLSysTablePrefix = "_vent_"
+
+// SQLHistoryViewSuffix is appended to event table names to name their history views
+const SQLHistoryViewSuffix = "_history"
+
+// SQLEnrichedViewSuffix is appended to event table names to name their views joined with block & tx data
+const SQLEnrichedViewSuffix = "_enriched"
+
 // fixed sql column names in tables
 const (
 	// log
@@ -49,6 +82,7 @@ const (
 	SQLColumnLabelDataRow     = "_datarow"
 	SQLColumnLabelSqlStmt     = "_sqlstmt"
 	SQLColumnLabelSqlValues   = "_sqlvalues"
+	SQLColumnLabelBeforeImage = "_beforeimage"
 
 	// dictionary
 	SQLColumnLabelColumnName   = "_columnname"
@@ -61,6 +95,14 @@ const (
 	SQLColumnLabelBurrowVer = "_burrowversion"
 	SQLColumnLabelChainID   = "_chainid"
 
+	// abi registry
+	SQLColumnLabelAddress = "_address"
+	SQLColumnLabelAbi     = "_abi"
+
+	// history views
+	SQLColumnLabelValidFromHeight = "_valid_from_height"
+	SQLColumnLabelValidToHeight   = "_valid_to_height"
+
 	// context
 	SQLColumnLabelIndex       = "_index"
 	SQLColumnLabelEventType   = "_eventtype"
@@ -71,18 +113,29 @@ const (
 	SQLColumnLabelResult      = "_result"
 	SQLColumnLabelReceipt     = "_receipt"
 	SQLColumnLabelException   = "_exception"
+	SQLColumnLabelBlockTime   = "_blocktime"
+	SQLColumnLabelCaller      = "_caller"
+
+	// json paths
+	SQLColumnLabelNumTxs          = "_numtxs"
+	SQLColumnLabelGasUsed         = "_gasused"
+	SQLColumnLabelCreatesContract = "_createscontract"
+	SQLColumnLabelContractAddress = "_contractaddress"
+	SQLColumnLabelExceptionCode   = "_exceptioncode"
 )
 
 // labels for column mapping
 const (
 	// event related
-	EventNameLabel = "eventName"
-	EventTypeLabel = "eventType"
+	EventNameLabel     = "eventName"
+	EventTypeLabel     = "eventType"
+	EventContractLabel = "contractName"
 
 	// block related
 	BlockHeightLabel = "height"
 	BlockHeaderLabel = "blockHeader"
 	BlockTxExecLabel = "txExecutions"
+	BlockTimeLabel   = "blockTime"
 
 	// transaction related
 	TxTxTypeLabel    = "txType"
@@ -93,4 +146,28 @@ const (
 	TxResultLabel    = "result"
 	TxReceiptLabel   = "receipt"
 	TxExceptionLabel = "exception"
+	TxCallerLabel    = "caller"
+
+	// raw log related (keys of raw columns)
+	LogTopic0Label = "topic0"
+	LogTopic1Label = "topic1"
+	LogTopic2Label = "topic2"
+	LogTopic3Label = "topic3"
+	LogDataLabel   = "data"
 )
+
+// LogTopicLabels are the raw log topic labels, in topic order
+var LogTopicLabels = []string{LogTopic0Label, LogTopic1Label, LogTopic2Label, LogTopic3Label}
+
+// IsRawLogLabel checks if a label names a raw (undecoded) log topic or data
+func IsRawLogLabel(label string) bool {
+	if label == LogDataLabel {
+		return true
+	}
+	for _, topicLabel := range LogTopicLabels {
+		if label == topicLabel {
+			return true
+		}
+	}
+	return false
+}
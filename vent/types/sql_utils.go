@@ -1,15 +1,59 @@
 package types
 
-import "github.com/monax/bosmarmot/vent/logger"
+import (
+	"time"
+
+	"github.com/monax/bosmarmot/vent/logger"
+)
 
 // SQLConnection stores parameters to build a new db connection & initialize the database
 type SQLConnection struct {
-	DBAdapter     string
-	DBURL         string
-	DBSchema      string
-	Log           *logger.Logger
-	ChainID       string
-	BurrowVersion string
+	DBAdapter        string
+	DBURL            string
+	DBSchema         string
+	Log              *logger.Logger
+	ChainID          string
+	BurrowVersion    string
+	AllowDestructive bool
+	ChainIDPolicy    string
+	NotifyChannel    string
+	// SQLite connection tuning, ignored by other adapters
+	SQLiteBusyTimeout time.Duration
+	SQLiteSynchronous string
+}
+
+// chain ID policies, applied when the chain ID stored in the database differs from the chain one
+const (
+	// ChainIDPolicyRefuse returns an error (default)
+	ChainIDPolicyRefuse = "refuse"
+	// ChainIDPolicyArchive renames event & system tables with the stored chain ID as suffix
+	ChainIDPolicyArchive = "archive"
+	// ChainIDPolicyNamespace uses a separate schema for each chain ID (<schema>_<chain ID>)
+	ChainIDPolicyNamespace = "namespace"
+	// ChainIDPolicyDrop drops all event tables and cleans system tables
+	ChainIDPolicyDrop = "drop"
+)
+
+// ChainIDPolicies lists valid chain ID policies
+var ChainIDPolicies = []string{ChainIDPolicyRefuse, ChainIDPolicyArchive, ChainIDPolicyNamespace, ChainIDPolicyDrop}
+
+// schema drift policies, applied when event tables differ from the structure stored in the dictionary
+const (
+	// SchemaDriftPolicyWarn reports drift through logs, health & metrics (default)
+	SchemaDriftPolicyWarn = "warn"
+	// SchemaDriftPolicyRepair recreates missing tables & columns and restores column types
+	SchemaDriftPolicyRepair = "repair"
+	// SchemaDriftPolicyRefuse returns an error
+	SchemaDriftPolicyRefuse = "refuse"
+)
+
+// SchemaDriftPolicies lists valid schema drift policies
+var SchemaDriftPolicies = []string{SchemaDriftPolicyWarn, SchemaDriftPolicyRepair, SchemaDriftPolicyRefuse}
+
+// BlockNotification is the payload sent to the notification channel when a block is committed
+type BlockNotification struct {
+	Height uint64         `json:"height"`
+	Tables map[string]int `json:"tables"`
 }
 
 // SQLCleanDBQuery stores queries needed to clean the database
@@ -20,4 +64,5 @@ type SQLCleanDBQuery struct {
 	SelectDictionaryQry string
 	DeleteDictionaryQry string
 	DeleteLogQry        string
+	DeleteAbiQry        string
 }